@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"flag"
+	"hash/fnv"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// anonymizeExport copies MT export replacing its text with lorem ipsum,
+// keeping structure, for sharing failing inputs. It's invoked as
+// "mt2kkr anonymize input.txt > fixture.txt".
+func anonymizeExport(args []string) {
+	fs := flag.NewFlagSet("anonymize", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		log.Fatal("usage: mt2kkr anonymize [input.txt] > fixture.txt")
+	}
+	in, err := openInput(context.Background(), fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+	out := bufio.NewWriter(os.Stdout)
+	a := newAnonymizer()
+	if err := a.copy(out, in); err != nil {
+		log.Fatal(err)
+	}
+	if err := out.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// anonymizer replaces words consistently within a run: the same word
+// always becomes the same lorem ipsum word of the same length, so that
+// categories, authors and basenames still match each other. Mapping is
+// salted randomly, so it can't be reversed by hashing guesses.
+type anonymizer struct {
+	salt [16]byte
+}
+
+func newAnonymizer() *anonymizer {
+	a := new(anonymizer)
+	if _, err := rand.Read(a.salt[:]); err != nil {
+		log.Fatal(err)
+	}
+	return a
+}
+
+var loremWords = strings.Fields(`lorem ipsum dolor sit amet consectetur
+	adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore
+	magna aliqua enim ad minim veniam quis nostrud exercitation ullamco
+	laboris nisi aliquip ex ea commodo consequat duis aute irure in
+	reprehenderit voluptate velit esse cillum fugiat nulla pariatur
+	excepteur sint occaecat cupidatat non proident sunt culpa qui officia
+	deserunt mollit anim id est laborum`)
+
+var loremByLen = make(map[int][]string)
+
+func init() {
+	for _, w := range loremWords {
+		loremByLen[len(w)] = append(loremByLen[len(w)], w)
+	}
+}
+
+func (a *anonymizer) hash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write(a.salt[:])
+	io.WriteString(h, s)
+	return h.Sum32()
+}
+
+// word returns lorem ipsum replacement of w with the same number of
+// characters and capitalization of the first letter.
+func (a *anonymizer) word(w string) string {
+	runes := []rune(w)
+	h := a.hash(strings.ToLower(w))
+	var s string
+	if ws := loremByLen[len(runes)]; len(ws) > 0 {
+		s = ws[h%uint32(len(ws))]
+	} else {
+		var b strings.Builder
+		for i := h; b.Len() < len(runes); i++ {
+			b.WriteString(loremWords[i%uint32(len(loremWords))])
+		}
+		s = b.String()[:len(runes)]
+	}
+	if unicode.IsUpper(runes[0]) {
+		s = strings.ToUpper(s[:1]) + s[1:]
+	}
+	return s
+}
+
+// digits replaces digits of n with other digits.
+func (a *anonymizer) digits(n string) string {
+	h := a.hash(n)
+	b := []byte(n)
+	for i := range b {
+		b[i] = '0' + byte(h%10)
+		h = h/10 ^ h<<7
+	}
+	return string(b)
+}
+
+var (
+	anonEmailRe = regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`)
+	anonURLRe   = regexp.MustCompile(`(?i)\b(?:https?|ftp)://[^\s"'<>]+`)
+	anonTokenRe = regexp.MustCompile(`(?i)\b(?:https?|ftp)://[^\s"'<>]+|[\w.+-]+@[\w-]+(?:\.[\w-]+)+|<[^>]*>|&#?\w+;|\pL+|\pN+`)
+	anonAttrRe  = regexp.MustCompile(`(\s[^\s"'>/=]+)(?:(\s*=\s*)("[^"]*"|'[^']*'|[^\s"'>]+))?`)
+	anonBlockRe = regexp.MustCompile(`^<!--\s*(?:more\b|/?wp:[\w/-]+)`)
+)
+
+// anonKeepAttrs are attributes with structural values kept as is.
+var anonKeepAttrs = map[string]bool{
+	"class": true, "width": true, "height": true, "align": true,
+	"valign": true, "border": true, "cellpadding": true, "cellspacing": true,
+	"colspan": true, "rowspan": true, "target": true, "rel": true,
+	"type": true, "dir": true, "frameborder": true, "scrolling": true,
+	"start": true, "span": true,
+}
+
+// anonURLAttrs are attributes with URL values.
+var anonURLAttrs = map[string]bool{
+	"href": true, "src": true, "action": true, "cite": true,
+	"poster": true, "background": true, "longdesc": true, "data": true,
+	"formaction": true,
+}
+
+func (a *anonymizer) email(s string) string {
+	local, _, _ := strings.Cut(s, "@")
+	return a.text(local) + "@example.com"
+}
+
+func (a *anonymizer) url(s string) string {
+	i := strings.Index(s, "://") + 3
+	host, path, _ := strings.Cut(s[i:], "/")
+	u := s[:i] + a.text(strings.TrimSuffix(host, ".com")) + ".example"
+	if path != "" || strings.Contains(s[i:], "/") {
+		u += "/" + a.path(path)
+	}
+	return u
+}
+
+// path replaces words of URL path, keeping its file extension, which
+// decides how links are converted.
+func (a *anonymizer) path(s string) string {
+	ext := ""
+	if j := strings.LastIndex(s, "."); j >= 0 && !strings.Contains(s[j:], "/") {
+		s, ext = s[:j], s[j:]
+	}
+	return a.text(s) + ext
+}
+
+// link replaces URL, email or relative path of link.
+func (a *anonymizer) link(s string) string {
+	switch {
+	case anonURLRe.MatchString(s) && anonURLRe.FindStringIndex(s)[0] == 0:
+		return anonURLRe.ReplaceAllStringFunc(s, a.url)
+	case strings.HasPrefix(strings.ToLower(s), "mailto:"):
+		return s[:len("mailto:")] + anonEmailRe.ReplaceAllStringFunc(s[len("mailto:"):], a.email)
+	}
+	return a.path(s)
+}
+
+// tag replaces HTML comment text and attribute values of tag, except
+// for structural ones, such as class and width.
+func (a *anonymizer) tag(t string) string {
+	if strings.HasPrefix(t, "<!--") {
+		// Keep markers of more and block editor comments.
+		keep := anonBlockRe.FindString(t)
+		if keep == "" {
+			keep = "<!--"
+		}
+		return keep + a.text(t[len(keep):])
+	}
+	return anonAttrRe.ReplaceAllStringFunc(t, func(attr string) string {
+		m := anonAttrRe.FindStringSubmatch(attr)
+		name, eq, val := m[1], m[2], m[3]
+		key := strings.ToLower(strings.TrimSpace(name))
+		if val == "" || anonKeepAttrs[key] {
+			return attr
+		}
+		quote := ""
+		if val[0] == '"' || val[0] == '\'' {
+			quote, val = val[:1], val[1:len(val)-1]
+		}
+		if anonURLAttrs[key] {
+			val = a.link(val)
+		} else {
+			val = a.text(val)
+		}
+		return name + eq + quote + val + quote
+	})
+}
+
+// text replaces words, numbers, emails and URLs in s, keeping HTML tags
+// and entities, but not text of comments and attributes, see tag.
+func (a *anonymizer) text(s string) string {
+	return anonTokenRe.ReplaceAllStringFunc(s, a.token)
+}
+
+func (a *anonymizer) token(t string) string {
+	switch r, _ := utf8.DecodeRuneInString(t); {
+	case anonURLRe.MatchString(t) && !strings.HasPrefix(t, "<"):
+		return a.url(t)
+	case r == '<':
+		return a.tag(t)
+	case r == '&':
+		return t
+	case strings.Contains(t, "@"):
+		return a.email(t)
+	case unicode.IsDigit(r):
+		return a.digits(t)
+	}
+	return a.word(t)
+}
+
+// anonKeepKeys are header keys whose values are kept as is.
+var anonKeepKeys = map[string]bool{
+	"STATUS":         true,
+	"ALLOW COMMENTS": true,
+	"ALLOW PINGS":    true,
+	"CONVERT BREAKS": true,
+	"DATE":           true,
+}
+
+// field anonymizes value of "KEY: value" line.
+func (a *anonymizer) field(line string) string {
+	key, val, ok := strings.Cut(line, ":")
+	if !ok || anonKeepKeys[key] {
+		return line
+	}
+	switch key {
+	case "IP":
+		return "IP: 192.0.2.1"
+	case "EMAIL":
+		if v := strings.TrimSpace(val); v != "" {
+			return "EMAIL: " + a.email(v)
+		}
+	}
+	return key + ":" + a.text(val)
+}
+
+// copy writes anonymized copy of MT export from r to w.
+func (a *anonymizer) copy(w io.Writer, r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<26)
+	header := true // in entry header
+	section := ""  // name of current section
+	meta := 0      // remaining metadata lines of comment or ping
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case line == mt.EntryMarker:
+			header, section = true, ""
+		case line == mt.SectionMarker:
+			header, section = false, ""
+		case !header && section == "":
+			section = line
+			if section == "COMMENT:" || section == "PING:" {
+				meta = 5
+			}
+		case header || meta > 0:
+			if !header {
+				meta--
+			}
+			line = a.field(line)
+		default:
+			line = a.text(line)
+		}
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
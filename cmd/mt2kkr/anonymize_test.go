@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnonymizeHTML(t *testing.T) {
+	a := new(anonymizer)
+	in := `<!-- note: call Jane Smith about the divorce -->
+<!-- wp:image {"id":12} -->
+<figure class="wp-block-image"><a href="/photos/jane-smith-wedding.jpg"><img src="http://jane.example.com/tom.png" width="300" data-caption="Jane and Tom" alt="Jane" controls></a></figure>
+<a href="mailto:jane@smith.org" title="Mail Jane">Jane</a> <a href=/jane/tom.html>x</a>
+<!--more-->`
+	out := a.text(in)
+	for _, s := range []string{"Jane", "jane", "Tom", "tom", "Smith", "smith", "divorce", "wedding", "photos", "note", "call"} {
+		if strings.Contains(out, s) {
+			t.Errorf("%q is left in:\n%s", s, out)
+		}
+	}
+	for _, s := range []string{
+		"<!-- wp:image ", "<!--more-->", `<figure class="wp-block-image">`,
+		`width="300"`, " controls>", `.jpg">`, `.png"`, `.html>`, `href="/`,
+		`href="mailto:`, "@example.com", "</a></figure>",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q is missing in:\n%s", s, out)
+		}
+	}
+	if n := strings.Count(out, "\n"); n != strings.Count(in, "\n") {
+		t.Errorf("%d lines, want %d", n, strings.Count(in, "\n"))
+	}
+}
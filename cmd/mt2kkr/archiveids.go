@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var archiveIDsFlag = flag.String("archive-ids", "", "guess numeric MT entry IDs from URLs like /archives/000123.html in sitemap or access log `file` and write archive-redirects.txt to output directory")
+
+var (
+	archiveURLRe = regexp.MustCompile(`/archives/0*([0-9]+)\.html?`)
+	lastmodRe    = regexp.MustCompile(`<lastmod>\s*([0-9]{4}-[0-9]{2}-[0-9]{2})`)
+)
+
+// archiveID is an entry ID seen in sitemap or access log.
+type archiveID struct {
+	ID   int
+	Path string    // as seen, e.g. /archives/000123.html
+	Date time.Time // sitemap lastmod, if any
+}
+
+// archiveEntry is a converted entry for ID matching.
+type archiveEntry struct {
+	Date time.Time
+	URL  string
+	File string
+}
+
+var (
+	archiveEntriesMu sync.Mutex
+	archiveEntries   []archiveEntry
+)
+
+// addArchiveEntry records converted entry for -archive-ids.
+func (e *entry) addArchiveEntry() {
+	if *archiveIDsFlag == "" {
+		return
+	}
+	archiveEntriesMu.Lock()
+	archiveEntries = append(archiveEntries, archiveEntry{e.Date, e.newURL(), e.filename})
+	archiveEntriesMu.Unlock()
+}
+
+// readArchiveIDs returns unique entry IDs found in file, in ascending
+// order. Sitemap lastmod dates are kept for <url> elements written one
+// per line or with <loc> and <lastmod> on following lines.
+func readArchiveIDs(filename string) ([]archiveID, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	seen := make(map[int]*archiveID)
+	var last *archiveID
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := s.Bytes()
+		for _, m := range archiveURLRe.FindAllSubmatch(line, -1) {
+			id, err := strconv.Atoi(string(m[1]))
+			if err != nil {
+				continue
+			}
+			if seen[id] == nil {
+				seen[id] = &archiveID{ID: id, Path: string(m[0])}
+			}
+			last = seen[id]
+		}
+		if m := lastmodRe.FindSubmatch(line); m != nil && last != nil && last.Date.IsZero() {
+			last.Date, _ = time.Parse("2006-01-02", string(m[1]))
+		}
+		if bytes.Contains(line, []byte("</url>")) {
+			last = nil
+		}
+	}
+	ids := make([]archiveID, 0, len(seen))
+	for _, id := range seen {
+		ids = append(ids, *id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].ID < ids[j].ID })
+	return ids, s.Err()
+}
+
+// matchArchiveIDs aligns entries, oldest first, with ascending IDs, as
+// MT numbers entries in order of creation. Some IDs may belong to
+// deleted entries or pages, and some entries may have no seen ID, so it
+// finds the alignment with the least cost: skipping an entry or ID
+// costs 1, and matching an ID with known date costs 2 unless it's the
+// entry date. It returns matched ID index for each entry, or -1.
+func matchArchiveIDs(entries []archiveEntry, ids []archiveID) []int {
+	n, m := len(entries), len(ids)
+	matchCost := func(i, j int) int {
+		d := ids[j].Date
+		if d.IsZero() || d.Format("2006-01-02") == entries[i].Date.Format("2006-01-02") {
+			return 0
+		}
+		return 2
+	}
+	// Costs of aligning entries[i:] with ids[j:] are computed from the
+	// end keeping two rows, with the best step for each i, j.
+	const (
+		stepMatch = iota
+		stepSkipID
+		stepSkipEntry
+	)
+	steps := make([][]byte, n+1)
+	next, cur := make([]int, m+1), make([]int, m+1)
+	for i := n; i >= 0; i-- {
+		steps[i] = make([]byte, m+1)
+		for j := m; j >= 0; j-- {
+			switch {
+			case i == n:
+				cur[j], steps[i][j] = m-j, stepSkipID
+			case j == m:
+				cur[j], steps[i][j] = n-i, stepSkipEntry
+			default:
+				cur[j], steps[i][j] = next[j+1]+matchCost(i, j), stepMatch
+				if c := cur[j+1] + 1; c < cur[j] {
+					cur[j], steps[i][j] = c, stepSkipID
+				}
+				if c := next[j] + 1; c < cur[j] {
+					cur[j], steps[i][j] = c, stepSkipEntry
+				}
+			}
+		}
+		next, cur = cur, next
+	}
+	match := make([]int, n)
+	for i, j := 0, 0; i < n; {
+		switch steps[i][j] {
+		case stepMatch:
+			match[i] = j
+			i++
+			j++
+		case stepSkipID:
+			j++
+		default:
+			match[i] = -1
+			i++
+		}
+	}
+	return match
+}
+
+// writeArchiveRedirects writes archive-redirects.txt into dir with lines
+// of old archive paths and new URLs of entries matched by ID.
+func writeArchiveRedirects(dir string) error {
+	if *archiveIDsFlag == "" {
+		return nil
+	}
+	ids, err := readArchiveIDs(*archiveIDsFlag)
+	if err != nil {
+		return err
+	}
+	archiveEntriesMu.Lock()
+	entries := append([]archiveEntry(nil), archiveEntries...)
+	archiveEntriesMu.Unlock()
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+	var buf bytes.Buffer
+	matched := 0
+	for i, j := range matchArchiveIDs(entries, ids) {
+		if j < 0 {
+			rep.addf(entries[i].File, "archive-id", "no archive ID found")
+			continue
+		}
+		if d := ids[j].Date; !d.IsZero() && d.Format("2006-01-02") != entries[i].Date.Format("2006-01-02") {
+			rep.addf(entries[i].File, "archive-id", "guessed %s, but its sitemap date is %s", ids[j].Path, d.Format("2006-01-02"))
+		}
+		fmt.Fprintf(&buf, "%s %s\n", ids[j].Path, entries[i].URL)
+		addRedirect(ids[j].Path, entries[i].URL)
+		matched++
+	}
+	log.Printf("Matched %d of %d entries with %d archive IDs", matched, len(entries), len(ids))
+	return os.WriteFile(filepath.Join(dir, "archive-redirects.txt"), buf.Bytes(), 0644)
+}
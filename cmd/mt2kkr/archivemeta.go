@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var archiveMetaFlag = flag.Bool("archive-meta", false, "write archive-meta.json with archive statistics to output directory")
+
+type archiveGap struct {
+	From  string    `json:"from"` // file names of posts around the gap
+	To    string    `json:"to"`
+	Days  int       `json:"days"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// archiveMeta describes the whole converted archive.
+type archiveMeta struct {
+	Count      int            `json:"count"`
+	First      time.Time      `json:"first"`
+	Last       time.Time      `json:"last"`
+	Years      map[string]int `json:"years"`
+	LongestGap *archiveGap    `json:"longest_gap,omitempty"`
+}
+
+// computeArchiveMeta returns statistics of converted entries.
+func computeArchiveMeta() *archiveMeta {
+	rep.mu.Lock()
+	entries := append([]reportEntry(nil), rep.entries...)
+	rep.mu.Unlock()
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+	m := &archiveMeta{Count: len(entries), Years: make(map[string]int)}
+	if len(entries) == 0 {
+		return m
+	}
+	m.First, m.Last = entries[0].Date, entries[len(entries)-1].Date
+	for i, e := range entries {
+		m.Years[strconv.Itoa(e.Date.Year())]++
+		if i == 0 {
+			continue
+		}
+		prev := entries[i-1]
+		d := e.Date.Sub(prev.Date)
+		if m.LongestGap == nil || d > m.LongestGap.End.Sub(m.LongestGap.Start) {
+			m.LongestGap = &archiveGap{prev.File, e.File, int(d.Hours() / 24), prev.Date, e.Date}
+		}
+	}
+	return m
+}
+
+func writeArchiveMeta(dir string) error {
+	if !*archiveMetaFlag {
+		return nil
+	}
+	b, err := json.MarshalIndent(computeArchiveMeta(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "archive-meta.json"), append(b, '\n'), 0644)
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/dchest/mt2kkr/output"
+)
+
+var (
+	mirrorAssetsFlag     = flag.String("mirror-assets", "", "download images referenced in bodies into `dir`")
+	assetsURLFlag        = flag.String("assets-url", "/assets/", "URL `prefix` for mirrored images")
+	brokenImagesFlag     = flag.String("broken-images", "keep", "`policy` for images that fail to download: keep, placeholder or drop")
+	placeholderImageFlag = flag.String("placeholder-image", "/assets/broken.png", "placeholder image `url` for -broken-images=placeholder")
+	hashAssetsFlag       = flag.Bool("hash-assets", false, "name mirrored images by content hash")
+	assetsMapFlag        = flag.String("assets-map", "", "write JSON mapping of image URLs to mirrored files to `file`")
+)
+
+var (
+	imgRe    = regexp.MustCompile(`(?i)<img\b[^>]*>`)
+	imgSrcRe = regexp.MustCompile(`(?i)(\ssrc\s*=\s*)("[^"]*"|'[^']*'|[^\s>]+)`)
+)
+
+var (
+	mirroredMu sync.Mutex
+	// mirrored maps image URLs to local file names, or to an empty
+	// string for images that failed to download.
+	mirrored = make(map[string]string)
+	// usedAssetNames records local file names of mirrored images.
+	usedAssetNames = make(map[string]bool)
+	// assetsByHash maps content hashes to local file names, so that
+	// images with different URLs but the same content share a file.
+	assetsByHash = make(map[string]string)
+	// pendingAssets are images being downloaded for some entry.
+	pendingAssets = make(map[string]*pendingAsset)
+)
+
+// pendingAsset is an image download in progress. Other entries
+// referencing the same URL wait for it instead of downloading again.
+type pendingAsset struct {
+	done chan struct{}
+}
+
+func setupAssets() error {
+	switch *brokenImagesFlag {
+	case "keep", "placeholder", "drop":
+	default:
+		return fmt.Errorf("unknown -broken-images policy %q", *brokenImagesFlag)
+	}
+	if *mirrorAssetsFlag == "" {
+		return nil
+	}
+	if err := os.MkdirAll(*mirrorAssetsFlag, 0755); err != nil {
+		return err
+	}
+	return loadAssetsState()
+}
+
+// assetName returns a local file name for the image URL.
+func assetName(u *url.URL) string {
+	mirroredMu.Lock()
+	defer mirroredMu.Unlock()
+	name := output.SafeName(path.Base(u.Path))
+	if name == "" || name == "." || name == "-" {
+		name = "image"
+	}
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; usedAssetNames[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	usedAssetNames[strings.ToLower(name)] = true
+	return name
+}
+
+// download fetches the image into the mirror directory, saving it
+// under name, or under content hash name with -hash-assets. It returns
+// the local name and hex-encoded SHA-256 hash of content.
+func download(ctx context.Context, rawurl, name string) (string, string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", "", err
+	}
+	var sum string
+	err = fetch(ctx, rawurl, func(r io.Reader) error {
+		if *hashAssetsFlag {
+			name, sum, err = saveHashed(u, r)
+			return err
+		}
+		// Download into a temporary file, so that failed downloads
+		// don't leave partial images.
+		f, err := os.CreateTemp(*mirrorAssetsFlag, ".download-")
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		_, err = io.Copy(f, io.TeeReader(r, h))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Chmod(f.Name(), 0644)
+		}
+		if err == nil {
+			err = os.Rename(f.Name(), filepath.Join(*mirrorAssetsFlag, name))
+		}
+		if err != nil {
+			os.Remove(f.Name())
+			return err
+		}
+		sum = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	return name, sum, err
+}
+
+// saveHashed saves image into a file named after the hash of its
+// content, keeping the extension from URL.
+func saveHashed(u *url.URL, r io.Reader) (name, sum string, err error) {
+	f, err := os.CreateTemp(*mirrorAssetsFlag, ".download-")
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(f, io.TeeReader(r, h)); err != nil {
+		f.Close()
+		return "", "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", "", err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return "", "", err
+	}
+	sum = hex.EncodeToString(h.Sum(nil))
+	name = sum[:16] + strings.ToLower(path.Ext(u.Path))
+	if err := os.Rename(f.Name(), filepath.Join(*mirrorAssetsFlag, name)); err != nil {
+		return "", "", err
+	}
+	return name, sum, nil
+}
+
+// fileHash returns hex-encoded SHA-256 hash of the mirrored file.
+func fileHash(name string) (string, error) {
+	f, err := os.Open(filepath.Join(*mirrorAssetsFlag, name))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeAssetsMap writes mapping of image URLs to mirrored file names to
+// the file given by -assets-map. Broken images map to null.
+func writeAssetsMap() error {
+	if *assetsMapFlag == "" {
+		return nil
+	}
+	mirroredMu.Lock()
+	defer mirroredMu.Unlock()
+	m := make(map[string]*string, len(mirrored))
+	for u, name := range mirrored {
+		if name != "" {
+			name := name
+			m[u] = &name
+		} else {
+			m[u] = nil
+		}
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*assetsMapFlag, append(b, '\n'), 0644)
+}
+
+// localImageURL returns the URL of the mirrored image, or an empty
+// string if it failed to download.
+func localImageURL(rawurl string) string {
+	mirroredMu.Lock()
+	name := mirrored[rawurl]
+	mirroredMu.Unlock()
+	if name == "" {
+		return ""
+	}
+	return *assetsURLFlag + name
+}
+
+func imageSrc(tag []byte) (src string, ok bool) {
+	sm := imgSrcRe.FindSubmatch(tag)
+	if sm == nil {
+		return "", false
+	}
+	src = html.UnescapeString(strings.Trim(string(sm[2]), `"'`))
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return "", false
+	}
+	return src, true
+}
+
+// mirrorImages concurrently downloads images referenced in body that
+// weren't mirrored yet, each unique URL once, even if entries are
+// processed in parallel. File names are assigned, duplicate content
+// merged and failures reported in order of appearance, so that results
+// don't depend on download timing.
+func mirrorImages(ctx context.Context, filename string, body []byte) {
+	type job struct {
+		src, name, sum string
+		err            error
+		pending        *pendingAsset
+	}
+	var jobs []*job
+	var waits []*pendingAsset
+	seen := make(map[string]bool)
+	for _, tag := range imgRe.FindAll(body, -1) {
+		src, ok := imageSrc(tag)
+		if !ok || seen[src] {
+			continue
+		}
+		seen[src] = true
+		mirroredMu.Lock()
+		_, done := mirrored[src]
+		p := pendingAssets[src]
+		if !done && p == nil {
+			p = &pendingAsset{done: make(chan struct{})}
+			pendingAssets[src] = p
+			jobs = append(jobs, &job{src: src, pending: p})
+		} else if p != nil {
+			waits = append(waits, p)
+		}
+		mirroredMu.Unlock()
+	}
+	for _, j := range jobs {
+		if u, err := url.Parse(j.src); err == nil && !*hashAssetsFlag {
+			j.name = assetName(u)
+		}
+	}
+	workers := make(chan struct{}, max(*assetConcurrencyFlag, 1))
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workers <- struct{}{}
+			j.name, j.sum, j.err = download(ctx, j.src, j.name)
+			<-workers
+		}()
+	}
+	wg.Wait()
+	mirroredMu.Lock()
+	for _, j := range jobs {
+		switch {
+		case j.err != nil:
+			rep.addf(filename, "assets", "broken image %s: %s", j.src, j.err)
+			j.name = ""
+		case assetsByHash[j.sum] != "" && assetsByHash[j.sum] != j.name:
+			// Same content as an image mirrored before.
+			os.Remove(filepath.Join(*mirrorAssetsFlag, j.name))
+			log.Printf("Image %s is the same as %s", j.src, assetsByHash[j.sum])
+			j.name = assetsByHash[j.sum]
+		default:
+			assetsByHash[j.sum] = j.name
+		}
+		mirrored[j.src] = j.name
+		delete(pendingAssets, j.src)
+		close(j.pending.done)
+	}
+	mirroredMu.Unlock()
+	for _, p := range waits {
+		<-p.done
+	}
+}
+
+// placeholderImage returns img tag with -placeholder-image as src,
+// keeping the original src in data-original-src.
+func placeholderImage(tag []byte, src string) []byte {
+	t := imgSrcRe.ReplaceAll(tag, []byte(`${1}"`+html.EscapeString(*placeholderImageFlag)+`"`))
+	// imgRe matches "<img" in any case.
+	return append([]byte(string(t[:4])+` data-original-src="`+html.EscapeString(src)+`"`), t[4:]...)
+}
+
+// mirrorAssets downloads images referenced in body and rewrites their
+// URLs, applying -broken-images policy to images that fail.
+func mirrorAssets(ctx context.Context, filename string, body []byte) ([]byte, error) {
+	if *mirrorAssetsFlag == "" {
+		return body, nil
+	}
+	mirrorImages(ctx, filename, body)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := saveAssetsState(); err != nil {
+		return nil, err
+	}
+	return imgRe.ReplaceAllFunc(body, func(tag []byte) []byte {
+		src, ok := imageSrc(tag)
+		if !ok {
+			return tag
+		}
+		local := localImageURL(src)
+		if local != "" {
+			return imgSrcRe.ReplaceAll(tag, []byte(`${1}"`+html.EscapeString(local)+`"`))
+		}
+		switch *brokenImagesFlag {
+		case "placeholder":
+			return placeholderImage(tag, src)
+		case "drop":
+			rep.addf(filename, "assets", "dropped broken image %s", src)
+			return nil
+		}
+		return tag
+	}), nil
+}
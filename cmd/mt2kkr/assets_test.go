@@ -0,0 +1,14 @@
+package main
+
+import "testing"
+
+func TestPlaceholderImage(t *testing.T) {
+	for _, tt := range []struct{ tag, want string }{
+		{`<img src="http://example.com/a.png" alt="a">`, `<img data-original-src="http://example.com/a.png" src="/assets/broken.png" alt="a">`},
+		{`<IMG SRC=http://example.com/a.png>`, `<IMG data-original-src="http://example.com/a.png" SRC="/assets/broken.png">`},
+	} {
+		if got := string(placeholderImage([]byte(tt.tag), "http://example.com/a.png")); got != tt.want {
+			t.Errorf("placeholderImage(%q) =\n%q, want\n%q", tt.tag, got, tt.want)
+		}
+	}
+}
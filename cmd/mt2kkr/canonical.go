@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+var (
+	canonicalBaseFlag = flag.String("canonical-base", "", "add canonical: field with original entry URL under `url`")
+	canonicalPathFlag = flag.String("canonical-path", "{year}/{month}/{basename}.html", "original entry URL `pattern` for -canonical-base")
+)
+
+// canonicalURL reconstructs the original URL of entry with the given
+// MT basename from -canonical-base and -canonical-path.
+func (e *entry) canonicalURL(basename string) string {
+	path := expandEntryPattern(e.Entry, *canonicalPathFlag, basename)
+	return strings.TrimSuffix(*canonicalBaseFlag, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// expandEntryPattern replaces {year}, {month}, {day} and {basename} in
+// pattern with values of entry.
+func expandEntryPattern(e *mt.Entry, pattern, basename string) string {
+	r := strings.NewReplacer(
+		"{year}", e.Date.Format("2006"),
+		"{month}", e.Date.Format("01"),
+		"{day}", e.Date.Format("02"),
+		"{basename}", basename,
+	)
+	return r.Replace(pattern)
+}
+
+func (e *entry) addCanonical(basename string) {
+	if *canonicalBaseFlag == "" {
+		return
+	}
+	e.Header["canonical"] = strconv.Quote(e.canonicalURL(basename))
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// checkConfig validates config file and options without converting
+// anything, printing all problems found. It's invoked as
+// "mt2kkr check-config [options] config.json".
+func checkConfig(args []string) {
+	flag.CommandLine.Parse(args)
+	if flag.NArg() > 1 || flag.NArg() == 0 && *configFlag == "" {
+		log.Fatal("usage: mt2kkr check-config [options] config.json")
+	}
+	if flag.NArg() == 1 {
+		*configFlag = flag.Arg(0)
+	}
+	var problems []string
+	add := func(err error) {
+		if err == nil {
+			return
+		}
+		var joined interface{ Unwrap() []error }
+		if errors.As(err, &joined) {
+			for _, err := range joined.Unwrap() {
+				problems = append(problems, err.Error())
+			}
+			return
+		}
+		problems = append(problems, err.Error())
+	}
+	add(loadConfig(*configFlag))
+	for _, f := range setupSteps() {
+		add(f())
+	}
+	problems = append(problems, configConflicts()...)
+	problems = append(problems, missingCommands()...)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+	fmt.Printf("%s: OK\n", *configFlag)
+}
+
+// isSet reports whether flag was given on the command line or in config.
+func isSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+	return set
+}
+
+// configConflicts returns problems with options that don't work
+// together or have no effect.
+func configConflicts() []string {
+	var problems []string
+	conflict := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	if *outFlag != "files" {
+		for _, name := range []string{"platform", "access-log", "archive-ids", "html-report", "feed", "json-feed", "digest", "merge", "markers", "source-map"} {
+			if isSet(name) {
+				conflict("-%s has no effect with -out %s, which writes no output directory", name, *outFlag)
+			}
+		}
+	}
+	requires := []struct{ name, needs string }{
+		{"tumblr-media", "from"},
+		{"redirect-status", "platform"},
+		{"redirect-min-hits", "access-log"},
+		{"live-content", "verify-live"},
+		{"live-match", "verify-live"},
+		{"canonical-path", "canonical-base"},
+		{"sample-seed", "sample"},
+		{"toc-length", "toc"},
+		{"toc-headings", "toc"},
+		{"stream-format", "out"},
+		{"placeholder-image", "broken-images"},
+		{"hash-assets", "mirror-assets"},
+	}
+	for _, r := range requires {
+		if isSet(r.name) && !isSet(r.needs) {
+			conflict("-%s has no effect without -%s", r.name, r.needs)
+		}
+	}
+	if *tumblrMediaFlag != "" && *fromFlag != "tumblr" {
+		conflict("-tumblr-media has no effect with -from %s", *fromFlag)
+	}
+	if *dialectFlag != "" && *fromFlag != "mt" {
+		conflict("-dialect has no effect with -from %s", *fromFlag)
+	}
+	if *templateFlag != "" && *presetFlag == "html" {
+		conflict("-template replaces the post template of -preset html; use -template-dir to override only some templates")
+	}
+	return problems
+}
+
+// missingCommands returns problems with external programs used by
+// options that aren't found in PATH.
+func missingCommands() []string {
+	var problems []string
+	commands := map[string][]string{
+		"filter":      filterFlag,
+		"pre-convert": {*preConvertFlag},
+		"post-write":  {*postWriteFlag},
+	}
+	for _, name := range []string{"filter", "pre-convert", "post-write"} {
+		for _, command := range commands[name] {
+			f := strings.Fields(command)
+			if len(f) == 0 || strings.ContainsAny(f[0], "$=`'\\\"") {
+				continue // can't tell without running the shell
+			}
+			if _, err := exec.LookPath(f[0]); err != nil {
+				problems = append(problems, fmt.Sprintf("-%s: %s not found", name, f[0]))
+			}
+		}
+	}
+	if strings.Contains(","+*passesFlag+",", ",textile,") {
+		if _, err := exec.LookPath("redcloth"); err != nil && *converterFallbackFlag == "fail" {
+			problems = append(problems, "redcloth not found; textile entries will fail with -converter-fallback fail")
+		}
+	}
+	return problems
+}
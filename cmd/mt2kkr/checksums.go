@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var checksumsFlag = flag.Bool("checksums", false, "write SHA256SUMS of all files in output directory, to check later with mt2kkr verify")
+
+const checksumsFile = "SHA256SUMS"
+
+func fileSHA256(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// outputFiles returns slash-separated names of files in dir, except
+// SHA256SUMS, sorted.
+func outputFiles(dir string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if name = filepath.ToSlash(name); name != checksumsFile {
+			names = append(names, name)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+// writeChecksums writes SHA256SUMS in the format of sha256sum, so it
+// can be checked with sha256sum -c too. It must be the last write.
+func writeChecksums(dir string) error {
+	if !*checksumsFlag {
+		return nil
+	}
+	names, err := outputFiles(dir)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, name := range names {
+		sum, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&buf, "%s  %s\n", sum, name)
+	}
+	return os.WriteFile(filepath.Join(dir, checksumsFile), buf.Bytes(), 0644)
+}
+
+// verifyChecksums checks files of output directory against its
+// SHA256SUMS, printing changed, missing and unlisted files. It's invoked
+// as "mt2kkr verify outdir".
+func verifyChecksums(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("usage: mt2kkr verify outdir")
+	}
+	dir := fs.Arg(0)
+	f, err := os.Open(filepath.Join(dir, checksumsFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	listed := make(map[string]bool)
+	bad := 0
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		sum, name, ok := strings.Cut(s.Text(), "  ")
+		if !ok {
+			log.Fatalf("%s:%d: bad line", checksumsFile, n)
+		}
+		listed[name] = true
+		got, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(name)))
+		switch {
+		case os.IsNotExist(err):
+			fmt.Printf("%s: missing\n", name)
+			bad++
+		case err != nil:
+			log.Fatal(err)
+		case got != sum:
+			fmt.Printf("%s: changed\n", name)
+			bad++
+		}
+	}
+	if err := s.Err(); err != nil {
+		log.Fatal(err)
+	}
+	names, err := outputFiles(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, name := range names {
+		if !listed[name] {
+			fmt.Printf("%s: not listed\n", name)
+		}
+	}
+	if bad > 0 {
+		log.Fatalf("%d of %d files failed verification", bad, len(listed))
+	}
+	log.Printf("%d files OK", len(listed))
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	commentFeedsFlag     = flag.Bool("comment-feeds", false, "write Atom comment feeds for each post and the whole site")
+	commentFeedNameFlag  = flag.String("comment-feed-name", "{name}.comments.xml", "file name `pattern` of per-post comment feeds, with {name} being output file name without extension")
+	commentsFeedFlag     = flag.String("comments-feed", "comments.xml", "file `name` of site-wide comment feed")
+	commentsFeedSizeFlag = flag.Int("comments-feed-size", 50, "`number` of latest comments in site-wide comment feed")
+)
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Link      []atomLink  `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Content   *atomText   `xml:"content,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Link    []atomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+func (f *atomFeed) write(filename string) error {
+	b, err := xml.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append([]byte(xml.Header), append(b, '\n')...), 0644)
+}
+
+func atomTime(t time.Time) string { return t.Format(time.RFC3339) }
+
+// feedComment is a comment with its post, for site-wide comment feed.
+type feedComment struct {
+	PostTitle string
+	entry     atomEntry
+	date      time.Time
+}
+
+var (
+	feedCommentsMu sync.Mutex
+	feedComments   []feedComment
+)
+
+// commentFeedEntries returns Atom entries for entry comments.
+func (e *entry) commentFeedEntries() []atomEntry {
+	title, _ := strconv.Unquote(e.Header["title"])
+	postURL := e.newURL()
+	ns, _ := parseUUID(e.uuid)
+	var entries []atomEntry
+	for i, c := range e.Comments {
+		anchor := "#comment-" + strconv.Itoa(i+1)
+		ae := atomEntry{
+			Title:     "Comment on " + title + " by " + c.Author,
+			ID:        "urn:uuid:" + newUUID5(ns, anchor).String(),
+			Link:      []atomLink{{Rel: "alternate", Type: "text/html", Href: postURL + anchor}},
+			Published: atomTime(c.Date),
+			Updated:   atomTime(c.Date),
+			Author:    &atomPerson{Name: c.Author, URI: c.URL},
+			Content:   &atomText{Type: "html", Body: c.Content},
+		}
+		entries = append(entries, ae)
+	}
+	return entries
+}
+
+// writeCommentFeed writes Atom feed of entry comments into dir, adding
+// them to the site-wide feed. Drafts have no comment feeds.
+func (e *entry) writeCommentFeed(dir string) error {
+	if !*commentFeedsFlag || len(e.Comments) == 0 || e.Status == "Draft" {
+		return nil
+	}
+	title, _ := strconv.Unquote(e.Header["title"])
+	ns, _ := parseUUID(e.uuid)
+	entries := e.commentFeedEntries()
+	name := strings.Replace(*commentFeedNameFlag, "{name}", strings.TrimSuffix(e.filename, e.route.Ext), -1)
+	feed := &atomFeed{
+		Title:   "Comments on " + title,
+		ID:      "urn:uuid:" + newUUID5(ns, "#comments").String(),
+		Link:    []atomLink{{Rel: "self", Href: siteURL(name)}, {Rel: "alternate", Type: "text/html", Href: e.newURL()}},
+		Updated: atomTime(e.Comments[len(e.Comments)-1].Date),
+		Entries: entries,
+	}
+	feedCommentsMu.Lock()
+	for i, ae := range entries {
+		feedComments = append(feedComments, feedComment{title, ae, e.Comments[i].Date})
+	}
+	feedCommentsMu.Unlock()
+	return feed.write(filepath.Join(dir, name))
+}
+
+// writeSiteCommentFeed writes Atom feed of the latest comments on all
+// posts into dir.
+func writeSiteCommentFeed(dir string) error {
+	if !*commentFeedsFlag {
+		return nil
+	}
+	feedCommentsMu.Lock()
+	defer feedCommentsMu.Unlock()
+	sort.SliceStable(feedComments, func(i, j int) bool { return feedComments[i].date.After(feedComments[j].date) })
+	n := len(feedComments)
+	if *commentsFeedSizeFlag > 0 && n > *commentsFeedSizeFlag {
+		n = *commentsFeedSizeFlag
+	}
+	feed := &atomFeed{
+		Title:   "Comments",
+		ID:      "urn:uuid:" + newUUID5(uuidNamespace, siteURL(*commentsFeedFlag)).String(),
+		Link:    []atomLink{{Rel: "self", Href: siteURL(*commentsFeedFlag)}},
+		Updated: atomTime(now()),
+	}
+	for _, fc := range feedComments[:n] {
+		feed.Entries = append(feed.Entries, fc.entry)
+	}
+	return feed.write(filepath.Join(dir, *commentsFeedFlag))
+}
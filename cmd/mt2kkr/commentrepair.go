@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	repairCommentsFlag  = flag.Bool("repair-comments", false, "repair each comment separately: decode invalid UTF-8 with the most plausible of -comment-charsets, fix mojibake and normalize Unicode, reporting repaired comments")
+	commentCharsetsFlag = flag.String("comment-charsets", "windows-1252", "comma-separated `list` of charsets of comments that aren't UTF-8 for -repair-comments: windows-1252, windows-1250, windows-1251 or koi8-r")
+)
+
+// charsets are characters of bytes 0x80-0xFF of single-byte charsets,
+// with U+FFFD for undefined bytes. Windows-1252 is made from cp1252.
+var charsets = map[string]string{
+	"windows-1250": "€\ufffd‚\ufffd„…†‡\ufffd‰Š‹ŚŤŽŹ\ufffd‘’“”•–—\ufffd™š›śťžź" +
+		"\u00a0ˇ˘Ł¤Ą¦§¨©Ş«¬\u00ad®Ż°±˛ł´µ¶·¸ąş»Ľ˝ľż" +
+		"ŔÁÂĂÄĹĆÇČÉĘËĚÍÎĎĐŃŇÓÔŐÖ×ŘŮÚŰÜÝŢß" +
+		"ŕáâăäĺćçčéęëěíîďđńňóôőö÷řůúűüýţ˙",
+	"windows-1251": "ЂЃ‚ѓ„…†‡€‰Љ‹ЊЌЋЏђ‘’“”•–—\ufffd™љ›њќћџ" +
+		"\u00a0ЎўЈ¤Ґ¦§Ё©Є«¬\u00ad®Ї°±Ііґµ¶·ё№є»јЅѕї" +
+		"АБВГДЕЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯ" +
+		"абвгдежзийклмнопрстуфхцчшщъыьэюя",
+	"koi8-r": "─│┌┐└┘├┤┬┴┼▀▄█▌▐░▒▓⌠■∙√≈≤≥\u00a0⌡°²·÷" +
+		"═║╒ё╓╔╕╖╗╘╙╚╛╜╝╞╟╠╡Ё╢╣╤╥╦╧╨╩╪╫╬©" +
+		"юабцдефгхийклмнопярстужвьызшэщчъ" +
+		"ЮАБЦДЕФГХИЙКЛМНОПЯРСТУЖВЬЫЗШЭЩЧЪ",
+}
+
+var commentCharsets []string
+
+func init() {
+	var b strings.Builder
+	for c := 0x80; c < 0x100; c++ {
+		r := rune(c)
+		for k, v := range cp1252 {
+			if int(v) == c {
+				r = k
+			}
+		}
+		b.WriteRune(r)
+	}
+	charsets["windows-1252"] = b.String()
+}
+
+func setupRepairComments() error {
+	commentCharsets = nil
+	for _, name := range strings.Split(*commentCharsetsFlag, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := charsets[name]; !ok {
+			return fmt.Errorf("unknown charset %q in -comment-charsets", name)
+		}
+		commentCharsets = append(commentCharsets, name)
+	}
+	return nil
+}
+
+// decodeCharset decodes s, which isn't valid UTF-8, keeping its valid
+// UTF-8 sequences, as text can be only partly in the charset.
+func decodeCharset(s, charset string) string {
+	table := []rune(charsets[charset])
+	var b strings.Builder
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if r == utf8.RuneError && size == 1 {
+			r = table[s[0]-0x80]
+		}
+		b.WriteRune(r)
+		s = s[size:]
+	}
+	return b.String()
+}
+
+// plausibility scores decoded text: words mixing scripts or cases (as
+// KOI8-R decoded as Windows-1251 does), Latin words made mostly of
+// accented letters and symbols of undefined bytes or pseudographics
+// lower it.
+func plausibility(s string) int {
+	score := 0
+	s = anyTagRe.ReplaceAllString(s, " ")
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsPunct(r) }) {
+		var latin, accented, other, lower, upperInside int
+		for i, r := range w {
+			if unicode.IsLower(r) {
+				lower++
+			} else if unicode.IsUpper(r) && i > 0 {
+				upperInside++
+			}
+			switch {
+			case r == utf8.RuneError || unicode.In(r, unicode.So, unicode.Sm, unicode.Co):
+				score -= 2
+			case unicode.Is(unicode.Latin, r):
+				latin++
+				if r >= 0x80 {
+					accented++
+				}
+			case unicode.IsLetter(r):
+				other++
+			}
+		}
+		if latin > 0 && other > 0 {
+			score -= 3
+		}
+		if lower > 0 && upperInside > 0 {
+			score -= 2
+		}
+		if accented > 1 && accented*2 > latin {
+			score -= 2
+		}
+	}
+	return score
+}
+
+// repairText returns s as valid UTF-8, decoded with the most plausible
+// of commentCharsets if it isn't, and the charset used.
+func repairText(s string) (string, string) {
+	if utf8.ValidString(s) {
+		return s, ""
+	}
+	best, bestCharset, bestScore := "", "", 0
+	for _, cs := range commentCharsets {
+		d := decodeCharset(s, cs)
+		if score := plausibility(d); bestCharset == "" || score > bestScore {
+			best, bestCharset, bestScore = d, cs, score
+		}
+	}
+	return best, bestCharset
+}
+
+// repairComments repairs encoding of each comment of entry on its own,
+// so that a comment in another charset or with mojibake is fixed
+// without touching the body and other comments.
+func (e *entry) repairComments(filename string) {
+	if !*repairCommentsFlag {
+		return
+	}
+	for i, c := range e.Comments {
+		decoded := ""
+		fixes, removed := 0, 0
+		for _, s := range []*string{&c.Author, &c.Content} {
+			fixed, charset := repairText(*s)
+			if charset != "" {
+				decoded = charset
+			}
+			fixed, n := fixMojibake(fixed)
+			fixes += n
+			fixed, n = normalizeText(fixed)
+			removed += n
+			*s = fixed
+		}
+		var notes []string
+		if decoded != "" {
+			notes = append(notes, "decoded as "+decoded)
+		}
+		if fixes > 0 {
+			notes = append(notes, fmt.Sprintf("fixed %d mojibake sequences", fixes))
+		}
+		if removed > 0 {
+			notes = append(notes, fmt.Sprintf("removed %d invisible characters", removed))
+		}
+		if len(notes) > 0 {
+			rep.addf(filename, "comment-encoding", "comment %d by %q: %s", i+1, c.Author, strings.Join(notes, ", "))
+		}
+	}
+}
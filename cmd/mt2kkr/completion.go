@@ -0,0 +1,190 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// optionFlag is a command-line flag for completions and man page.
+type optionFlag struct {
+	name  string
+	value string // name of value, or empty for boolean flags
+	usage string
+	def   string // default value, if not zero
+}
+
+// optionFlags returns flags of conversion in alphabetical order.
+func optionFlags() []optionFlag {
+	var opts []optionFlag
+	flag.VisitAll(func(f *flag.Flag) {
+		name, usage := flag.UnquoteUsage(f)
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			name = ""
+		} else if name == "" {
+			name = "value"
+		}
+		o := optionFlag{name: f.Name, value: name, usage: usage}
+		switch f.DefValue {
+		case "", "0", "false", "0s":
+		default:
+			o.def = f.DefValue
+		}
+		opts = append(opts, o)
+	})
+	return opts
+}
+
+// publicCommands returns names of subcommands that aren't internal.
+func publicCommands() []command {
+	var cs []command
+	for _, c := range commands() {
+		if c.summary != "" {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// completionCommand prints completion script for shell. It's invoked as
+// "mt2kkr completion bash", e.g. in .bashrc:
+//
+//	source <(mt2kkr completion bash)
+func completionCommand(args []string) {
+	if len(args) != 1 {
+		log.Fatal("usage: mt2kkr completion bash | zsh | fish")
+	}
+	var names, valueFlags, flags []string
+	for _, c := range publicCommands() {
+		names = append(names, c.name)
+	}
+	for _, o := range optionFlags() {
+		flags = append(flags, "-"+o.name)
+		if o.value != "" {
+			valueFlags = append(valueFlags, "-"+o.name)
+		}
+	}
+	w := os.Stdout
+	switch args[0] {
+	case "bash":
+		fmt.Fprintf(w, `_mt2kkr() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+	if [ "$COMP_CWORD" -eq 1 ] && [[ "$cur" != -* ]]; then
+		COMPREPLY=($(compgen -W "%s" -- "$cur") $(compgen -d -- "$cur"))
+		return
+	fi
+	case "$prev" in
+	%s)
+		COMPREPLY=($(compgen -f -- "$cur"))
+		return
+		;;
+	esac
+	if [[ "$cur" == -* ]]; then
+		COMPREPLY=($(compgen -W "%s" -- "$cur"))
+		return
+	fi
+	COMPREPLY=($(compgen -f -- "$cur"))
+}
+complete -o filenames -F _mt2kkr mt2kkr
+`, strings.Join(names, " "), strings.Join(valueFlags, "|"), strings.Join(flags, " "))
+	case "zsh":
+		fmt.Fprintln(w, "#compdef mt2kkr\n\n_arguments \\")
+		zq := strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`)
+		for _, o := range optionFlags() {
+			usage := zq.Replace(firstLine(o.usage))
+			if o.value != "" {
+				fmt.Fprintf(w, "  '-%s[%s]:%s:_files' \\\n", o.name, usage, o.value)
+			} else {
+				fmt.Fprintf(w, "  '-%s[%s]' \\\n", o.name, usage)
+			}
+		}
+		var cs []string
+		for _, c := range publicCommands() {
+			cs = append(cs, c.name+`\:`+zq.Replace(strings.Replace(c.summary, " ", `\ `, -1)))
+		}
+		fmt.Fprintf(w, "  '1: :_alternative \"commands:command:((%s))\" \"dirs:output directory:_files -/\"' \\\n", strings.Join(cs, " "))
+		fmt.Fprintln(w, "  '*:input:_files'")
+	case "fish":
+		fq := strings.NewReplacer(`\`, `\\`, "'", `\'`)
+		for _, c := range publicCommands() {
+			fmt.Fprintf(w, "complete -c mt2kkr -n __fish_use_subcommand -a %s -d '%s'\n", c.name, fq.Replace(c.summary))
+		}
+		for _, o := range optionFlags() {
+			opt := ""
+			if o.value != "" {
+				opt = " -r -F"
+			}
+			fmt.Fprintf(w, "complete -c mt2kkr -o %s%s -d '%s'\n", o.name, opt, fq.Replace(firstLine(o.usage)))
+		}
+	default:
+		log.Fatalf("unknown shell %q (available: bash, zsh, fish)", args[0])
+	}
+}
+
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(s, "\n")
+	return s
+}
+
+// manCommand prints man page in roff format. It's invoked as "mt2kkr man",
+// e.g. mt2kkr man > /usr/local/share/man/man1/mt2kkr.1
+func manCommand(args []string) {
+	if len(args) != 0 {
+		log.Fatal("usage: mt2kkr man")
+	}
+	// roff escapes hyphens and backslashes, and lines can't start with
+	// a dot or apostrophe.
+	esc := func(s string) string {
+		s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+		lines := strings.Split(s, "\n")
+		for i, l := range lines {
+			if strings.HasPrefix(l, ".") || strings.HasPrefix(l, "'") {
+				lines[i] = `\&` + l
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	w := os.Stdout
+	fmt.Fprint(w, `.TH MT2KKR 1
+.SH NAME
+mt2kkr \- convert Movable Type exports into static site posts
+.SH SYNOPSIS
+.B mt2kkr
+[\fIoptions\fR] \fIoutdir\fR [\fIinput.txt\fR | \fIurl\fR]
+.br
+.B mt2kkr
+\fIcommand\fR [\fIarguments\fR]
+.SH DESCRIPTION
+.B mt2kkr
+converts entries and comments of Movable Type export (or, with
+.BR \-from ,
+exports of other blog engines) from input, or standard input, into
+files in
+.IR outdir .
+Options can also be given in JSON file with
+.BR \-config .
+.SH COMMANDS
+`)
+	for _, c := range publicCommands() {
+		fmt.Fprintf(w, ".TP\n.B %s\n", esc(strings.TrimSpace("mt2kkr "+c.name+" "+c.usage)))
+		fmt.Fprintf(w, "%s\n", esc(strings.ToUpper(c.summary[:1])+c.summary[1:]+"."))
+	}
+	fmt.Fprint(w, ".SH OPTIONS\n")
+	for _, o := range optionFlags() {
+		if o.value != "" {
+			fmt.Fprintf(w, ".TP\n.BI %s \" %s\"\n", esc("-"+o.name), esc(o.value))
+		} else {
+			fmt.Fprintf(w, ".TP\n.B %s\n", esc("-"+o.name))
+		}
+		usage := o.usage
+		if o.def != "" {
+			usage += fmt.Sprintf(" (default %s)", o.def)
+		}
+		fmt.Fprintf(w, "%s\n", esc(usage))
+	}
+	fmt.Fprint(w, `.SH SEE ALSO
+https://github.com/dchest/mt2kkr
+`)
+}
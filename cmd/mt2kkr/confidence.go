@@ -0,0 +1,114 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// kindPenalties are confidence penalties of report notes by kind.
+// Notes that don't indicate broken conversion, such as found PII or
+// spelling mistakes, don't lower confidence.
+var kindPenalties = map[string]int{
+	"input":      40,
+	"markup":     40,
+	"write":      40,
+	"live":       30,
+	"html":       25,
+	"sanitize":   20,
+	"mojibake":   20,
+	"modernize":  10,
+	"headings":   10,
+	"assets":     10,
+	"linklog":    5,
+	"photo":      5,
+	"filename":   5,
+	"slug":       5,
+	"hook":       5,
+	"unicode":    0,
+	"archive-id": 0, // noted after conversion
+	"redirect":   0,
+	"pii":        0,
+	"cw":         0,
+	"spell":      0,
+}
+
+func kindPenalty(kind string) int {
+	if p, ok := kindPenalties[kind]; ok {
+		return p
+	}
+	return 10
+}
+
+// confidence returns heuristic confidence from 0 to 100 that entry
+// with the given total penalty of notes was converted correctly.
+func confidence(penalty int) int {
+	if penalty > 100 {
+		return 0
+	}
+	return 100 - penalty
+}
+
+// confidences returns confidence of converted entries by file.
+func (r *report) confidences() map[string]int {
+	conf := make(map[string]int)
+	for _, e := range r.entries {
+		conf[e.File] = e.Confidence
+	}
+	return conf
+}
+
+// sortedItems returns report notes ordered by ascending confidence of
+// their entries, so that the most likely broken ones come first. Notes
+// of files that weren't written have zero confidence.
+func (r *report) sortedItems() []reportItem {
+	conf := r.confidences()
+	items := append([]reportItem(nil), r.items...)
+	sort.SliceStable(items, func(i, j int) bool { return conf[items[i].File] < conf[items[j].File] })
+	return items
+}
+
+// voidTags are HTML elements without closing tags, and tags whose
+// closing tags are optional.
+var voidTags = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+	"p": true, "li": true, "dt": true, "dd": true, "tr": true, "td": true,
+	"th": true, "option": true, "thead": true, "tbody": true,
+}
+
+// checkHTML reports unclosed and stray tags in HTML body.
+func (e *entry) checkHTML(body []byte) {
+	if e.Header["markup"] != "" {
+		return // not HTML
+	}
+	var open, problems []string
+	for _, m := range anyTagRe.FindAllSubmatch(body, -1) {
+		name := strings.ToLower(string(m[2]))
+		if voidTags[name] || strings.HasSuffix(string(m[3]), "/") {
+			continue
+		}
+		if len(m[1]) == 0 {
+			open = append(open, name)
+			continue
+		}
+		i := len(open) - 1
+		for i >= 0 && open[i] != name {
+			i--
+		}
+		if i < 0 {
+			problems = append(problems, "stray </"+name+">")
+			continue
+		}
+		for _, t := range open[i+1:] {
+			problems = append(problems, "unclosed <"+t+">")
+		}
+		open = open[:i]
+	}
+	for _, t := range open {
+		problems = append(problems, "unclosed <"+t+">")
+	}
+	if len(problems) > 0 {
+		rep.addf(e.filename, "html", "unbalanced tags: %s", strings.Join(problems, ", "))
+	}
+}
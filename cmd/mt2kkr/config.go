@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+var configFlag = flag.String("config", "", "read options from JSON `file`")
+
+// loadConfig reads options from JSON file, which contains an object with
+// keys named after command-line flags, with either dashes or
+// underscores, e.g. {"post_write": "optipng $MT2KKR_FILE"}. Arrays set
+// flags that can be repeated. Flags given on the command line take
+// precedence over the config file. Errors in all options are returned
+// together.
+func loadConfig(filename string) error {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var m map[string]interface{}
+	if err := d.Decode(&m); err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, k := range keys {
+		name := strings.Replace(k, "_", "-", -1)
+		if flag.Lookup(name) == nil || name == "config" {
+			errs = append(errs, fmt.Errorf("%s: unknown option %q", filename, k))
+			continue
+		}
+		if set[name] {
+			continue
+		}
+		values, ok := m[k].([]interface{})
+		if !ok {
+			values = []interface{}{m[k]}
+		}
+		for _, v := range values {
+			if err := flag.Set(name, fmt.Sprint(v)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %s", filename, k, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
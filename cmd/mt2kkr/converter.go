@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var (
+	converterTimeoutFlag  = flag.Duration("converter-timeout", time.Minute, "kill external markup converters after `duration` (0 for no limit)")
+	converterRetriesFlag  = flag.Int("converter-retries", 1, "`number` of retries of failed external markup converters")
+	converterFallbackFlag = flag.String("converter-fallback", "raw", "what to do if external converter fails: raw (keep body unconverted), skip (entry) or fail")
+)
+
+func setupConverter() error {
+	switch *converterFallbackFlag {
+	case "raw", "skip", "fail":
+		return nil
+	}
+	return fmt.Errorf("unknown -converter-fallback %q (available: raw, skip, fail)", *converterFallbackFlag)
+}
+
+// runConverter runs external converter command with body as input,
+// returning its output. Hung converters are killed after
+// -converter-timeout, and failed ones are retried.
+func runConverter(ctx context.Context, filename string, body []byte, name string, args ...string) ([]byte, error) {
+	var err error
+	for attempt := 0; ; attempt++ {
+		var out []byte
+		out, err = runConverterOnce(ctx, body, name, args...)
+		if err == nil {
+			return out, nil
+		}
+		if attempt >= *converterRetriesFlag || ctx.Err() != nil {
+			break
+		}
+		log.Printf("%s: %s failed: %s, retrying", filename, name, err)
+	}
+	return nil, err
+}
+
+func runConverterOnce(ctx context.Context, body []byte, name string, args ...string) ([]byte, error) {
+	if *converterTimeoutFlag > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *converterTimeoutFlag)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	if *sandboxFlag {
+		var err error
+		if cmd, err = sandboxCommand(ctx, name, args...); err != nil {
+			return nil, err
+		}
+	}
+	killGroup(cmd)
+	cmd.Stdin = bytes.NewReader(body)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	// Don't wait for children of killed converter holding its output.
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("timed out after %s", *converterTimeoutFlag)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// killGroup does nothing: only the converter itself is killed on this
+// platform.
+func killGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	cwTermsFlag = flag.String("cw-terms", "", "flag entries and comments matching patterns from `file`")
+	cwFieldFlag = flag.Bool("cw-field", false, "add cw: front matter field listing matched -cw-terms")
+)
+
+// cwTerm is a content warning pattern. Name is the pattern as written
+// in the terms file, used in the report and in the cw: field.
+type cwTerm struct {
+	Name string
+	re   *regexp.Regexp
+}
+
+var cwTerms []cwTerm
+
+// loadCWTerms reads content warning patterns, one case-insensitive
+// regular expression per line.
+func loadCWTerms(filename string) error {
+	lines, err := readLines(filename)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		re, err := regexp.Compile("(?i)" + line)
+		if err != nil {
+			return err
+		}
+		cwTerms = append(cwTerms, cwTerm{line, re})
+	}
+	return nil
+}
+
+// matchCWTerms returns names of terms found in text.
+func matchCWTerms(text string) []string {
+	var names []string
+	for _, t := range cwTerms {
+		if t.re.MatchString(text) {
+			names = append(names, t.Name)
+		}
+	}
+	return names
+}
+
+// flagContentWarnings reports entry and comment content matching
+// content warning terms and, if requested, adds the cw: header.
+func (e *entry) flagContentWarnings(filename string, body []byte) {
+	if len(cwTerms) == 0 {
+		return
+	}
+	seen := make(map[string]bool)
+	var all []string
+	add := func(names []string) {
+		for _, n := range names {
+			if !seen[n] {
+				seen[n] = true
+				all = append(all, n)
+			}
+		}
+	}
+	title, _ := strconv.Unquote(e.Header["title"])
+	if names := matchCWTerms(title + "\n" + string(body)); len(names) > 0 {
+		rep.addf(filename, "cw", "entry matches %s", strings.Join(names, ", "))
+		add(names)
+	}
+	for i, c := range e.Comments {
+		if names := matchCWTerms(c.Content); len(names) > 0 {
+			rep.addf(filename, "cw", "comment %d by %s matches %s", i+1, c.Author, strings.Join(names, ", "))
+			add(names)
+		}
+	}
+	if *cwFieldFlag && len(all) > 0 {
+		e.Header["cw"] = quoteList(all)
+	}
+}
+
+// quoteList formats values as a front matter list.
+func quoteList(values []string) string {
+	q := make([]string, len(values))
+	for i, v := range values {
+		q[i] = strconv.Quote(v)
+	}
+	return "[" + strings.Join(q, ", ") + "]"
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var digestFlag = flag.String("digest", "", "write digest of posts to digest.txt in output directory: twtxt or text (`format`)")
+
+func setupDigest() error {
+	switch *digestFlag {
+	case "", "twtxt", "text":
+		return nil
+	}
+	return fmt.Errorf("unknown digest format %q (available: twtxt, text)", *digestFlag)
+}
+
+// writeDigest writes one line per published post, oldest first: date,
+// title and new URL.
+func writeDigest(dir string) error {
+	if *digestFlag == "" {
+		return nil
+	}
+	feedItemsMu.Lock()
+	items := append([]*feedItem(nil), feedItems...)
+	feedItemsMu.Unlock()
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Date.Before(items[j].Date) })
+	var buf bytes.Buffer
+	for _, it := range items {
+		if it.Draft {
+			continue
+		}
+		switch *digestFlag {
+		case "twtxt":
+			fmt.Fprintf(&buf, "%s\t%s %s\n", it.Date.Format(time.RFC3339), oneLine(it.Title), it.URL)
+		case "text":
+			fmt.Fprintf(&buf, "%s  %s  %s\n", it.Date.Format("2006-01-02"), oneLine(it.Title), it.URL)
+		}
+	}
+	return os.WriteFile(filepath.Join(dir, "digest.txt"), buf.Bytes(), 0644)
+}
+
+// oneLine collapses whitespace, including line breaks, in s.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	assetConcurrencyFlag = flag.Int("asset-concurrency", 4, "maximum `number` of concurrent image downloads")
+	hostConcurrencyFlag  = flag.Int("host-concurrency", 1, "maximum `number` of concurrent image downloads per host")
+	assetDelayFlag       = flag.Duration("asset-delay", 0, "minimum `delay` between requests to the same host")
+	assetRetriesFlag     = flag.Int("asset-retries", 3, "`number` of retries for failed image downloads")
+	assetsStateFlag      = flag.String("assets-state", "", "keep image download state in `file` to resume interrupted runs")
+)
+
+// hostGate limits concurrency and request rate for a single host.
+type hostGate struct {
+	sem  chan struct{}
+	mu   sync.Mutex
+	next time.Time // earliest time of the next request
+}
+
+var (
+	gatesMu sync.Mutex
+	gates   = make(map[string]*hostGate)
+)
+
+func gateFor(host string) *hostGate {
+	gatesMu.Lock()
+	defer gatesMu.Unlock()
+	g, ok := gates[host]
+	if !ok {
+		n := *hostConcurrencyFlag
+		if n < 1 {
+			n = 1
+		}
+		g = &hostGate{sem: make(chan struct{}, n)}
+		gates[host] = g
+	}
+	return g
+}
+
+// enter waits for a free slot and the delay since the previous request,
+// or for ctx to be done.
+func (g *hostGate) enter(ctx context.Context) error {
+	select {
+	case g.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	g.mu.Lock()
+	start := time.Now()
+	if g.next.After(start) {
+		start = g.next
+	}
+	g.next = start.Add(*assetDelayFlag)
+	g.mu.Unlock()
+	t := time.NewTimer(time.Until(start))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		g.leave()
+		return ctx.Err()
+	}
+}
+
+func (g *hostGate) leave() { <-g.sem }
+
+// fetch gets the URL and calls save with response body, respecting
+// per-host limits. Network errors, including ones reading the body, and
+// 5xx and 429 responses are retried with exponential backoff.
+func fetch(ctx context.Context, rawurl string, save func(io.Reader) error) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+	g := gateFor(u.Host)
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		retry, err := fetchOnce(ctx, g, rawurl, save)
+		if err == nil || !retry || attempt >= *assetRetriesFlag || ctx.Err() != nil {
+			if err != nil {
+				stats.AssetsFailed.Add(1)
+			} else {
+				stats.AssetsFetched.Add(1)
+			}
+			return err
+		}
+		stats.AssetRetries.Add(1)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		backoff *= 2
+	}
+}
+
+func fetchOnce(ctx context.Context, g *hostGate, rawurl string, save func(io.Reader) error) (retry bool, err error) {
+	if err := g.enter(ctx); err != nil {
+		return false, err
+	}
+	defer g.leave()
+	resp, err := httpGet(ctx, rawurl)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("%s", resp.Status)
+	}
+	body := &bodyReader{r: countingReader{resp.Body, &stats.AssetBytes}}
+	if err := save(body); err != nil {
+		// Retry errors of reading the body, but not of saving it.
+		return body.err != nil, err
+	}
+	return false, nil
+}
+
+// bodyReader records the error of reading response body.
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+// loadAssetsState reads previously mirrored images from -assets-state.
+func loadAssetsState() error {
+	if *assetsStateFlag == "" {
+		return nil
+	}
+	b, err := os.ReadFile(*assetsStateFlag)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var state map[string]string
+	if err := json.Unmarshal(b, &state); err != nil {
+		return fmt.Errorf("%s: %s", *assetsStateFlag, err)
+	}
+	for u, name := range state {
+		sum, err := fileHash(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		mirrored[u] = name
+		usedAssetNames[strings.ToLower(name)] = true
+		if old := assetsByHash[sum]; old == "" || name < old {
+			assetsByHash[sum] = name
+		}
+	}
+	return nil
+}
+
+// saveAssetsState writes successfully mirrored images to -assets-state.
+// Failed images are not saved, so they are retried on the next run.
+func saveAssetsState() error {
+	if *assetsStateFlag == "" {
+		return nil
+	}
+	mirroredMu.Lock()
+	state := make(map[string]string, len(mirrored))
+	for u, name := range mirrored {
+		if name != "" {
+			state[u] = name
+		}
+	}
+	mirroredMu.Unlock()
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := *assetsStateFlag + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, *assetsStateFlag)
+}
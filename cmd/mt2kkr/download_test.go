@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestDownloadRetriesBody checks that a download cut off in the middle
+// of the body is retried and leaves no partial file.
+func TestDownloadRetriesBody(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			// Promise more than is sent and drop the connection.
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte("partial"))
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte("image"))
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	defer func(s string) { *mirrorAssetsFlag = s }(*mirrorAssetsFlag)
+	*mirrorAssetsFlag = dir
+	name, _, err := download(context.Background(), srv.URL+"/a.jpg", "a.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("%d requests, want 2", n)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil || string(b) != "image" {
+		t.Errorf("file %q, %v", b, err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("files left: %v", files)
+	}
+}
+
+// TestHostGateCancel checks that waiting for the next request to a host
+// stops on cancellation.
+func TestHostGateCancel(t *testing.T) {
+	defer func(d time.Duration) { *assetDelayFlag = d }(*assetDelayFlag)
+	*assetDelayFlag = time.Hour
+	g := &hostGate{sem: make(chan struct{}, 1)}
+	if err := g.enter(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	g.leave()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := g.enter(ctx); err == nil {
+		t.Error("entered before the delay")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("cancellation took %s", d)
+	}
+	if len(g.sem) != 0 {
+		t.Error("slot is kept after cancellation")
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dchest/mt2kkr/mt"
+	"github.com/dchest/mt2kkr/output"
+)
+
+var passphraseFileFlag = flag.String("passphrase-file", "", "read passphrase for -encrypt-category from `file` (default $MT2KKR_PASSPHRASE)")
+
+var encryptCategoryFlag listFlag
+
+func init() {
+	flag.Var(&encryptCategoryFlag, "encrypt-category", "encrypt entries in `category` into .enc files with passphrase, listing them in encrypted.txt (can repeat)")
+}
+
+var (
+	encryptedMu sync.Mutex
+	// encryptedFiles are names of encrypted output files.
+	encryptedFiles []string
+)
+
+// readPassphrase returns passphrase from -passphrase-file or environment.
+func readPassphrase() (string, error) {
+	if *passphraseFileFlag == "" {
+		if p := os.Getenv("MT2KKR_PASSPHRASE"); p != "" {
+			return p, nil
+		}
+		return "", errors.New("no passphrase: use -passphrase-file or set MT2KKR_PASSPHRASE")
+	}
+	b, err := os.ReadFile(*passphraseFileFlag)
+	if err != nil {
+		return "", err
+	}
+	p := strings.TrimRight(string(b), "\r\n")
+	if p == "" {
+		return "", fmt.Errorf("%s: empty passphrase", *passphraseFileFlag)
+	}
+	return p, nil
+}
+
+func setupEncrypt() error {
+	if len(encryptCategoryFlag) == 0 {
+		return nil
+	}
+	if *outFlag != "files" {
+		return errors.New("-encrypt-category requires -out files")
+	}
+	if *verifyReproducibleFlag {
+		// Salt and nonces are random, so files differ on each run.
+		return errors.New("-encrypt-category output isn't reproducible, it can't be used with -verify-reproducible")
+	}
+	passphrase, err := readPassphrase()
+	if err != nil {
+		return err
+	}
+	if outputOpts.Encrypter, err = output.NewEncrypter(passphrase); err != nil {
+		return err
+	}
+	outputOpts.EncryptCategories = encryptCategoryFlag
+	return nil
+}
+
+// encrypts reports whether entry is written encrypted.
+func encrypts(e *mt.Entry) bool { return outputOpts.Encrypts(e) }
+
+func addEncryptedFile(filename string) {
+	encryptedMu.Lock()
+	encryptedFiles = append(encryptedFiles, filename)
+	encryptedMu.Unlock()
+}
+
+// writeEncryptedList writes names of encrypted files to encrypted.txt.
+func writeEncryptedList(dir string) error {
+	if outputOpts.Encrypter == nil {
+		return nil
+	}
+	sort.Strings(encryptedFiles)
+	var buf bytes.Buffer
+	for _, f := range encryptedFiles {
+		buf.WriteString(f + "\n")
+	}
+	return os.WriteFile(filepath.Join(dir, "encrypted.txt"), buf.Bytes(), 0644)
+}
+
+// decryptFiles prints decrypted contents of files. It's invoked as
+// "mt2kkr decrypt [-passphrase-file file] file.html.enc...".
+func decryptFiles(args []string) {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	fs.StringVar(passphraseFileFlag, "passphrase-file", "", "read passphrase from `file` (default $MT2KKR_PASSPHRASE)")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		log.Fatal("usage: mt2kkr decrypt [-passphrase-file file] file.enc...")
+	}
+	passphrase, err := readPassphrase()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, name := range fs.Args() {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		out, err := output.Decrypt(passphrase, data)
+		if err != nil {
+			log.Fatalf("%s: %s", name, err)
+		}
+		os.Stdout.Write(out)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// extractEntries prints raw MT text of entries with the given slug. It's
+// invoked as "mt2kkr extract -slug slug input.txt".
+func extractEntries(args []string) {
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	slug := fs.String("slug", "", "extract entries with basename or title slug `slug`")
+	fs.Parse(args)
+	if *slug == "" || fs.NArg() > 1 {
+		log.Fatal("usage: mt2kkr extract -slug slug [input.txt]")
+	}
+	in, err := openInput(context.Background(), fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, err := io.ReadAll(in)
+	in.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	want := strings.Replace(*slug, "_", "-", -1)
+	found := false
+	p := mt.NewParser(bytes.NewReader(data))
+	p.Lenient = true
+	for {
+		e, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		name := strings.Replace(e.Slug, "_", "-", -1)
+		if name == "" {
+			name, _ = slugify(e.Title)
+		}
+		if name != want {
+			continue
+		}
+		found = true
+		for _, line := range lines[e.Src[0].Start-1 : min(p.Line(), len(lines))] {
+			os.Stdout.WriteString(line)
+		}
+	}
+	if !found {
+		log.Fatalf("no entry with slug %q", *slug)
+	}
+}
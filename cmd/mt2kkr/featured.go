@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+)
+
+var (
+	featuredFlag       listFlag
+	featuredWeightFlag = flag.Int("featured-weight", 0, "also add weight: `n` to featured entries")
+)
+
+func init() {
+	flag.Var(&featuredFlag, "featured", "mark entries with category or keyword `name` as featured: true and remove the category (can repeat)")
+}
+
+func isFeatured(name string) bool {
+	for _, f := range featuredFlag {
+		if name == f {
+			return true
+		}
+	}
+	return false
+}
+
+// addFeatured maps featured categories and keywords to front matter
+// fields, removing such categories from the category list.
+func (e *entry) addFeatured() {
+	if len(featuredFlag) == 0 {
+		return
+	}
+	featured := false
+	for _, k := range e.Keywords {
+		if isFeatured(k) {
+			featured = true
+		}
+	}
+	cats := e.Categories[:0]
+	for _, c := range e.Categories {
+		if isFeatured(c) {
+			featured = true
+		} else {
+			cats = append(cats, c)
+		}
+	}
+	e.Categories = cats
+	if !featured {
+		return
+	}
+	e.Header["featured"] = "true"
+	if *featuredWeightFlag != 0 {
+		e.Header["weight"] = strconv.Itoa(*featuredWeightFlag)
+	}
+	if p, _ := strconv.Unquote(e.Header["primary_category"]); isFeatured(p) {
+		delete(e.Header, "primary_category")
+	}
+	if c, _ := strconv.Unquote(e.Header["category"]); isFeatured(c) {
+		delete(e.Header, "category")
+		if len(cats) > 0 {
+			e.Header["category"] = strconv.Quote(cats[len(cats)-1])
+		}
+	}
+}
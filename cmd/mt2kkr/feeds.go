@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"html"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	feedFlag      = flag.Bool("feed", false, "write Atom feed of posts to feed.xml in output directory")
+	jsonFeedFlag  = flag.Bool("json-feed", false, "write JSON Feed 1.1 of posts to feed.json in output directory")
+	feedSizeFlag  = flag.Int("feed-size", 0, "`number` of latest posts in feeds (0 for all)")
+	feedTitleFlag = flag.String("feed-title", "Archive", "feed `title`")
+)
+
+// feedItem is a converted post for feeds.
+type feedItem struct {
+	ID          string
+	URL         string
+	Title       string
+	Author      string
+	Date        time.Time
+	Content     string
+	Tags        []string
+	Attachments []feedAttachment
+	Draft       bool // not published, so left out
+}
+
+type feedAttachment struct {
+	URL      string `json:"url"`
+	MIMEType string `json:"mime_type"`
+}
+
+var (
+	feedItemsMu sync.Mutex
+	feedItems   []*feedItem
+)
+
+// enclosureRe matches links to media files, which become attachments.
+var enclosureRe = regexp.MustCompile(`(?i)<a\b[^>]*\shref\s*=\s*["']?([^"'\s>]+\.(?:mp3|m4a|ogg|oga|wav|mp4|m4v|mov|webm|pdf))["'\s>]`)
+
+// enclosureTypes are MIME types of attachments. It's not taken from the
+// mime package, which uses system tables, to keep output the same on
+// all platforms.
+var enclosureTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".wav":  "audio/wav",
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".pdf":  "application/pdf",
+}
+
+func enclosures(body []byte) []feedAttachment {
+	var atts []feedAttachment
+	seen := make(map[string]bool)
+	for _, sm := range enclosureRe.FindAllSubmatch(body, -1) {
+		u := html.UnescapeString(string(sm[1]))
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		atts = append(atts, feedAttachment{u, enclosureTypes[strings.ToLower(path.Ext(u))]})
+	}
+	return atts
+}
+
+// addFeedItem records converted entry for feeds and digest.
+func (e *entry) addFeedItem(body []byte) {
+	if !*feedFlag && !*jsonFeedFlag && *digestFlag == "" {
+		return
+	}
+	str := func(key string) string {
+		s, _ := strconv.Unquote(e.Header[key])
+		return s
+	}
+	u := e.newURL()
+	it := &feedItem{
+		ID:          "urn:uuid:" + e.uuid,
+		URL:         u,
+		Title:       str("title"),
+		Author:      str("author"),
+		Date:        e.Date,
+		Content:     string(body),
+		Tags:        e.Tags,
+		Attachments: enclosures(body),
+		Draft:       e.Status == "Draft",
+	}
+	feedItemsMu.Lock()
+	feedItems = append(feedItems, it)
+	feedItemsMu.Unlock()
+}
+
+// latestFeedItems returns up to -feed-size latest published posts,
+// newest first.
+func latestFeedItems() []*feedItem {
+	feedItemsMu.Lock()
+	defer feedItemsMu.Unlock()
+	var items []*feedItem
+	for _, it := range feedItems {
+		if !it.Draft {
+			items = append(items, it)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Date.After(items[j].Date) })
+	if *feedSizeFlag > 0 && len(items) > *feedSizeFlag {
+		items = items[:*feedSizeFlag]
+	}
+	return items
+}
+
+func writeAtomFeed(dir string, items []*feedItem) error {
+	feed := &atomFeed{
+		Title:   *feedTitleFlag,
+		ID:      "urn:uuid:" + newUUID5(uuidNamespace, siteURL("feed.xml")).String(),
+		Link:    []atomLink{{Rel: "self", Href: siteURL("feed.xml")}, {Rel: "alternate", Type: "text/html", Href: siteURL("")}},
+		Updated: atomTime(now()),
+	}
+	for _, it := range items {
+		ae := atomEntry{
+			Title:     it.Title,
+			ID:        it.ID,
+			Link:      []atomLink{{Rel: "alternate", Type: "text/html", Href: it.URL}},
+			Published: atomTime(it.Date),
+			Updated:   atomTime(it.Date),
+			Content:   &atomText{Type: "html", Body: it.Content},
+		}
+		if it.Author != "" {
+			ae.Author = &atomPerson{Name: it.Author}
+		}
+		for _, a := range it.Attachments {
+			ae.Link = append(ae.Link, atomLink{Rel: "enclosure", Type: a.MIMEType, Href: a.URL})
+		}
+		feed.Entries = append(feed.Entries, ae)
+	}
+	return feed.write(filepath.Join(dir, "feed.xml"))
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title,omitempty"`
+	ContentHTML   string           `json:"content_html"`
+	DatePublished string           `json:"date_published"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+	Attachments   []feedAttachment `json:"attachments,omitempty"`
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+func writeJSONFeed(dir string, items []*feedItem) error {
+	feed := &jsonFeed{
+		Version: "https://jsonfeed.org/version/1.1",
+		Title:   *feedTitleFlag,
+		Items:   []jsonFeedItem{},
+	}
+	if *siteURLFlag != "" {
+		feed.HomePageURL = siteURL("")
+		feed.FeedURL = siteURL("feed.json")
+	}
+	for _, it := range items {
+		ji := jsonFeedItem{
+			ID:            it.ID,
+			URL:           it.URL,
+			Title:         it.Title,
+			ContentHTML:   it.Content,
+			DatePublished: atomTime(it.Date),
+			Tags:          it.Tags,
+			Attachments:   it.Attachments,
+		}
+		if it.Author != "" {
+			ji.Authors = []jsonFeedAuthor{{it.Author}}
+		}
+		feed.Items = append(feed.Items, ji)
+	}
+	b, err := json.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "feed.json"), append(b, '\n'), 0644)
+}
+
+// writeFeeds writes feeds of posts requested by -feed and -json-feed.
+func writeFeeds(dir string) error {
+	if !*feedFlag && !*jsonFeedFlag {
+		return nil
+	}
+	items := latestFeedItems()
+	if *feedFlag {
+		if err := writeAtomFeed(dir, items); err != nil {
+			return err
+		}
+	}
+	if *jsonFeedFlag {
+		return writeJSONFeed(dir, items)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package main
+
+import "flag"
+
+var (
+	maxSlugFlag = flag.Int("max-slug", 200, "maximum slug length in `bytes`")
+	maxPathFlag = flag.Int("max-path", 0, "maximum output file path length in `bytes` (0 for no limit)")
+	extFlag     = flag.String("ext", ".html", "output file `extension`")
+)
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+var filterFlag listFlag
+
+func init() {
+	flag.Var(&filterFlag, "filter", "pipe each entry as JSON through shell `command` and read it back; empty output drops the entry (can repeat)")
+}
+
+// filterTransform returns a transform running command with the entry
+// JSON on stdin. The command writes the changed entry JSON, or nothing
+// or null to drop the entry.
+func filterTransform(command string) mt.Transform {
+	return func(e *mt.Entry) (*mt.Entry, error) {
+		in, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stdin = bytes.NewReader(in)
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("-filter %q: %s: %s", command, e.Slug, err)
+		}
+		out = bytes.TrimSpace(out)
+		if len(out) == 0 || string(out) == "null" {
+			return nil, nil
+		}
+		var ne mt.Entry
+		if err := json.Unmarshal(out, &ne); err != nil {
+			return nil, fmt.Errorf("-filter %q: %s: %s", command, e.Slug, err)
+		}
+		keepHidden(e, &ne)
+		return &ne, nil
+	}
+}
+
+// keepHidden copies fields that aren't in JSON from entry e to ne:
+// password, export header and source lines, of comments if they
+// weren't added or removed.
+func keepHidden(e, ne *mt.Entry) {
+	ne.Password = e.Password
+	ne.Src = e.Src
+	ne.Header = e.Header
+	if len(ne.Comments) == len(e.Comments) {
+		for i, c := range ne.Comments {
+			c.SrcStart, c.SrcEnd = e.Comments[i].SrcStart, e.Comments[i].SrcEnd
+		}
+	}
+}
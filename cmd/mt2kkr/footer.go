@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	footerFlag      = flag.String("footer", "", "append footers from rules `file` to posts")
+	footerFieldFlag = flag.String("footer-field", "", "put footer into front matter `field` instead of body")
+)
+
+// footerRule is a footer template applied to entries dated within
+// [From, To). Zero times mean an open range.
+type footerRule struct {
+	From, To time.Time
+	tmpl     *template.Template
+}
+
+var footerRules []footerRule
+
+// footerFuncs are placeholders replaced for each entry in renderFooter.
+var footerFuncs = template.FuncMap{
+	"date":      func() string { return "" },
+	"title":     func() string { return "" },
+	"author":    func() string { return "" },
+	"permalink": func() string { return "" },
+}
+
+func parseRuleDate(s string) (time.Time, error) {
+	if s == "*" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// loadFooterRules reads footer rules from file. Each line is
+//
+//	FROM TO TEMPLATE
+//
+// where FROM and TO are dates in YYYY-MM-DD format or "*", and TEMPLATE
+// is a text/template that can use {{date}}, {{title}}, {{author}} and
+// {{permalink}}. The first matching rule wins.
+func loadFooterRules(filename string) error {
+	lines, err := readLines(filename)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		f := strings.SplitN(line, " ", 3)
+		if len(f) != 3 {
+			return fmt.Errorf("%s: bad footer rule `%s`", filename, line)
+		}
+		var r footerRule
+		if r.From, err = parseRuleDate(f[0]); err != nil {
+			return err
+		}
+		if r.To, err = parseRuleDate(f[1]); err != nil {
+			return err
+		}
+		if r.tmpl, err = template.New("footer").Funcs(footerFuncs).Parse(f[2]); err != nil {
+			return err
+		}
+		footerRules = append(footerRules, r)
+	}
+	return nil
+}
+
+func (r *footerRule) matches(t time.Time) bool {
+	return (r.From.IsZero() || !t.Before(r.From)) && (r.To.IsZero() || t.Before(r.To))
+}
+
+// renderFooter returns the footer for entry, or an empty string if no
+// rule matches.
+func (e *entry) renderFooter(permalink string) (string, error) {
+	for _, r := range footerRules {
+		if !r.matches(e.Date) {
+			continue
+		}
+		str := func(key string) func() string {
+			return func() string {
+				s, _ := strconv.Unquote(e.Header[key])
+				return s
+			}
+		}
+		r.tmpl.Funcs(template.FuncMap{
+			"date":      func() string { return e.Date.Format("January 2, 2006") },
+			"title":     str("title"),
+			"author":    str("author"),
+			"permalink": func() string { return permalink },
+		})
+		var buf bytes.Buffer
+		if err := r.tmpl.Execute(&buf, nil); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	return "", nil
+}
+
+// addFooter appends the footer to body or puts it into the front
+// matter field given by -footer-field.
+func (e *entry) addFooter(body []byte, permalink string) ([]byte, error) {
+	footer, err := e.renderFooter(permalink)
+	if err != nil || footer == "" {
+		return body, err
+	}
+	if *footerFieldFlag != "" {
+		e.Header[*footerFieldFlag] = strconv.Quote(footer)
+		return body, nil
+	}
+	return append(body, []byte("\n"+generated("footer")+"<div class=\"footer\">"+footer+"</div>\n"+endGenerated("footer"))...), nil
+}
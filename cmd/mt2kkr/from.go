@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dchest/mt2kkr/importer"
+	"github.com/dchest/mt2kkr/mt"
+)
+
+var (
+	fromFlag        = flag.String("from", "mt", "input `format`: mt, or export of another engine: "+strings.Join(importerNames(), ", "))
+	dialectFlag     = flag.String("dialect", "", "MT export `dialect`: typepad accepts extra keys, sections and date formats of TypePad exports and unwraps its image popup links")
+	tumblrMediaFlag = flag.String("tumblr-media", "", "for -from tumblr, use photos from media `dir` of the backup archive instead of their URLs")
+)
+
+func importerNames() []string {
+	names := make([]string, 0, len(importer.Formats))
+	for k := range importer.Formats {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func setupFrom() error {
+	if _, ok := importer.Formats[*fromFlag]; !ok && *fromFlag != "mt" {
+		return fmt.Errorf("unknown -from format %q", *fromFlag)
+	}
+	switch *dialectFlag {
+	case "":
+	case mt.DialectTypePad:
+		// Image links of TypePad point to popup pages, e.g.
+		// http://example.typepad.com/.shared/image.html?/photos/a.jpg
+		re := regexp.MustCompile(`^(https?://[^/]+)/\.shared/image\.html\?(/.*)$`)
+		rewriteRules = append(rewriteRules, rewriteRule{Old: "~" + re.String(), New: "$1$2", re: re})
+	default:
+		return fmt.Errorf("unknown -dialect %q", *dialectFlag)
+	}
+	return nil
+}
+
+// newReader returns reader of entries from input in -from format.
+func newReader(r io.Reader) (importer.Reader, error) {
+	if *fromFlag == "mt" {
+		p := newParser(r)
+		p.Lenient = *outFlag == "stdout"
+		return p, nil
+	}
+	read := importer.Formats[*fromFlag]
+	if *fromFlag == "tumblr" && *tumblrMediaFlag != "" {
+		read = func(r io.Reader) (importer.Reader, error) {
+			return importer.ReadTumblrMedia(r, *tumblrMediaFlag)
+		}
+	}
+	ir, err := read(r)
+	if err != nil {
+		return nil, err
+	}
+	return importer.WithTransforms(ir, transforms), nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// grepEntries prints date, basename and title of entries matching
+// regular expressions, without converting anything. It's invoked as
+// "mt2kkr grep [-title regexp] [-body regexp] [-i] input.txt".
+func grepEntries(args []string) {
+	fs := flag.NewFlagSet("grep", flag.ExitOnError)
+	titleExpr := fs.String("title", "", "print entries with title matching `regexp`")
+	bodyExpr := fs.String("body", "", "print entries with body matching `regexp`")
+	ignoreCase := fs.Bool("i", false, "ignore case")
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		log.Fatal("usage: mt2kkr grep [-title regexp] [-body regexp] [-i] [input.txt]")
+	}
+	compile := func(expr string) *regexp.Regexp {
+		if expr == "" {
+			return nil
+		}
+		if *ignoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return re
+	}
+	titleRe, bodyRe := compile(*titleExpr), compile(*bodyExpr)
+	in, err := openInput(context.Background(), fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	p := mt.NewParser(in)
+	p.Lenient = true
+	for {
+		e, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		title, basename := e.Title, e.Slug
+		if titleRe != nil && !titleRe.MatchString(title) ||
+			bodyRe != nil && !bodyRe.MatchString(e.Text()) {
+			continue
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\n", e.Date.Format("2006-01-02 15:04"), basename, title)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+)
+
+var (
+	headingOffsetFlag     = flag.Int("heading-offset", 0, "shift heading levels in bodies by `n`")
+	normalizeHeadingsFlag = flag.Bool("normalize-headings", false, "make h2 the top heading level in bodies and close skipped levels")
+)
+
+// headingLevels returns levels of headings in body in order.
+func headingLevels(body []byte) []int {
+	var levels []int
+	for _, sm := range headingRe.FindAllSubmatch(body, -1) {
+		level, _ := strconv.Atoi(string(sm[1]))
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+// headingMap returns mapping from old to new heading levels.
+func headingMap(filename string, levels []int) map[int]int {
+	m := make(map[int]int)
+	if *normalizeHeadingsFlag {
+		// Assign consecutive levels starting from 2 to distinct
+		// levels in use, so that h1 h3 becomes h2 h3.
+		var used [7]bool
+		for _, l := range levels {
+			used[l] = true
+		}
+		next := 2
+		for l := 1; l <= 6; l++ {
+			if used[l] {
+				m[l] = next
+				next++
+			}
+		}
+	} else {
+		for l := 1; l <= 6; l++ {
+			m[l] = l
+		}
+	}
+	clamped := false
+	for l, n := range m {
+		n += *headingOffsetFlag
+		if n < 1 {
+			n, clamped = 1, true
+		}
+		if n > 6 {
+			n, clamped = 6, true
+		}
+		m[l] = n
+	}
+	if clamped {
+		rep.addf(filename, "headings", "heading levels clamped to h1-h6")
+	}
+	return m
+}
+
+// reportHeadingAnomalies notes h1 headings and skipped levels.
+func reportHeadingAnomalies(filename string, levels []int) {
+	prev := 1
+	for _, l := range levels {
+		if l == 1 {
+			rep.addf(filename, "headings", "body has h1 heading")
+		} else if l > prev+1 {
+			rep.addf(filename, "headings", "heading skips from h%d to h%d", prev, l)
+		}
+		prev = l
+	}
+}
+
+// normalizeHeadings shifts heading levels in body according to
+// -heading-offset and -normalize-headings.
+func normalizeHeadings(filename string, body []byte) []byte {
+	if *headingOffsetFlag == 0 && !*normalizeHeadingsFlag {
+		return body
+	}
+	levels := headingLevels(body)
+	if len(levels) == 0 {
+		return body
+	}
+	reportHeadingAnomalies(filename, levels)
+	m := headingMap(filename, levels)
+	return headingRe.ReplaceAllFunc(body, func(h []byte) []byte {
+		sm := headingRe.FindSubmatch(h)
+		level, _ := strconv.Atoi(string(sm[1]))
+		n := m[level]
+		return []byte(fmt.Sprintf("<h%d%s>%s</h%d>", n, sm[2], sm[3], n))
+	})
+}
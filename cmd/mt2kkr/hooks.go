@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	preConvertFlag = flag.String("pre-convert", "", "run shell `command` before converting each entry; failure skips the entry")
+	postWriteFlag  = flag.String("post-write", "", "run shell `command` after writing each entry")
+)
+
+// hookEnv returns environment for hook commands describing the entry.
+func (e *entry) hookEnv(dir, filename string) []string {
+	env := os.Environ()
+	for _, k := range []string{"title", "author", "status", "permalink", "uuid"} {
+		v, _ := strconv.Unquote(e.Header[k])
+		env = append(env, "MT2KKR_"+strings.ToUpper(k)+"="+v)
+	}
+	return append(env,
+		"MT2KKR_DATE="+e.Date.Format("2006-01-02T15:04:05"),
+		"MT2KKR_CATEGORIES="+strings.Join(e.Categories, ","),
+		"MT2KKR_COMMENTS="+strconv.Itoa(len(e.Comments)),
+		"MT2KKR_DIR="+dir,
+		"MT2KKR_FILE="+filepath.Join(dir, filename),
+	)
+}
+
+// runHook runs shell command with entry environment.
+func (e *entry) runHook(ctx context.Context, command, dir, filename string) error {
+	return runHook(ctx, command, e.hookEnv(dir, filename))
+}
+
+func runHook(ctx context.Context, command string, env []string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = env
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+var htmlReportFlag = flag.Bool("html-report", false, "write report.html with conversion results to output directory")
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Conversion report</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; }
+table { border-collapse: collapse; width: 100%; }
+td, th { text-align: left; padding: 0.2em 0.5em; border-bottom: 1px solid #ddd; }
+.bar { background: #48c; height: 1em; }
+</style>
+</head>
+<body>
+<h1>Conversion report</h1>
+<p>{{len .Entries}} entries converted, {{len .Items}} notes, {{len .Skipped}} known skips, as of {{.Now.Format "2006-01-02 15:04"}}.</p>
+<h2>Posts per year</h2>
+<table>
+{{range .Years}}<tr><td>{{.Year}}</td><td>{{.Count}}</td><td style="width:70%"><div class="bar" style="width:{{.Percent}}%"></div></td></tr>
+{{end}}</table>
+{{if .Items}}<h2>Notes</h2>
+<table>
+<tr><th>File</th><th>Kind</th><th>Note</th></tr>
+{{range .Items}}<tr><td><a href="{{.File}}">{{.File}}</a></td><td>{{.Kind}}</td><td>{{.Msg}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Skipped}}<h2>Known skips</h2>
+<table>
+<tr><th>Entry</th><th>Reason</th></tr>
+{{range .Skipped}}<tr><td>{{.File}}</td><td>{{.Msg}}</td></tr>
+{{end}}</table>
+{{end}}<h2>Entries</h2>
+<table>
+{{range .Entries}}<tr><td>{{.Date.Format "2006-01-02"}}</td><td><a href="{{.File}}">{{.Title}}</a></td><td>{{.Confidence}}%</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+type yearCount struct {
+	Year    int
+	Count   int
+	Percent int
+}
+
+// writeHTMLReport writes report.html into dir.
+func writeHTMLReport(dir string) error {
+	if !*htmlReportFlag {
+		return nil
+	}
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	counts := make(map[int]int)
+	most := 0
+	for _, e := range rep.entries {
+		counts[e.Date.Year()]++
+		if counts[e.Date.Year()] > most {
+			most = counts[e.Date.Year()]
+		}
+	}
+	var years []yearCount
+	for y, n := range counts {
+		years = append(years, yearCount{y, n, n * 100 / most})
+	}
+	sort.Slice(years, func(i, j int) bool { return years[i].Year < years[j].Year })
+	f, err := os.Create(filepath.Join(dir, "report.html"))
+	if err != nil {
+		return err
+	}
+	err = htmlReportTemplate.Execute(f, map[string]interface{}{
+		"Entries": rep.entries,
+		"Items":   rep.sortedItems(),
+		"Skipped": rep.skipped,
+		"Years":   years,
+		"Now":     now(),
+	})
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
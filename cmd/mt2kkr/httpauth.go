@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	httpHeaderFlag listFlag
+	basicAuthFlag  = flag.String("basic-auth", "", "HTTP basic auth `user:password` for downloads")
+	cookieJarFlag  = flag.String("cookie-jar", "", "read cookies for downloads from Netscape cookies.txt `file`")
+)
+
+func init() {
+	flag.Var(&httpHeaderFlag, "http-header", "add `\"Name: value\"` header to HTTP requests (can repeat)")
+}
+
+// httpClient is used for fetching input and mirroring images.
+var httpClient = http.DefaultClient
+
+func setupHTTP() error {
+	for _, h := range httpHeaderFlag {
+		if !strings.Contains(h, ":") {
+			return fmt.Errorf("bad -http-header `%s`", h)
+		}
+	}
+	if *basicAuthFlag != "" && !strings.Contains(*basicAuthFlag, ":") {
+		return fmt.Errorf("-basic-auth must be user:password")
+	}
+	if *cookieJarFlag == "" {
+		return nil
+	}
+	jar, err := loadCookieJar(*cookieJarFlag)
+	if err != nil {
+		return err
+	}
+	httpClient = &http.Client{Jar: jar}
+	return nil
+}
+
+// loadCookieJar reads cookies from file in Netscape cookies.txt format.
+func loadCookieJar(filename string) (http.CookieJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Split(line, "\t")
+		if len(f) != 7 {
+			return nil, fmt.Errorf("%s: bad cookie line `%s`", filename, line)
+		}
+		domain := strings.TrimPrefix(f[0], ".")
+		c := &http.Cookie{
+			Name:     f[5],
+			Value:    f[6],
+			Path:     f[2],
+			Secure:   f[3] == "TRUE",
+			HttpOnly: httpOnly,
+		}
+		if f[1] == "TRUE" {
+			c.Domain = domain
+		}
+		if exp, err := strconv.ParseInt(f[4], 10, 64); err == nil && exp > 0 {
+			c.Expires = time.Unix(exp, 0)
+		}
+		scheme := "http"
+		if c.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: domain, Path: "/"}, []*http.Cookie{c})
+	}
+	return jar, s.Err()
+}
+
+// httpGet performs GET request with configured headers, basic auth and
+// cookies.
+func httpGet(ctx context.Context, rawurl string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range httpHeaderFlag {
+		kv := strings.SplitN(h, ":", 2)
+		req.Header.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+	if *basicAuthFlag != "" {
+		kv := strings.SplitN(*basicAuthFlag, ":", 2)
+		req.SetBasicAuth(kv[0], kv[1])
+	}
+	return httpClient.Do(req)
+}
+
+// openInput opens input file or URL. An empty name means standard input.
+func openInput(ctx context.Context, name string) (io.ReadCloser, error) {
+	switch {
+	case name == "" || name == "-":
+		return io.NopCloser(os.Stdin), nil
+	case strings.HasPrefix(name, "http://"), strings.HasPrefix(name, "https://"):
+		resp, err := httpGet(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s: %s", name, resp.Status)
+		}
+		return resp.Body, nil
+	default:
+		return os.Open(name)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	linklogCategoryFlag listFlag
+	linklogWordsFlag    = flag.Int("linklog-words", 0, "mark entries with a single link and fewer than `n` words as link posts (0 to disable)")
+)
+
+func init() {
+	flag.Var(&linklogCategoryFlag, "linklog-category", "mark entries with category `name` as link posts (can repeat)")
+}
+
+var (
+	htmlLinkRe     = regexp.MustCompile(`(?i)<a\b[^>]*\shref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	markdownLinkRe = regexp.MustCompile(`\[[^\]]*\]\(([^)\s]+)[^)]*\)`)
+)
+
+// bodyLinks returns URLs of links in HTML or Markdown body.
+func bodyLinks(body []byte) []string {
+	var links []string
+	for _, m := range htmlLinkRe.FindAllSubmatch(body, -1) {
+		links = append(links, html.UnescapeString(string(m[1])+string(m[2])+string(m[3])))
+	}
+	for _, m := range markdownLinkRe.FindAllSubmatch(body, -1) {
+		links = append(links, string(m[1]))
+	}
+	return links
+}
+
+// countWords returns the number of words in body text outside of tags
+// and link URLs.
+func countWords(body []byte) int {
+	text := anyTagRe.ReplaceAll(body, []byte(" "))
+	text = markdownLinkRe.ReplaceAllFunc(text, func(m []byte) []byte {
+		return m[:strings.IndexByte(string(m), ']')+1]
+	})
+	return len(strings.Fields(string(text)))
+}
+
+// addLinklog marks link posts, either in -linklog-category or short
+// ones with a single link, with type: link and link_url of their first
+// link.
+func (e *entry) addLinklog(body []byte) {
+	if len(linklogCategoryFlag) == 0 && *linklogWordsFlag <= 0 {
+		return
+	}
+	links := bodyLinks(body)
+	isLink := false
+	for _, c := range append([]string{e.PrimaryCategory}, e.Categories...) {
+		if contains(linklogCategoryFlag, c) {
+			isLink = true
+		}
+	}
+	if !isLink && *linklogWordsFlag > 0 && len(links) == 1 && countWords(body) < *linklogWordsFlag {
+		isLink = true
+	}
+	if !isLink {
+		return
+	}
+	e.Header["type"] = strconv.Quote("link")
+	if len(links) > 0 {
+		e.Header["link_url"] = strconv.Quote(links[0])
+	} else {
+		rep.addf(e.filename, "linklog", "link post without links")
+	}
+}
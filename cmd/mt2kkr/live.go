@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	liveURLFlag     = flag.String("verify-live", "", "compare converted entries with their live pages at URL `pattern` with {year}, {month}, {day} and {basename}, reporting stale or truncated exports")
+	liveContentFlag = flag.String("live-content", `entry-content|entry-body|entry-more|asset-body|post-body`, "`regexp` of class names of live page elements with entry content for -verify-live")
+	liveMatchFlag   = flag.Int("live-match", 90, "minimum `percent` of live page words that must be in converted entry for -verify-live")
+)
+
+var (
+	liveContentRe  *regexp.Regexp
+	liveClassRe    = regexp.MustCompile(`(?i)\sclass\s*=\s*["']([^"']*)["']`)
+	liveSkipRe     = regexp.MustCompile(`(?is)<(script|style|noscript)\b.*?</(script|style|noscript)\s*>|<!--.*?-->`)
+	liveFallbackRe = regexp.MustCompile(`(?is)<(article|body)\b[^>]*>(.*?)</(article|body)\s*>`)
+)
+
+func setupLive() error {
+	if *liveURLFlag == "" {
+		return nil
+	}
+	re, err := regexp.Compile(`^(?:` + *liveContentFlag + `)$`)
+	if err != nil {
+		return fmt.Errorf("bad -live-content: %s", err)
+	}
+	liveContentRe = re
+	return nil
+}
+
+// liveContent returns HTML of elements of the page that have a class
+// matching -live-content, or of the article or body if there are none.
+func liveContent(page string) string {
+	page = liveSkipRe.ReplaceAllString(page, "")
+	var b strings.Builder
+	end := 0 // end of the last found element, to skip nested ones
+	for _, loc := range anyTagRe.FindAllStringSubmatchIndex(page, -1) {
+		if loc[3] > loc[2] || loc[0] < end {
+			continue
+		}
+		cm := liveClassRe.FindStringSubmatch(page[loc[6]:loc[7]])
+		if cm == nil || !hasClass(cm[1]) {
+			continue
+		}
+		content := elementContent(page[loc[1]:], page[loc[4]:loc[5]])
+		end = loc[1] + len(content)
+		b.WriteString(content)
+		b.WriteString("\n")
+	}
+	if b.Len() == 0 {
+		if m := liveFallbackRe.FindStringSubmatch(page); m != nil {
+			return m[2]
+		}
+		return page
+	}
+	return b.String()
+}
+
+func hasClass(classes string) bool {
+	for _, c := range strings.Fields(classes) {
+		if liveContentRe.MatchString(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// elementContent returns s up to the tag closing the element with the
+// given name.
+func elementContent(s, name string) string {
+	depth := 0
+	for _, loc := range anyTagRe.FindAllStringSubmatchIndex(s, -1) {
+		if !strings.EqualFold(s[loc[4]:loc[5]], name) {
+			continue
+		}
+		if loc[3] == loc[2] {
+			depth++
+			continue
+		}
+		if depth == 0 {
+			return s[:loc[0]]
+		}
+		depth--
+	}
+	return s
+}
+
+// textWords returns lowercase words of HTML text.
+func textWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(stripTags(s)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// verifyLive fetches the live page of entry and reports it if the
+// converted body misses words of the live content.
+func (e *entry) verifyLive(ctx context.Context, body []byte) error {
+	if *liveURLFlag == "" || e.Slug == "" {
+		return nil
+	}
+	url := expandEntryPattern(e.Entry, *liveURLFlag, e.Slug)
+	resp, err := httpGet(ctx, url)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		rep.addf(e.filename, "live", "%s: %s", url, err)
+		return nil
+	}
+	page, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err == nil && resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("%s", resp.Status)
+	}
+	if err != nil {
+		rep.addf(e.filename, "live", "%s: %s", url, err)
+		return nil
+	}
+	live := textWords(liveContent(string(page)))
+	converted := make(map[string]int)
+	for _, w := range textWords(string(body)) {
+		converted[w]++
+	}
+	found := 0
+	for _, w := range live {
+		if converted[w] > 0 {
+			converted[w]--
+			found++
+		}
+	}
+	if len(live) == 0 || found*100 >= *liveMatchFlag*len(live) {
+		return nil
+	}
+	n := len(textWords(string(body)))
+	if n < len(live) {
+		rep.addf(e.filename, "live", "truncated: %d words, live page has %d (%d%% found): %s", n, len(live), found*100/len(live), url)
+	} else {
+		rep.addf(e.filename, "live", "stale: %d%% of live page words found: %s", found*100/len(live), url)
+	}
+	return nil
+}
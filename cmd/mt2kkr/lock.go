@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+var (
+	waitFlag         = flag.Bool("wait", false, "wait for another run using the output directory to finish")
+	failIfLockedFlag = flag.Bool("fail-if-locked", false, "exit with error if another run is using the output directory (default: exit quietly)")
+)
+
+// errLocked is returned by lockFile when the lock is held elsewhere.
+var errLocked = errors.New("locked")
+
+// dirLock keeps the output directory open, and thus locked, until exit.
+var dirLock *os.File
+
+// lockOutput takes an advisory lock on the output directory, so that
+// overlapping runs, e.g. from cron, don't write the same files. It
+// returns false if the directory is locked and this run should quietly
+// exit.
+func lockOutput(dir string) (bool, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return false, err
+	}
+	err = lockFile(f, *waitFlag)
+	if err == errLocked {
+		f.Close()
+		if *failIfLockedFlag {
+			return false, fmt.Errorf("%s is used by another run", dir)
+		}
+		return false, nil
+	}
+	if err != nil {
+		f.Close()
+		return false, err
+	}
+	dirLock = f
+	return true, nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// lockFile does nothing: output directories are not locked on this
+// platform.
+func lockFile(f *os.File, wait bool) error {
+	return nil
+}
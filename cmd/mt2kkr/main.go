@@ -0,0 +1,471 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/dchest/mt2kkr/mt"
+	"github.com/dchest/mt2kkr/output"
+)
+
+type entry struct {
+	*mt.Entry
+	Header     map[string]string // front matter, see output.FrontMatter
+	env        []string          // environment for hooks
+	name       string            // permalink name, from basename or title
+	filename   string            // output file name
+	slug       string            // output file name without date prefix and extension
+	route      *output.Route     // output route, see -routes
+	invisible  int               // characters removed by -normalize-unicode
+	slugNote   string            // how titleSlug made the name, for the report
+	counterDay string            // day numbered by date-counter
+	uuid       string            // stable identifier, see entryUUID
+}
+
+func newEntry(e *mt.Entry) *entry {
+	invisible := 0
+	if *normalizeUnicodeFlag {
+		invisible = normalizeEntry(e)
+	}
+	ne := &entry{Entry: e, Header: output.FrontMatter(e), invisible: invisible}
+	// Excerpts of other engines are subtitles written by hand, unlike
+	// MT excerpts, which are often generated.
+	if *fromFlag != "mt" && e.Excerpt != "" {
+		ne.Header["description"] = strconv.Quote(e.Excerpt)
+	}
+	return ne
+}
+
+// newOutputEntry sets entry of the command as Data of converted entry,
+// sharing its front matter, and skips known entries.
+func newOutputEntry(p *output.Entry) error {
+	if p.Source.Date.After(latestDate) {
+		latestDate = p.Source.Date
+	}
+	e := newEntry(p.Source)
+	if e.skipKnown(e.Slug) {
+		return output.ErrSkip
+	}
+	p.Header = e.Header
+	p.Content = []byte(e.text())
+	p.Data = e
+	return nil
+}
+
+// entryName returns permalink name of entry by -slug-strategy.
+func entryName(p *output.Entry) string {
+	e := p.Data.(*entry)
+	e.name = slugStrategies[e.slugStrategy()](e)
+	return e.name
+}
+
+// prepare is the first pass: it takes output file name of entry,
+// withholding report notes if it's encrypted, checks that
+// password-protected entries will be protected, runs the
+// pre-convert hook and sets UUID.
+func (e *entry) prepare(ctx context.Context, p *output.Entry, dir string) error {
+	e.filename, e.slug, e.route = p.Filename, p.Slug, p.Route
+	if encrypts(e.Entry) {
+		rep.addPrivate(e.filename)
+	}
+	if e.invisible > 0 {
+		rep.addf(e.filename, "unicode", "removed %d invisible characters", e.invisible)
+	}
+	if e.slugNote != "" {
+		rep.addf(e.filename, "slug", "%s", e.slugNote)
+	}
+	if e.Password != "" && !protects {
+		return fmt.Errorf("%s: entry is password-protected, but -passes has no protect", e.filename)
+	}
+	if *preConvertFlag != "" {
+		if err := e.runHook(ctx, *preConvertFlag, dir, e.filename); err != nil {
+			rep.addf(e.filename, "hook", "skipped: pre-convert hook failed: %s", err)
+			return output.ErrSkip
+		}
+	}
+	if e.Slug != "" {
+		e.addUUID(e.Slug)
+	} else {
+		e.addUUID(e.name)
+	}
+	e.env = e.hookEnv(dir, e.filename)
+	return nil
+}
+
+// outputPasses returns passes of conversion into dir: prepare, passes
+// from -passes and counting of converted entries.
+func outputPasses(dir string) []output.Pass {
+	ps := []output.Pass{func(ctx context.Context, p *output.Entry) error {
+		return p.Data.(*entry).prepare(ctx, p, dir)
+	}}
+	for _, run := range runPasses {
+		run := run
+		ps = append(ps, func(ctx context.Context, p *output.Entry) (err error) {
+			p.Content, err = run(ctx, p.Data.(*entry), p.Content)
+			return err
+		})
+	}
+	return append(ps, func(ctx context.Context, p *output.Entry) error {
+		if day := p.Data.(*entry).counterDay; day != "" {
+			dayCounts[day]++
+		}
+		stats.Entries.Add(1)
+		stats.Comments.Add(int64(len(p.Source.Comments)))
+		return nil
+	})
+}
+
+// files writes entries into files in -out files mode.
+var files *output.FileWriter
+
+// newEntryWriter returns the writer for -out mode with options.
+func newEntryWriter(ctx context.Context, dir string, opts output.Options) (output.EntryWriter, error) {
+	fw, err := output.NewFileWriter(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	switch *outFlag {
+	case "stream":
+		return streamWriter{}, nil
+	case "stdout":
+		return &stdoutWriter{fw: fw}, nil
+	}
+	files = fw
+	return fileWriter{ctx, fw}, nil
+}
+
+// fileWriter writes entries into files, adding them to feeds and the
+// report.
+type fileWriter struct {
+	ctx context.Context // for the post-write hook
+	*output.FileWriter
+}
+
+func (w fileWriter) WriteEntry(p *output.Entry) error {
+	log.Printf("Writing %s", p.Filename)
+	if err := w.FileWriter.WriteEntry(p); err != nil {
+		return err
+	}
+	return p.Data.(*entry).written(w.ctx, w.Dir, p.Content)
+}
+
+// written adds entry written into file in dir with body to feeds and
+// the report.
+func (e *entry) written(ctx context.Context, dir string, body []byte) error {
+	filename := e.filename
+	encrypted := encrypts(e.Entry)
+	unlisted := outputOpts.Unlisted(e.Entry)
+	if !unlisted {
+		if err := e.writeCommentFeed(dir); err != nil {
+			return err
+		}
+		e.addFeedItem(body)
+	}
+	if *postWriteFlag != "" {
+		if err := runHook(ctx, *postWriteFlag, e.env); err != nil {
+			rep.addf(filename, "hook", "post-write hook failed: %s", err)
+		}
+	}
+	if encrypted {
+		addEncryptedFile(filename)
+	}
+	if unlisted {
+		// Keep encrypted and password-protected entries out of
+		// feeds, samples, redirects and the report, which lists
+		// titles.
+		return nil
+	}
+	title, _ := strconv.Unquote(e.Header["title"])
+	rep.addEntry(filename, title, e.Date)
+	e.addSample(filename)
+	e.addArchiveEntry()
+	e.addManifestEntry()
+	e.addEntryRedirect()
+	e.addNoindexURL()
+	e.addTranslation(filename)
+	return nil
+}
+
+// importReader converts entries read from r with output.ConvertContext
+// until the end of input or cancellation of ctx. Entries that fail to be
+// written are reported and skipped; their errors are returned together
+// at the end.
+func importReader(ctx context.Context, r io.Reader, dir string) error {
+	opts := outputOpts
+	opts.Dir = dir
+	opts.Input = inputName
+	opts.Reader = func(r io.Reader) (output.Reader, error) { return newReader(r) }
+	opts.New = newOutputEntry
+	opts.Name = entryName
+	opts.Passes = outputPasses(dir)
+	opts.Report = func(filename, kind, msg string) { rep.addf(filename, kind, "%s", msg) }
+	w, err := newEntryWriter(ctx, dir, opts)
+	if err != nil {
+		return err
+	}
+	opts.Writer = w
+	return output.ConvertContext(ctx, r, opts)
+}
+
+// readLines returns non-empty lines of the file, skipping lines
+// starting with '#'.
+func readLines(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, s.Err()
+}
+
+// listFlag is a flag that can be given multiple times.
+type listFlag []string
+
+func (f *listFlag) String() string { return strings.Join(*f, ", ") }
+
+func (f *listFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// setup loads files and validates options given by flags.
+func setup() error {
+	for _, f := range setupSteps() {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupSteps returns setup steps in order.
+func setupSteps() []func() error {
+	return []func() error{
+		setupFiles,
+		setupOutput,
+		setupNow,
+		setupUUID,
+		setupPII,
+		setupProfile,
+		setupNoindex,
+		setupHTTP,
+		setupRewriteAssets,
+		setupTemplate,
+		setupAssets,
+		setupModernize,
+		setupDigest,
+		setupConverter,
+		setupSandbox,
+		setupSpell,
+		setupPasses,
+		setupTransforms,
+		setupRoutes,
+		setupSample,
+		setupFrom,
+		setupLive,
+		setupSlugStrategy,
+		setupScaffold,
+		setupRedirects,
+		setupPlatform,
+		setupEncrypt,
+		setupProtected,
+		setupTranslations,
+		setupMore,
+		setupRepairComments,
+	}
+}
+
+// setupFiles loads files given by flags.
+func setupFiles() error {
+	if *cwTermsFlag != "" {
+		if err := loadCWTerms(*cwTermsFlag); err != nil {
+			return err
+		}
+	}
+	switch *tocFlag {
+	case "", "block", "front":
+	default:
+		return fmt.Errorf("unknown -toc mode %q", *tocFlag)
+	}
+	if *translitFlag != "" {
+		if err := loadTranslit(*translitFlag); err != nil {
+			return err
+		}
+	}
+	if *footerFlag != "" {
+		if err := loadFooterRules(*footerFlag); err != nil {
+			return err
+		}
+	}
+	if *skipListFlag != "" {
+		if err := loadSkipList(*skipListFlag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// command is a subcommand of mt2kkr.
+type command struct {
+	name    string
+	usage   string // arguments
+	summary string // empty for internal commands
+	run     func(args []string)
+}
+
+func commands() []command {
+	return []command{
+		{"serve", "[-addr host:port]", "serve conversion in browser, over HTTP and JSON-RPC",
+			func(args []string) { serveAPI("serve", args) }},
+		{"serve-api", "[-addr host:port]", "same as serve",
+			func(args []string) { serveAPI("serve-api", args) }},
+		{"check-config", "[options] config.json", "validate config file and options", checkConfig},
+		{"grep", "[-title regexp] [-body regexp] [-i] [input.txt]", "print entries matching regexps", grepEntries},
+		{"extract", "-slug slug [input.txt]", "print raw export text of entry", extractEntries},
+		{"anonymize", "[input.txt]", "replace private content of export with lorem ipsum", anonymizeExport},
+		{"completion", "bash | zsh | fish", "print shell completion script", completionCommand},
+		{"decrypt", "[-passphrase-file file] file.enc...", "print decrypted files of -encrypt-category", decryptFiles},
+		{"verify", "outdir", "check output files against SHA256SUMS of -checksums", verifyChecksums},
+		{"man", "", "print man page", manCommand},
+		{"sandbox-exec", "", "", sandboxExec},
+	}
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		for _, c := range commands() {
+			if c.name == os.Args[1] {
+				c.run(os.Args[2:])
+				return
+			}
+		}
+	}
+	flag.Parse()
+	if err := run(flag.Args()); err != nil {
+		if errors.Is(err, context.Canceled) {
+			log.Fatal("interrupted")
+		}
+		log.Fatal(err)
+	}
+}
+
+// run converts input given by args and flags, returning the first error
+// or, for write errors, all of them after writing the rest of output.
+func run(args []string) error {
+	if *configFlag != "" {
+		if err := loadConfig(*configFlag); err != nil {
+			return err
+		}
+	}
+	var dir string
+	if *outFlag == "files" {
+		if len(args) < 1 {
+			return errors.New("usage: mt2kkr outdir [input.txt | url] (default input is stdin)")
+		}
+		dir, args = args[0], args[1:]
+	}
+	if err := setup(); err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		inputName = args[0]
+	}
+	if *verifyReproducibleFlag {
+		return verifyReproducible()
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		ok, err := lockOutput(dir)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.Printf("%s is used by another run, exiting", dir)
+			return nil
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	input, err := openInput(ctx, inputName)
+	if err != nil {
+		return err
+	}
+	writeErr := importReader(ctx, input, dir)
+	input.Close()
+	if writeErr != nil && !onlyEntryErrors(writeErr) {
+		// Report entries converted before the error or interrupt.
+		writeReport()
+		writeStats()
+		return writeErr
+	}
+	// Entries that failed to be written or have sections over
+	// -max-section-size are reported and skipped, so the rest of
+	// output is written, and the errors returned at the end.
+	if err := writeAssetsMap(); err != nil {
+		return errors.Join(writeErr, err)
+	}
+	if dir != "" {
+		for _, write := range []func(dir string) error{
+			writeIndex,
+			writeFeeds,
+			writeDigest,
+			writeSiteCommentFeed,
+			writeArchiveMeta,
+			writeManifest,
+			writeHTMLReport,
+			writeSample,
+			writeArchiveRedirects,
+			writeRedirects,
+			writeScaffold,
+			writePlatformFiles,
+			writeEncryptedList,
+			writeTranslations,
+			writeChecksums,
+		} {
+			if err := write(dir); err != nil {
+				return errors.Join(writeErr, err)
+			}
+		}
+	}
+	if err := writeReport(); err != nil {
+		return errors.Join(writeErr, err)
+	}
+	return errors.Join(writeErr, writeStats())
+}
+
+// onlyEntryErrors reports whether err is *output.WriteError,
+// *output.SpillError or joined errors of them.
+func onlyEntryErrors(err error) bool {
+	if errs, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range errs.Unwrap() {
+			if !onlyEntryErrors(err) {
+				return false
+			}
+		}
+		return true
+	}
+	var we *output.WriteError
+	var se *output.SpillError
+	return errors.As(err, &we) || errors.As(err, &se)
+}
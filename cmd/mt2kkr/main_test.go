@@ -0,0 +1,261 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the command instead of tests when mt2kkrCommand calls
+// the test binary.
+func TestMain(m *testing.M) {
+	if os.Getenv("MT2KKR_TEST_COMMAND") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// mt2kkrCommand runs the command with args in a new directory,
+// converting input into its "out" subdirectory, and returns the
+// directory.
+func mt2kkrCommand(t *testing.T, input string, args ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "input.txt"), []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(os.Args[0], append(args, "out", "input.txt")...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "MT2KKR_TEST_COMMAND=1")
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("mt2kkr %s: %s\n%s", strings.Join(args, " "), err, b)
+	}
+	return dir
+}
+
+// readFile returns contents of file in dir, failing the test if it
+// can't be read.
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+const untitledEntries = `AUTHOR: A
+TITLE: 日本
+STATUS: Publish
+CONVERT BREAKS: 0
+DATE: 04/05/2007 11:00:00 AM
+-----
+BODY:
+<p>First</p>
+-----
+--------
+AUTHOR: A
+TITLE: 日本
+STATUS: Publish
+CONVERT BREAKS: 0
+DATE: 04/05/2007 11:00:00 AM
+-----
+BODY:
+<p>Second</p>
+-----
+--------
+`
+
+// TestSlugReport checks that slug fallbacks are reported against the
+// names of written files.
+func TestSlugReport(t *testing.T) {
+	dir := mt2kkrCommand(t, untitledEntries, "-report", "report.txt", "-slug-strategy", "title")
+	want := []string{
+		"2007-04-05-110000.html\tslug\tno basename, using date-based slug for \"日本\"",
+		"2007-04-05-110000-2.html\tslug\tno basename, using date-based slug for \"日本\"",
+	}
+	report := readFile(t, dir, "report.txt")
+	for _, w := range want {
+		if !strings.Contains(report, w+"\n") {
+			t.Errorf("report has no line %q:\n%s", w, report)
+		}
+	}
+	for _, name := range []string{"2007-04-05-110000.html", "2007-04-05-110000-2.html"} {
+		readFile(t, dir, filepath.Join("out", name))
+	}
+}
+
+// TestEncryptedReport checks that the report has no notes about
+// encrypted entries, which would quote their titles and bodies.
+func TestEncryptedReport(t *testing.T) {
+	entries := `AUTHOR: A
+TITLE: 日本
+STATUS: Publish
+CATEGORY: Private
+CONVERT BREAKS: 0
+DATE: 04/05/2007 11:00:00 AM
+-----
+BODY:
+<p>Secretword cafÃ© spil1ed</p>
+-----
+--------
+AUTHOR: A
+TITLE: Ünïcode
+STATUS: Publish
+CONVERT BREAKS: 0
+DATE: 04/05/2007 11:00:00 AM
+-----
+BODY:
+<p>Publicword cafÃ©</p>
+-----
+--------
+`
+	t.Setenv("MT2KKR_PASSPHRASE", "pw")
+	dict := filepath.Join(t.TempDir(), "en.dic")
+	if err := os.WriteFile(dict, []byte("first\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := mt2kkrCommand(t, entries, "-report", "report.txt", "-html-report",
+		"-encrypt-category", "Private", "-spell-dict", "en="+dict, "-fix-mojibake", "-slug-strategy", "title")
+	for _, name := range []string{"report.txt", "out/report.html"} {
+		report := readFile(t, dir, name)
+		for _, leak := range []string{"日本", "Secretword", "spil1ed", "2007-04-05-110000.html"} {
+			if strings.Contains(report, leak) {
+				t.Errorf("%s has %q of encrypted entry:\n%s", name, leak, report)
+			}
+		}
+		for _, note := range []string{"Ünïcode", "Publicword", "café"} {
+			if !strings.Contains(report, note) {
+				t.Errorf("%s has no %q of public entry:\n%s", name, note, report)
+			}
+		}
+	}
+	readFile(t, dir, "out/2007-04-05-110000.html.enc")
+}
+
+const protectedEntries = `AUTHOR: A
+TITLE: Locked
+BASENAME: locked
+STATUS: Publish
+PASSWORD: pw
+CONVERT BREAKS: 0
+DATE: 04/05/2007 11:00:00 AM
+-----
+BODY:
+<p>Lockedbody</p>
+-----
+COMMENT:
+AUTHOR: Lockedcommenter
+EMAIL: 
+IP: 
+URL: 
+DATE: 04/06/2007 11:00:00 AM
+Lockedcomment
+-----
+--------
+AUTHOR: A
+TITLE: Open
+BASENAME: open
+STATUS: Publish
+CONVERT BREAKS: 0
+DATE: 04/06/2007 11:00:00 AM
+-----
+BODY:
+<p>Openbody</p>
+-----
+COMMENT:
+AUTHOR: Opencommenter
+EMAIL: 
+IP: 
+URL: 
+DATE: 04/07/2007 11:00:00 AM
+Opencomment
+-----
+--------
+`
+
+// TestProtectedUnlisted checks that password-protected entries written
+// with -protected private are left out of feeds and the digest.
+func TestProtectedUnlisted(t *testing.T) {
+	dir := mt2kkrCommand(t, protectedEntries, "-protected", "private", "-feed", "-json-feed", "-digest", "text",
+		"-comment-feeds", "-site-url", "https://example.com/")
+	if s := readFile(t, dir, "out/2007-04-05-locked.html"); !strings.Contains(s, "private: true") {
+		t.Errorf("protected entry isn't private:\n%s", s)
+	}
+	for _, name := range []string{"feed.xml", "feed.json", "digest.txt", "comments.xml"} {
+		s := readFile(t, dir, filepath.Join("out", name))
+		if strings.Contains(s, "Locked") {
+			t.Errorf("%s has protected entry:\n%s", name, s)
+		}
+		if !strings.Contains(s, "Open") {
+			t.Errorf("%s has no public entry:\n%s", name, s)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out/2007-04-05-locked.comments.xml")); err == nil {
+		t.Error("protected entry has comment feed")
+	}
+}
+
+// TestUUIDEverywhere checks that feeds and the manifest identify
+// entries by their front matter UUID.
+func TestUUIDEverywhere(t *testing.T) {
+	dir := mt2kkrCommand(t, protectedEntries, "-protected", "private", "-uuid", "-feed", "-json-feed", "-comment-feeds", "-site-url", "https://example.com/")
+	post := readFile(t, dir, "out/2007-04-06-open.html")
+	m := regexp.MustCompile(`(?m)^uuid: "(.*)"$`).FindStringSubmatch(post)
+	if m == nil {
+		t.Fatalf("no uuid:\n%s", post)
+	}
+	uuid := m[1]
+	ns, _ := parseUUID(uuid)
+	for name, want := range map[string][]string{
+		"feed.xml":                     {"<id>urn:uuid:" + uuid + "</id>"},
+		"feed.json":                    {`"id": "urn:uuid:` + uuid + `"`},
+		"2007-04-06-open.comments.xml": {"<id>urn:uuid:" + newUUID5(ns, "#comments").String() + "</id>", "<id>urn:uuid:" + newUUID5(ns, "#comment-1").String() + "</id>"},
+		"comments.xml":                 {"<id>urn:uuid:" + newUUID5(ns, "#comment-1").String() + "</id>"},
+		"manifest.json":                {`"` + uuid + `": {`, `"file": "2007-04-06-open.html"`},
+	} {
+		s := readFile(t, dir, filepath.Join("out", name))
+		for _, w := range want {
+			if !strings.Contains(s, w) {
+				t.Errorf("%s lacks %s:\n%s", name, w, s)
+			}
+		}
+	}
+}
+
+// TestDraftsUnpublished checks that drafts are left out of feeds,
+// comment feeds and the digest.
+func TestDraftsUnpublished(t *testing.T) {
+	entries := strings.Replace(protectedEntries, "STATUS: Publish\nPASSWORD: pw", "STATUS: Draft", 1)
+	dir := mt2kkrCommand(t, entries, "-feed", "-json-feed", "-digest", "text", "-comment-feeds", "-site-url", "https://example.com/")
+	readFile(t, dir, "out/2007-04-05-locked.html")
+	if _, err := os.Stat(filepath.Join(dir, "out/2007-04-05-locked.comments.xml")); err == nil {
+		t.Error("draft has comment feed")
+	}
+	for _, name := range []string{"feed.xml", "feed.json", "digest.txt", "comments.xml"} {
+		s := readFile(t, dir, filepath.Join("out", name))
+		if strings.Contains(s, "Locked") {
+			t.Errorf("%s has draft:\n%s", name, s)
+		}
+		if !strings.Contains(s, "Open") {
+			t.Errorf("%s has no published entry:\n%s", name, s)
+		}
+	}
+}
+
+// TestDateCounterSkipped checks that date-counter doesn't count entries
+// that aren't written.
+func TestDateCounterSkipped(t *testing.T) {
+	entries := strings.Replace(protectedEntries, "DATE: 04/06/2007 11:00:00 AM\n-----\nBODY:", "DATE: 04/05/2007 11:00:00 AM\n-----\nBODY:", 1)
+	dir := mt2kkrCommand(t, entries, "-slug-strategy", "date-counter")
+	if s := readFile(t, dir, "out/2007-04-05-1.html"); !strings.Contains(s, "Openbody") {
+		t.Errorf("first written entry isn't number 1:\n%s", s)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out/2007-04-05-2.html")); err == nil {
+		t.Error("skipped entry is counted")
+	}
+}
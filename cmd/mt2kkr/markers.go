@@ -0,0 +1,12 @@
+package main
+
+import "flag"
+
+var markersFlag = flag.Bool("markers", false, "wrap generated comments and footer in marker comments, and on re-runs replace only text between markers in existing files")
+
+// generated returns the marker starting generated region name, or an
+// empty string without -markers.
+func generated(name string) string { return outputOpts.Generated(name) }
+
+// endGenerated returns the marker ending generated region name.
+func endGenerated(name string) string { return outputOpts.EndGenerated(name) }
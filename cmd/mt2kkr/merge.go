@@ -0,0 +1,5 @@
+package main
+
+import "flag"
+
+var mergeFlag = flag.Bool("merge", false, "when output file exists, keep front matter fields added to it by hand, replacing only generated fields and body")
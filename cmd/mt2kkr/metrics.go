@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// durationBuckets are upper bounds of conversion duration histogram,
+// in seconds.
+var durationBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600}
+
+// serveMetrics collects statistics of conversions done by serve-api.
+type serveMetrics struct {
+	mu          sync.Mutex
+	conversions int64
+	failures    int64
+	totals      statsSnapshot
+	buckets     []int64 // counts of durations per bucket, not cumulative
+	durationSum float64
+}
+
+var metrics = serveMetrics{buckets: make([]int64, len(durationBuckets)+1)}
+
+// observe records a conversion that took d, adding statistics written
+// by it to statsFile, if it succeeded.
+func (m *serveMetrics) observe(d time.Duration, failed bool, statsFile string) {
+	var s statsSnapshot
+	if b, err := os.ReadFile(statsFile); err == nil {
+		json.Unmarshal(b, &s)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.conversions++
+	if failed {
+		m.failures++
+	}
+	m.totals.Entries += s.Entries
+	m.totals.Comments += s.Comments
+	m.totals.AssetsFetched += s.AssetsFetched
+	m.totals.AssetsFailed += s.AssetsFailed
+	m.totals.AssetRetries += s.AssetRetries
+	m.totals.AssetBytes += s.AssetBytes
+	sec := d.Seconds()
+	i := 0
+	for i < len(durationBuckets) && sec > durationBuckets[i] {
+		i++
+	}
+	m.buckets[i]++
+	m.durationSum += sec
+}
+
+// writeTo writes metrics in Prometheus text format.
+func (m *serveMetrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counter := func(name, help string, v int64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+	}
+	counter("mt2kkr_conversions_total", "Conversions done.", m.conversions)
+	counter("mt2kkr_conversion_failures_total", "Conversions that failed.", m.failures)
+	counter("mt2kkr_entries_total", "Entries converted.", m.totals.Entries)
+	counter("mt2kkr_comments_total", "Comments converted.", m.totals.Comments)
+	counter("mt2kkr_assets_fetched_total", "Assets downloaded.", m.totals.AssetsFetched)
+	counter("mt2kkr_asset_failures_total", "Assets that failed to download.", m.totals.AssetsFailed)
+	counter("mt2kkr_asset_retries_total", "Retried asset downloads.", m.totals.AssetRetries)
+	counter("mt2kkr_asset_bytes_total", "Bytes of downloaded assets.", m.totals.AssetBytes)
+	const h = "mt2kkr_conversion_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Duration of conversions.\n# TYPE %s histogram\n", h, h)
+	var n int64
+	for i, le := range durationBuckets {
+		n += m.buckets[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", h, le, n)
+	}
+	n += m.buckets[len(durationBuckets)]
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %g\n%s_count %d\n", h, n, h, m.durationSum, h, n)
+}
+
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics.writeTo(w)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	modernizeFlag    = flag.Bool("modernize", false, "rewrite presentational tags and inline styles into classes")
+	modernizeMapFlag = flag.String("modernize-map", "", "read -modernize mapping from `file`")
+)
+
+// defaultModernizeMap is the mapping used by -modernize. Lines are either
+//
+//	tag newtag[.class]
+//	style property:value class
+//
+// The first form renames tags, the second replaces inline style
+// declarations with a class.
+const defaultModernizeMap = `
+center div.center
+font span
+b strong
+i em
+u span.underline
+strike del
+s del
+tt code
+style text-align:center text-center
+style text-align:right text-right
+style font-weight:bold bold
+style font-style:italic italic
+style text-decoration:underline underline
+`
+
+type tagMapping struct {
+	Tag, Class string
+}
+
+var (
+	tagMap   map[string]tagMapping
+	styleMap map[string]string
+)
+
+func parseModernizeMap(lines []string) error {
+	tagMap = make(map[string]tagMapping)
+	styleMap = make(map[string]string)
+	for _, line := range lines {
+		f := strings.Fields(line)
+		if len(f) == 3 && f[0] == "style" {
+			styleMap[normalizeStyle(f[1])] = f[2]
+			continue
+		}
+		if len(f) != 2 {
+			return fmt.Errorf("bad modernize mapping `%s`", line)
+		}
+		m := tagMapping{Tag: f[1]}
+		if i := strings.IndexByte(f[1], '.'); i >= 0 {
+			m.Tag, m.Class = f[1][:i], f[1][i+1:]
+		}
+		tagMap[strings.ToLower(f[0])] = m
+	}
+	return nil
+}
+
+func setupModernize() error {
+	if !*modernizeFlag {
+		return nil
+	}
+	if *modernizeMapFlag != "" {
+		lines, err := readLines(*modernizeMapFlag)
+		if err != nil {
+			return err
+		}
+		return parseModernizeMap(lines)
+	}
+	var lines []string
+	for _, line := range strings.Split(defaultModernizeMap, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return parseModernizeMap(lines)
+}
+
+func normalizeStyle(decl string) string {
+	kv := strings.SplitN(decl, ":", 2)
+	if len(kv) != 2 {
+		return strings.ToLower(strings.TrimSpace(decl))
+	}
+	return strings.ToLower(strings.TrimSpace(kv[0]) + ":" + strings.TrimSpace(kv[1]))
+}
+
+var (
+	anyTagRe = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9]*)([^>]*)>`)
+	// attrRe matches attributes, including ones without values, such
+	// as controls, and with prefixes, such as xml:lang.
+	attrRe = regexp.MustCompile(`\s+([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*("[^"]*"|'[^']*'|[^\s>]+))?`)
+)
+
+// fontClassAttrs are <font> attributes turned into classes, such as
+// "font-color-red".
+var fontClassAttrs = map[string]bool{"color": true, "size": true, "face": true}
+
+func classSafe(s string) string {
+	s, _ = slugify(s)
+	return s
+}
+
+// modernizeTag rewrites a single tag according to the mapping.
+func modernizeTag(slash, name, attrs string) (string, bool) {
+	lname := strings.ToLower(name)
+	m, renamed := tagMap[lname]
+	if !renamed {
+		m.Tag = name
+	}
+	if slash != "" {
+		return "</" + m.Tag + ">", renamed
+	}
+	changed := renamed
+	var classes []string
+	if m.Class != "" {
+		classes = append(classes, m.Class)
+	}
+	var kept []string
+	for _, a := range attrRe.FindAllStringSubmatch(attrs, -1) {
+		key := strings.ToLower(a[1])
+		val := strings.Trim(a[2], `"'`)
+		switch {
+		case key == "class":
+			classes = append(classes, strings.Fields(val)...)
+			continue
+		case key == "style":
+			var rest []string
+			for _, decl := range strings.Split(val, ";") {
+				if strings.TrimSpace(decl) == "" {
+					continue
+				}
+				if c, ok := styleMap[normalizeStyle(decl)]; ok {
+					classes = append(classes, c)
+					changed = true
+				} else {
+					rest = append(rest, strings.TrimSpace(decl))
+				}
+			}
+			if len(rest) > 0 {
+				kept = append(kept, ` style="`+strings.Join(rest, "; ")+`"`)
+			}
+			continue
+		case lname == "font" && renamed && fontClassAttrs[key]:
+			classes = append(classes, "font-"+key+"-"+classSafe(val))
+			continue
+		}
+		kept = append(kept, a[0])
+	}
+	if !changed {
+		return "", false
+	}
+	out := "<" + m.Tag
+	if len(classes) > 0 {
+		out += ` class="` + strings.Join(classes, " ") + `"`
+	}
+	out += strings.Join(kept, "")
+	if strings.HasSuffix(strings.TrimSpace(attrs), "/") {
+		out += " /"
+	}
+	return out + ">", true
+}
+
+// modernizeBody rewrites presentational markup in body.
+func modernizeBody(filename string, body []byte) []byte {
+	if tagMap == nil {
+		return body
+	}
+	n := 0
+	body = anyTagRe.ReplaceAllFunc(body, func(tag []byte) []byte {
+		sm := anyTagRe.FindSubmatch(tag)
+		out, ok := modernizeTag(string(sm[1]), string(sm[2]), string(sm[3]))
+		if !ok {
+			return tag
+		}
+		n++
+		return []byte(out)
+	})
+	if n > 0 {
+		rep.addf(filename, "modernize", "rewrote %d tags", n)
+	}
+	return body
+}
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestModernizeKeepsAttrs(t *testing.T) {
+	defer func(m map[string]tagMapping, s map[string]string) { tagMap, styleMap = m, s }(tagMap, styleMap)
+	if err := parseModernizeMap([]string{"center div.center", "b strong", "style text-align:center text-center"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ in, want string }{
+		{`<center xml:lang="en" data-x=1 hidden>`, `<div class="center" xml:lang="en" data-x=1 hidden>`},
+		{`<b lang=en>`, `<strong lang=en>`},
+		{`<video controls style="text-align: center" autoplay>`, `<video class="text-center" controls autoplay>`},
+		{`<script async src="a.js" style="text-align:center">`, `<script class="text-center" async src="a.js">`},
+	} {
+		if got := string(modernizeBody("x.html", []byte(tt.in))); got != tt.want {
+			t.Errorf("modernize %s:\n got %s\nwant %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var fixMojibakeFlag = flag.Bool("fix-mojibake", false, "repair UTF-8 text that was decoded as Latin-1 or Windows-1252 in titles, bodies and comments")
+
+// cp1252 maps characters of Windows-1252 bytes 0x80-0x9F to the bytes.
+var cp1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86,
+	'‡': 0x87, 'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C,
+	'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95,
+	'–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// latin1Byte returns the byte which decoded as Latin-1 or Windows-1252
+// gives r.
+func latin1Byte(r rune) (byte, bool) {
+	if b, ok := cp1252[r]; ok {
+		return b, true
+	}
+	if r < 0x100 {
+		return byte(r), true
+	}
+	return 0, false
+}
+
+// isContinuation reports whether r is a UTF-8 continuation byte
+// decoded as Latin-1 or Windows-1252.
+func isContinuation(r rune) bool {
+	b, ok := latin1Byte(r)
+	return ok && b >= 0x80 && b <= 0xBF
+}
+
+// hasMojibake reports whether s has a UTF-8 lead byte followed by a
+// continuation byte, both decoded as Latin-1 or Windows-1252.
+func hasMojibake(s string) bool {
+	prev := rune(0)
+	for _, r := range s {
+		if prev >= 0xC2 && prev <= 0xF4 && isContinuation(r) {
+			return true
+		}
+		prev = r
+	}
+	return false
+}
+
+// unmojibake decodes UTF-8 sequences of s that were decoded as Latin-1
+// or Windows-1252, returning the fixed string and the number of fixed
+// sequences.
+func unmojibake(s string) (string, int) {
+	runes := []rune(s)
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		size := 0
+		switch {
+		case r >= 0xC2 && r <= 0xDF:
+			size = 2
+		case r >= 0xE0 && r <= 0xEF:
+			size = 3
+		case r >= 0xF0 && r <= 0xF4:
+			size = 4
+		}
+		if size > 0 && i+size <= len(runes) {
+			seq := []byte{byte(r)}
+			for _, c := range runes[i+1 : i+size] {
+				if !isContinuation(c) {
+					break
+				}
+				cb, _ := latin1Byte(c)
+				seq = append(seq, cb)
+			}
+			if len(seq) == size {
+				if d, _ := utf8.DecodeRune(seq); d != utf8.RuneError {
+					b.WriteRune(d)
+					i += size - 1
+					n++
+					continue
+				}
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), n
+}
+
+// fixMojibake repairs s, which may be encoded several times, returning
+// the fixed string and the number of fixed sequences.
+func fixMojibake(s string) (string, int) {
+	total := 0
+	for pass := 0; pass < 3 && hasMojibake(s); pass++ {
+		fixed, n := unmojibake(s)
+		if n == 0 {
+			break
+		}
+		s = fixed
+		total += n
+	}
+	return s, total
+}
+
+// mojibakeSample returns the first word of s that changes after fixing,
+// before and after.
+func mojibakeSample(s string) (before, after string) {
+	for _, w := range strings.Fields(s) {
+		if f, n := fixMojibake(w); n > 0 {
+			return w, f
+		}
+	}
+	return "", ""
+}
+
+// fixEntryMojibake repairs title, body and comments of entry, reporting
+// the number of fixes with a sample.
+func (e *entry) fixEntryMojibake(filename string, body []byte) []byte {
+	if !*fixMojibakeFlag {
+		return body
+	}
+	total := 0
+	var before, after string
+	fix := func(s string) string {
+		fixed, n := fixMojibake(s)
+		if n > 0 && before == "" {
+			before, after = mojibakeSample(s)
+		}
+		total += n
+		return fixed
+	}
+	if title, err := strconv.Unquote(e.Header["title"]); err == nil {
+		e.Header["title"] = strconv.Quote(fix(title))
+	}
+	body = []byte(fix(string(body)))
+	for _, c := range e.Comments {
+		if *repairCommentsFlag {
+			break // repaired one by one
+		}
+		c.Author = fix(c.Author)
+		c.Content = fix(c.Content)
+	}
+	if total > 0 {
+		rep.addf(filename, "mojibake", "fixed %d sequences, e.g. %q to %q", total, before, after)
+	}
+	return body
+}
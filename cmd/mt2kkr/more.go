@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	moreMarkerFlag = flag.String("more-marker", "", "insert summary `marker` between body and extended body, e.g. <!--more--> for Hugo")
+	autoMoreFlag   = flag.String("auto-more", "", "with -more-marker, insert marker into entries without extended body and excerpt after the first paragraph (paragraph) or the paragraph reaching `N` words")
+)
+
+// autoMoreWords is the number of words before automatic marker.
+var autoMoreWords = -1
+
+var paragraphEndRe = regexp.MustCompile(`(?i)</p>[ \t]*\n?|\n[ \t]*\n`)
+
+func setupMore() error {
+	autoMoreWords = -1
+	switch {
+	case *autoMoreFlag == "":
+		return nil
+	case *moreMarkerFlag == "":
+		return errors.New("-auto-more requires -more-marker")
+	case *autoMoreFlag == "paragraph":
+		autoMoreWords = 0
+		return nil
+	}
+	n, err := strconv.Atoi(*autoMoreFlag)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("bad -auto-more %q: want paragraph or number of words", *autoMoreFlag)
+	}
+	autoMoreWords = n
+	return nil
+}
+
+// text returns body with extended body, separated by -more-marker.
+func (e *entry) text() string {
+	marker := *moreMarkerFlag
+	if marker == "" {
+		return e.Text()
+	}
+	body, extended := e.Body, e.Extended
+	if extended == "" && e.Excerpt == "" && autoMoreWords >= 0 {
+		i := summaryEnd(body, autoMoreWords)
+		body, extended = body[:i], body[i:]
+	}
+	if extended == "" {
+		return body
+	}
+	if !strings.HasSuffix(body, "\n") {
+		body += "\n"
+	}
+	return body + marker + "\n" + extended
+}
+
+// summaryEnd returns position of the end of the first paragraph of body
+// with at least words words before it, or len(body) if the rest is empty.
+func summaryEnd(body string, words int) int {
+	for _, m := range paragraphEndRe.FindAllStringIndex(body, -1) {
+		end := m[1]
+		if strings.TrimSpace(body[end:]) == "" {
+			break
+		}
+		if len(strings.Fields(anyTagRe.ReplaceAllString(body[:end], " "))) >= words {
+			return end
+		}
+	}
+	return len(body)
+}
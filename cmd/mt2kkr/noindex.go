@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"time"
+)
+
+var (
+	noindexBeforeFlag   = flag.String("noindex-before", "", "add noindex: true to entries dated before `date` (YYYY-MM-DD)")
+	noindexCategoryFlag listFlag
+)
+
+func init() {
+	flag.Var(&noindexCategoryFlag, "noindex-category", "add noindex: true to entries in `category` (can repeat)")
+}
+
+var noindexBefore time.Time
+
+func setupNoindex() (err error) {
+	if *noindexBeforeFlag != "" {
+		noindexBefore, err = time.Parse("2006-01-02", *noindexBeforeFlag)
+	}
+	return err
+}
+
+func (e *entry) inCategory(names []string) bool {
+	for _, c := range e.Categories {
+		for _, n := range names {
+			if c == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addNoindex marks entries selected by -noindex-before and
+// -noindex-category to be excluded from search engines.
+func (e *entry) addNoindex() {
+	if (!noindexBefore.IsZero() && e.Date.Before(noindexBefore)) || e.inCategory(noindexCategoryFlag) {
+		e.Header["noindex"] = "true"
+	}
+}
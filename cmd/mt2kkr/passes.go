@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/dchest/mt2kkr/output"
+)
+
+// pass is a step of entry conversion, returning new body. It returns
+// errSkipEntry if the entry shouldn't be written.
+type pass func(ctx context.Context, e *entry, body []byte) ([]byte, error)
+
+var errSkipEntry = output.ErrSkip
+
+// passes are the available conversion passes. Most do nothing unless
+// enabled by their flags.
+var passes = map[string]pass{
+	"repair-comments": headerPass(func(e *entry) { e.repairComments(e.filename) }),
+	"mojibake": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		return e.fixEntryMojibake(e.filename, body), nil
+	},
+	"textile":   convertTextile,
+	"sanitize":  bodyPass(sanitizeBody),
+	"modernize": bodyPass(modernizeBody),
+	"headings":  bodyPass(normalizeHeadings),
+	"toc": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		return e.addTOC(body), nil
+	},
+	"rewrite-assets": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		return e.rewriteAssets(e.filename, body), nil
+	},
+	"mirror-assets": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		return mirrorAssets(ctx, e.filename, body)
+	},
+	"canonical":    headerPass(func(e *entry) { e.addCanonical(e.Slug) }),
+	"noindex":      headerPass((*entry).addNoindex),
+	"translations": headerPass((*entry).addTranslationKey),
+	"featured":     headerPass((*entry).addFeatured),
+	"linklog": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		e.addLinklog(body)
+		return body, nil
+	},
+	"photo": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		e.addPhoto(body)
+		return body, nil
+	},
+	"quote": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		e.addQuote(body)
+		return body, nil
+	},
+	"pii": headerPass(func(e *entry) { e.scanCommentsPII(e.filename) }),
+	"cw": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		e.flagContentWarnings(e.filename, body)
+		return body, nil
+	},
+	"spell": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		e.spellCheck(e.filename, body)
+		return body, nil
+	},
+	"verify-live": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		return body, e.verifyLive(ctx, body)
+	},
+	"footer": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		return e.addFooter(body, e.name)
+	},
+	"check-html": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		e.checkHTML(body)
+		return body, nil
+	},
+	"protect": func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		return e.protect(body)
+	},
+}
+
+// defaultPasses is the default order of conversion passes.
+var defaultPasses = []string{
+	"repair-comments", "mojibake", "textile", "sanitize", "modernize",
+	"headings", "toc", "rewrite-assets", "pii", "cw", "spell",
+	"footer",
+	"check-html", "protect",
+	"mirror-assets", "canonical", "noindex", "translations", "featured",
+	"linklog", "photo", "quote", "verify-live",
+}
+
+// publishingPasses copy content of entries elsewhere: into the assets
+// directory or front matter. They must run after protect, so that they
+// don't publish password-protected content.
+var publishingPasses = map[string]bool{
+	"mirror-assets": true, "featured": true, "linklog": true, "photo": true, "quote": true,
+}
+
+var passesFlag = flag.String("passes", strings.Join(defaultPasses, ","), "comma-separated `list` of conversion passes to run in order; leave a pass out to disable it")
+
+// runPasses are passes from -passes, in order; protects is true if
+// they include protect.
+var (
+	runPasses []pass
+	protects  bool
+)
+
+func setupPasses() error {
+	runPasses, protects = nil, false
+	seen := make(map[string]bool)
+	publishing := "" // the first publishing pass
+	for _, name := range strings.Split(*passesFlag, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		p, ok := passes[name]
+		if !ok {
+			names := make([]string, 0, len(passes))
+			for k := range passes {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown pass %q in -passes (available: %s)", name, strings.Join(names, ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("pass %q is repeated in -passes", name)
+		}
+		if publishingPasses[name] && publishing == "" {
+			publishing = name
+		}
+		if name == "protect" {
+			if publishing != "" {
+				return fmt.Errorf("pass %q must come after protect in -passes", publishing)
+			}
+			protects = true
+		}
+		seen[name] = true
+		runPasses = append(runPasses, p)
+	}
+	return nil
+}
+
+// bodyPass makes a pass of body transformation.
+func bodyPass(f func(filename string, body []byte) []byte) pass {
+	return func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		return f(e.filename, body), nil
+	}
+}
+
+// headerPass makes a pass that changes only entry header.
+func headerPass(f func(e *entry)) pass {
+	return func(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+		f(e)
+		return body, nil
+	}
+}
+
+// convertTextile converts textile body to HTML with redcloth, applying
+// -converter-fallback policy on failure.
+func convertTextile(ctx context.Context, e *entry, body []byte) ([]byte, error) {
+	if e.Header["markup"] != "textile" {
+		return body, nil
+	}
+	out, err := runConverter(ctx, e.filename, body, "redcloth")
+	switch {
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case err == nil:
+		delete(e.Header, "markup")
+		log.Printf("*** Converted textile")
+		return out, nil
+	case *converterFallbackFlag == "raw":
+		rep.addf(e.filename, "markup", "redcloth failed, keeping textile unconverted: %s", err)
+		return body, nil
+	case *converterFallbackFlag == "skip":
+		rep.addf(e.filename, "markup", "skipped: redcloth failed: %s", err)
+		return nil, errSkipEntry
+	default:
+		return nil, fmt.Errorf("%s: redcloth: %s", e.filename, err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	photoCategoryFlag listFlag
+	photoWordsFlag    = flag.Int("photo-words", 0, "mark entries with images and fewer than `n` words as photo posts (0 to disable)")
+)
+
+func init() {
+	flag.Var(&photoCategoryFlag, "photo-category", "mark entries with category `name` as photo posts (can repeat)")
+}
+
+var markdownImageRe = regexp.MustCompile(`!\[[^\]]*\]\(([^)\s]+)[^)]*\)`)
+
+// bodyImages returns image URLs of HTML and Markdown images in body,
+// which are local after mirror-assets.
+func bodyImages(body []byte) []string {
+	var images []string
+	for _, tag := range imgRe.FindAll(body, -1) {
+		if sm := imgSrcRe.FindSubmatch(tag); sm != nil {
+			images = append(images, html.UnescapeString(strings.Trim(string(sm[2]), `"'`)))
+		}
+	}
+	for _, m := range markdownImageRe.FindAllSubmatch(body, -1) {
+		images = append(images, string(m[1]))
+	}
+	return images
+}
+
+// addPhoto marks photo posts, either in -photo-category or ones with
+// images and little text, with type: photo and the list of their images.
+func (e *entry) addPhoto(body []byte) {
+	if len(photoCategoryFlag) == 0 && *photoWordsFlag <= 0 {
+		return
+	}
+	images := bodyImages(body)
+	isPhoto := false
+	for _, c := range append([]string{e.PrimaryCategory}, e.Categories...) {
+		if contains(photoCategoryFlag, c) {
+			isPhoto = true
+		}
+	}
+	if !isPhoto && *photoWordsFlag > 0 && len(images) > 0 && countWords(markdownImageRe.ReplaceAll(body, nil)) < *photoWordsFlag {
+		isPhoto = true
+	}
+	if !isPhoto {
+		return
+	}
+	e.Header["type"] = strconv.Quote("photo")
+	if len(images) == 0 {
+		rep.addf(e.filename, "photo", "photo post without images")
+		return
+	}
+	quoted := make([]string, len(images))
+	for i, s := range images {
+		quoted[i] = strconv.Quote(s)
+	}
+	e.Header["images"] = "[" + strings.Join(quoted, ", ") + "]"
+}
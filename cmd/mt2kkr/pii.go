@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	piiFlag      = flag.String("pii", "", "scan comments for phone numbers and emails, with `action` redact, drop or flag")
+	piiRulesFlag = flag.String("pii-rules", "", "read comment scanning rules from `file`")
+)
+
+// piiRule is a comment scanning rule. Action is one of "redact",
+// "drop" or "flag".
+type piiRule struct {
+	Action string
+	Name   string
+	re     *regexp.Regexp
+}
+
+var builtinPII = map[string]string{
+	"phone": `(?:\+\d{1,3}[\s.-]?)?\(?\b\d{3}\)?[\s.-]?\d{3}[\s.-]\d{4}\b`,
+	"email": `[\w.+-]+@[\w-]+(?:\.[\w-]+)+`,
+}
+
+var piiRules []piiRule
+
+func addPIIRule(action, name, pattern string) error {
+	switch action {
+	case "redact", "drop", "flag":
+	default:
+		return fmt.Errorf("unknown pii action %q", action)
+	}
+	if pattern == "" {
+		pattern = builtinPII[name]
+		if pattern == "" {
+			return fmt.Errorf("unknown pii rule %q", name)
+		}
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	piiRules = append(piiRules, piiRule{action, name, re})
+	return nil
+}
+
+// loadPIIRules reads rules from file. Each line is
+//
+//	action name [regexp]
+//
+// where the regexp may be omitted for built-in rules "phone" and "email".
+func loadPIIRules(filename string) error {
+	lines, err := readLines(filename)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		f := strings.SplitN(line, " ", 3)
+		if len(f) < 2 {
+			return fmt.Errorf("%s: bad rule `%s`", filename, line)
+		}
+		pattern := ""
+		if len(f) == 3 {
+			pattern = strings.TrimSpace(f[2])
+		}
+		if err := addPIIRule(f[0], f[1], pattern); err != nil {
+			return fmt.Errorf("%s: %s", filename, err)
+		}
+	}
+	return nil
+}
+
+func setupPII() error {
+	if *piiFlag != "" {
+		for _, name := range []string{"phone", "email"} {
+			if err := addPIIRule(*piiFlag, name, ""); err != nil {
+				return err
+			}
+		}
+	}
+	if *piiRulesFlag != "" {
+		return loadPIIRules(*piiRulesFlag)
+	}
+	return nil
+}
+
+// scanCommentsPII applies comment scanning rules, redacting, dropping
+// or reporting comments with personal data.
+func (e *entry) scanCommentsPII(filename string) {
+	if len(piiRules) == 0 {
+		return
+	}
+	kept := e.Comments[:0]
+	for i, c := range e.Comments {
+		drop := false
+		for _, r := range piiRules {
+			if !r.re.MatchString(c.Content) {
+				continue
+			}
+			switch r.Action {
+			case "redact":
+				c.Content = r.re.ReplaceAllString(c.Content, "[redacted]")
+				rep.addf(filename, "pii", "redacted %s in comment %d by %s", r.Name, i+1, c.Author)
+			case "drop":
+				drop = true
+				rep.addf(filename, "pii", "dropped comment %d by %s: contains %s", i+1, c.Author, r.Name)
+			case "flag":
+				rep.addf(filename, "pii", "comment %d by %s contains %s", i+1, c.Author, r.Name)
+			}
+			if drop {
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, c)
+		}
+	}
+	e.Comments = kept
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	platformFlag       = flag.String("platform", "", "write _redirects from original URLs (see -canonical-path) and _headers for hosting `platform`: netlify or cloudflare")
+	redirectStatusFlag = flag.Int("redirect-status", 301, "HTTP `status` of -platform redirects: 301 (permanent) or 302 (temporary)")
+)
+
+// platformRedirectLimits are maximum numbers of redirects in _redirects,
+// or 0 if unlimited.
+var platformRedirectLimits = map[string]int{
+	"netlify":    0,
+	"cloudflare": 2000,
+}
+
+var (
+	noindexMu sync.Mutex
+	// noindexURLs are new URLs of entries with noindex.
+	noindexURLs []string
+)
+
+func setupPlatform() error {
+	if _, ok := platformRedirectLimits[*platformFlag]; !ok && *platformFlag != "" {
+		return fmt.Errorf("unknown -platform %q", *platformFlag)
+	}
+	if *redirectStatusFlag != 301 && *redirectStatusFlag != 302 {
+		return fmt.Errorf("bad -redirect-status %d, must be 301 or 302", *redirectStatusFlag)
+	}
+	return nil
+}
+
+func (e *entry) addNoindexURL() {
+	if *platformFlag != "" && e.Header["noindex"] == "true" {
+		noindexMu.Lock()
+		noindexURLs = append(noindexURLs, e.newURL())
+		noindexMu.Unlock()
+	}
+}
+
+// sitePath returns path of URL on the new site, starting with slash.
+func sitePath(u string) string {
+	if pu, err := url.Parse(u); err == nil && pu.IsAbs() {
+		u = pu.EscapedPath()
+	}
+	return "/" + strings.TrimPrefix(u, "/")
+}
+
+// writePlatformFiles writes _redirects and _headers into dir. Redirects
+// over platform limit, least visited first, go to _redirects-2, etc. to be
+// set up separately, e.g. as Cloudflare bulk redirects.
+func writePlatformFiles(dir string) error {
+	if *platformFlag == "" {
+		return nil
+	}
+	var files []*bytes.Buffer
+	limit := platformRedirectLimits[*platformFlag]
+	n := 0
+	for _, old := range sortedRedirects() {
+		target := sitePath(redirects[old])
+		if pu, err := url.Parse(redirects[old]); err == nil && pu.IsAbs() {
+			target = redirects[old]
+		}
+		if target == old {
+			continue
+		}
+		if limit == 0 && len(files) == 0 || limit > 0 && n%limit == 0 {
+			files = append(files, new(bytes.Buffer))
+		}
+		n++
+		fmt.Fprintf(files[len(files)-1], "%s %s %d\n", old, target, *redirectStatusFlag)
+	}
+	for i, buf := range files {
+		name := "_redirects"
+		if i > 0 {
+			name = fmt.Sprintf("_redirects-%d", i+1)
+			rep.addf(name, "redirect", "over %s limit of %d redirects, set them up separately", *platformFlag, limit)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	var buf bytes.Buffer
+	for _, u := range noindexURLs {
+		fmt.Fprintf(&buf, "%s\n  X-Robots-Tag: noindex\n", sitePath(u))
+	}
+	if *mirrorAssetsFlag != "" && *hashAssetsFlag {
+		fmt.Fprintf(&buf, "%s*\n  Cache-Control: public, max-age=31536000, immutable\n", sitePath(*assetsURLFlag))
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(dir, "_headers"), buf.Bytes(), 0644)
+}
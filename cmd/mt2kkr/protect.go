@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash"
+
+	"github.com/dchest/mt2kkr/output"
+)
+
+var protectedFlag = flag.String("protected", "skip", "`policy` for entries with PASSWORD of protection plugins: skip, private (write with private: true) or staticrypt (encrypt body and comments with entry password into staticrypt-compatible HTML)")
+
+func setupProtected() error {
+	switch *protectedFlag {
+	case "skip", "private", "staticrypt":
+		return nil
+	}
+	return fmt.Errorf("unknown -protected policy %q", *protectedFlag)
+}
+
+// protect applies -protected policy to password-protected entry.
+func (e *entry) protect(body []byte) ([]byte, error) {
+	if e.Password == "" {
+		return body, nil
+	}
+	switch *protectedFlag {
+	case "private":
+		e.Header["private"] = "true"
+		rep.addf(e.filename, "protected", "password-protected, marked private")
+		return body, nil
+	case "staticrypt":
+		if e.Header["markup"] != "" {
+			rep.addf(e.filename, "protected", "skipped: password-protected %s can't be encrypted", e.Header["markup"])
+			return nil, errSkipEntry
+		}
+		var buf bytes.Buffer
+		buf.Write(body)
+		if len(e.Comments) > 0 {
+			buf.WriteString("\n\n<div class=\"comments\">\n")
+			for _, c := range e.Comments {
+				output.WriteComment(&buf, c, outputOpts.MF2)
+			}
+			buf.WriteString("</div>\n")
+			e.Comments = nil
+		}
+		delete(e.Header, "description")
+		return staticryptHTML(buf.String(), e.Password, e.filename)
+	}
+	rep.addf(e.filename, "protected", "skipped: password-protected, see -protected")
+	return nil, errSkipEntry
+}
+
+// staticryptHash hashes password as staticrypt 3 does, in three rounds
+// of PBKDF2 on hex strings.
+func staticryptHash(password, salt string) ([]byte, error) {
+	var key []byte
+	for _, round := range []struct {
+		h    func() hash.Hash
+		iter int
+	}{{sha1.New, 1000}, {sha256.New, 14000}, {sha256.New, 585000}} {
+		var err error
+		if key, err = pbkdf2.Key(round.h, password, []byte(salt), round.iter, 32); err != nil {
+			return nil, err
+		}
+		password = hex.EncodeToString(key)
+	}
+	return key, nil
+}
+
+// staticryptEncrypt returns staticrypt message: hex of HMAC-SHA256 of
+// the rest, IV and AES-CBC ciphertext of text. To keep output
+// reproducible, IV is derived from key and text, so it only repeats for
+// the same text.
+func staticryptEncrypt(text string, key []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	pad := aes.BlockSize - len(text)%aes.BlockSize
+	data := append([]byte(text), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	ivMAC := hmac.New(sha256.New, key)
+	ivMAC.Write([]byte("mt2kkr staticrypt iv\x00" + text))
+	iv := ivMAC.Sum(nil)[:aes.BlockSize]
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	msg := hex.EncodeToString(iv) + hex.EncodeToString(data)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return hex.EncodeToString(mac.Sum(nil)) + msg, nil
+}
+
+// staticryptHTML returns form decrypting text in place, with
+// staticryptConfig as in pages of staticrypt, so staticrypt can decrypt
+// them too. Salt is derived from filename, which is unique in output.
+func staticryptHTML(text, password, filename string) ([]byte, error) {
+	salt := sha256.Sum256([]byte("mt2kkr staticrypt salt\x00" + filename))
+	hexSalt := hex.EncodeToString(salt[:16])
+	key, err := staticryptHash(password, hexSalt)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := staticryptEncrypt(text, key)
+	if err != nil {
+		return nil, err
+	}
+	config, err := json.Marshal(map[string]string{
+		"staticryptEncryptedMsgUniqueVariableName": msg,
+		"staticryptSaltUniqueVariableName":         hexSalt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(`<div class="staticrypt">
+<form><input type="password" placeholder="Password" autofocus> <button>Show</button></form>
+<script>
+(function() {
+const staticryptConfig = ` + string(config) + `;
+const root = document.currentScript.parentNode;
+const enc = new TextEncoder();
+const hexBytes = (s) => new Uint8Array(s.match(/../g).map((h) => parseInt(h, 16)));
+const bytesHex = (b) => Array.from(new Uint8Array(b), (x) => x.toString(16).padStart(2, "0")).join("");
+async function pbkdf2(password, salt, iterations, hash) {
+	const key = await crypto.subtle.importKey("raw", enc.encode(password), "PBKDF2", false, ["deriveBits"]);
+	return bytesHex(await crypto.subtle.deriveBits({name: "PBKDF2", hash, iterations, salt: enc.encode(salt)}, key, 256));
+}
+root.querySelector("form").onsubmit = async function(ev) {
+	ev.preventDefault();
+	const salt = staticryptConfig.staticryptSaltUniqueVariableName;
+	const msg = staticryptConfig.staticryptEncryptedMsgUniqueVariableName;
+	let hash = await pbkdf2(this.querySelector("input").value, salt, 1000, "SHA-1");
+	hash = await pbkdf2(hash, salt, 14000, "SHA-256");
+	hash = hexBytes(await pbkdf2(hash, salt, 585000, "SHA-256"));
+	const hmacKey = await crypto.subtle.importKey("raw", hash, {name: "HMAC", hash: "SHA-256"}, false, ["verify"]);
+	if (!await crypto.subtle.verify("HMAC", hmacKey, hexBytes(msg.slice(0, 64)), enc.encode(msg.slice(64)))) {
+		alert("Wrong password");
+		return;
+	}
+	const aesKey = await crypto.subtle.importKey("raw", hash, "AES-CBC", false, ["decrypt"]);
+	const text = await crypto.subtle.decrypt({name: "AES-CBC", iv: hexBytes(msg.slice(64, 96))}, aesKey, hexBytes(msg.slice(96)));
+	root.innerHTML = new TextDecoder().decode(text);
+};
+})();
+</script>
+</div>
+`), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestStaticrypt checks that protected text decrypts as by staticrypt
+// and that output is the same on each run.
+func TestStaticrypt(t *testing.T) {
+	text := "<p>secret</p>\n"
+	out, err := staticryptHTML(text, "pw", "2006-01-02-x.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := staticryptHTML(text, "pw", "2006-01-02-x.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, again) {
+		t.Error("output differs between runs")
+	}
+	if bytes.Contains(out, []byte("secret")) {
+		t.Error("text isn't encrypted")
+	}
+	var config map[string]string
+	m := regexp.MustCompile(`const staticryptConfig = (.*);`).FindSubmatch(out)
+	if m == nil {
+		t.Fatalf("no config in %s", out)
+	}
+	if err := json.Unmarshal(m[1], &config); err != nil {
+		t.Fatal(err)
+	}
+	salt, msg := config["staticryptSaltUniqueVariableName"], config["staticryptEncryptedMsgUniqueVariableName"]
+	key, err := staticryptHash("pw", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg[64:]))
+	if hex.EncodeToString(mac.Sum(nil)) != msg[:64] {
+		t.Fatal("bad HMAC")
+	}
+	iv, _ := hex.DecodeString(msg[64:96])
+	data, _ := hex.DecodeString(msg[96:])
+	block, _ := aes.NewCipher(key)
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, data)
+	data = data[:len(data)-int(data[len(data)-1])]
+	if string(data) != text {
+		t.Errorf("decrypted %q, want %q", data, text)
+	}
+}
+
+func TestSetupPassesProtect(t *testing.T) {
+	defer func(s string) { *passesFlag = s; setupPasses() }(*passesFlag)
+	*passesFlag = "mirror-assets,protect"
+	if err := setupPasses(); err == nil || !strings.Contains(err.Error(), "after protect") {
+		t.Errorf("mirror-assets before protect: error %v", err)
+	}
+	*passesFlag = "protect,mirror-assets"
+	if err := setupPasses(); err != nil || !protects {
+		t.Errorf("protect before mirror-assets: error %v, protects %v", err, protects)
+	}
+	*passesFlag = "textile"
+	if err := setupPasses(); err != nil || protects {
+		t.Errorf("no protect: error %v, protects %v", err, protects)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var quotePostsFlag = flag.Bool("quote-posts", false, "mark entries made of a single blockquote with attribution as quote posts")
+
+var (
+	blockquoteRe = regexp.MustCompile(`(?is)<blockquote\b([^>]*)>(.*?)</blockquote\s*>`)
+	citeAttrRe   = regexp.MustCompile(`(?i)\scite\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	citeTagRe    = regexp.MustCompile(`(?is)<cite\b[^>]*>(.*?)</cite\s*>`)
+)
+
+// maxAttributionWords is the maximum number of words outside of
+// blockquote in quote posts.
+const maxAttributionWords = 15
+
+// tagText returns text of HTML without tags and surrounding space.
+func tagText(s string) string {
+	return strings.TrimSpace(html.UnescapeString(anyTagRe.ReplaceAllString(s, "")))
+}
+
+// addQuote marks posts consisting of a single blockquote and a short
+// attribution with type: quote, quote_author from <cite> or text after a
+// dash, and quote_source from cite attribute or attribution link.
+func (e *entry) addQuote(body []byte) {
+	if !*quotePostsFlag {
+		return
+	}
+	quotes := blockquoteRe.FindAllSubmatchIndex(body, -1)
+	if len(quotes) != 1 {
+		return
+	}
+	q := quotes[0]
+	attrs, inside := string(body[q[2]:q[3]]), string(body[q[4]:q[5]])
+	outside := string(body[:q[0]]) + " " + string(body[q[1]:])
+	if countWords([]byte(outside)) > maxAttributionWords || countWords([]byte(inside)) == 0 {
+		return
+	}
+	var author, source string
+	if m := citeTagRe.FindStringSubmatch(outside + inside); m != nil {
+		author = tagText(m[1])
+	} else if s := tagText(outside); s != "" {
+		s = strings.TrimLeft(s, "—―–-~ ")
+		if i := strings.Index(s, ","); i > 0 {
+			s = s[:i]
+		}
+		author = strings.TrimSpace(s)
+	}
+	if m := citeAttrRe.FindStringSubmatch(attrs); m != nil {
+		source = html.UnescapeString(m[1] + m[2])
+	} else if links := bodyLinks([]byte(outside)); len(links) > 0 {
+		source = links[0]
+	}
+	if author == "" && source == "" {
+		return
+	}
+	e.Header["type"] = strconv.Quote("quote")
+	if author != "" {
+		e.Header["quote_author"] = strconv.Quote(author)
+	}
+	if source != "" {
+		e.Header["quote_source"] = strconv.Quote(source)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	accessLogFlag       = flag.String("access-log", "", "rank redirects from original URLs (see -canonical-path) by hits in server access log `file` and write redirects.txt to output directory")
+	redirectMinHitsFlag = flag.Int("redirect-min-hits", 10, "report unmapped URLs from -access-log with at least `n` hits")
+)
+
+var (
+	redirectsMu sync.Mutex
+	// redirects maps original paths of entries to new URLs.
+	redirects = make(map[string]string)
+)
+
+// addRedirect records redirect from original path to new URL.
+func addRedirect(old, new string) {
+	redirectsMu.Lock()
+	redirects[old] = new
+	redirectsMu.Unlock()
+}
+
+// originalPath returns the original path of entry from -canonical-path.
+func (e *entry) originalPath() string {
+	u := e.canonicalURL(e.Slug)
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(u, strings.TrimSuffix(*canonicalBaseFlag, "/")), "/")
+}
+
+func (e *entry) addEntryRedirect() {
+	if (*accessLogFlag != "" || *platformFlag != "") && e.Slug != "" {
+		addRedirect(e.originalPath(), e.newURL())
+	}
+}
+
+// accessLogRe matches requests in common and combined log formats.
+var accessLogRe = regexp.MustCompile(`"(?:GET|HEAD) (\S+) HTTP/[0-9.]+" ([0-9]{3})`)
+
+// assetExts are extensions of files that are not pages.
+var assetExts = map[string]bool{
+	".css": true, ".js": true, ".png": true, ".jpg": true, ".jpeg": true,
+	".gif": true, ".ico": true, ".svg": true, ".woff": true, ".woff2": true,
+}
+
+// accessHits are numbers of requests of paths in -access-log.
+var accessHits map[string]int
+
+func setupRedirects() (err error) {
+	if *accessLogFlag != "" {
+		accessHits, err = readAccessLog(*accessLogFlag)
+	}
+	return err
+}
+
+// readAccessLog returns numbers of successful requests by path.
+func readAccessLog(filename string) (map[string]int, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	hits := make(map[string]int)
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		m := accessLogRe.FindSubmatch(s.Bytes())
+		if m == nil || m[2][0] == '4' || m[2][0] == '5' {
+			continue
+		}
+		u, err := url.Parse(string(m[1]))
+		if err != nil || u.Path == "" {
+			continue
+		}
+		hits[u.Path]++
+	}
+	return hits, s.Err()
+}
+
+// sortedRedirects returns original paths of redirects, most visited
+// first.
+func sortedRedirects() []string {
+	redirectsMu.Lock()
+	olds := make([]string, 0, len(redirects))
+	for old := range redirects {
+		olds = append(olds, old)
+	}
+	redirectsMu.Unlock()
+	sort.Slice(olds, func(i, j int) bool {
+		if accessHits[olds[i]] != accessHits[olds[j]] {
+			return accessHits[olds[i]] > accessHits[olds[j]]
+		}
+		return olds[i] < olds[j]
+	})
+	return olds
+}
+
+// writeRedirects writes redirects.txt into dir with lines of original
+// paths and new URLs, most visited first, and reports frequently visited
+// paths without redirects.
+func writeRedirects(dir string) error {
+	if *accessLogFlag == "" {
+		return nil
+	}
+	hits := accessHits
+	var buf bytes.Buffer
+	for _, old := range sortedRedirects() {
+		fmt.Fprintf(&buf, "%s %s %d\n", old, redirects[old], hits[old])
+	}
+	var unmapped []string
+	for p, n := range hits {
+		if _, ok := redirects[p]; !ok && n >= *redirectMinHitsFlag && !assetExts[strings.ToLower(path.Ext(p))] {
+			unmapped = append(unmapped, p)
+		}
+	}
+	sort.Slice(unmapped, func(i, j int) bool {
+		if hits[unmapped[i]] != hits[unmapped[j]] {
+			return hits[unmapped[i]] > hits[unmapped[j]]
+		}
+		return unmapped[i] < unmapped[j]
+	})
+	for _, p := range unmapped {
+		rep.addf(p, "redirect", "%d hits, but no redirect", hits[p])
+	}
+	return os.WriteFile(filepath.Join(dir, "redirects.txt"), buf.Bytes(), 0644)
+}
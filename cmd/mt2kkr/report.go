@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var reportFlag = flag.String("report", "", "write conversion report to `file`")
+
+// reportItem is a single note about a converted entry that may need review.
+type reportItem struct {
+	File string
+	Kind string
+	Msg  string
+}
+
+// reportEntry is a converted entry.
+type reportEntry struct {
+	File       string
+	Title      string
+	Date       time.Time
+	Confidence int // see confidence
+}
+
+// report collects converted entries and notes about them.
+type report struct {
+	mu      sync.Mutex
+	items   []reportItem
+	entries []reportEntry
+	skipped []reportItem    // entries skipped by -skip-list
+	penalty map[string]int  // confidence penalties by file
+	private map[string]bool // encrypted files, whose notes are withheld
+}
+
+var rep report
+
+func (r *report) addf(file, kind, format string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.private[file] {
+		// Notes quote titles, bodies and comments.
+		log.Printf("*** %s: %s: withheld, entry is encrypted", file, kind)
+		return
+	}
+	r.items = append(r.items, reportItem{file, kind, fmt.Sprintf(format, args...)})
+	if r.penalty == nil {
+		r.penalty = make(map[string]int)
+	}
+	r.penalty[file] += kindPenalty(kind)
+	log.Printf("*** %s: %s: %s", file, kind, r.items[len(r.items)-1].Msg)
+}
+
+// addPrivate withholds notes about file, which is encrypted, dropping
+// notes added before.
+func (r *report) addPrivate(file string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.private == nil {
+		r.private = make(map[string]bool)
+	}
+	r.private[file] = true
+	items := r.items[:0]
+	for _, it := range r.items {
+		if it.File != file {
+			items = append(items, it)
+		}
+	}
+	r.items = items
+	delete(r.penalty, file)
+}
+
+func (r *report) addKnownSkip(id, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipped = append(r.skipped, reportItem{id, "known-skip", reason})
+}
+
+func (r *report) addEntry(file, title string, date time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, reportEntry{file, title, date, confidence(r.penalty[file])})
+}
+
+// writeTo writes notes sorted by ascending confidence, preceding notes
+// of each converted entry with its confidence, and then known skips.
+func (r *report) writeTo(w io.Writer) error {
+	conf := r.confidences()
+	last := ""
+	for _, it := range r.sortedItems() {
+		if c, ok := conf[it.File]; ok && it.File != last {
+			if _, err := fmt.Fprintf(w, "%s\tconfidence\t%d\n", it.File, c); err != nil {
+				return err
+			}
+		}
+		last = it.File
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", it.File, it.Kind, it.Msg); err != nil {
+			return err
+		}
+	}
+	for _, it := range r.skipped {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", it.File, it.Kind, it.Msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeReport writes the report to the file given by -report, if any.
+func writeReport() error {
+	if *reportFlag == "" {
+		return nil
+	}
+	f, err := os.Create(*reportFlag)
+	if err != nil {
+		return err
+	}
+	if err := rep.writeTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
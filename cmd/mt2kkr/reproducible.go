@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	nowFlag                = flag.String("now", "", "use `time` (RFC 3339) for generated timestamps instead of the date of the latest entry")
+	verifyReproducibleFlag = flag.Bool("verify-reproducible", false, "convert twice into temporary directories and check that outputs are identical")
+)
+
+var (
+	fixedNow   time.Time
+	latestDate time.Time // date of the latest entry seen
+)
+
+func setupNow() (err error) {
+	if *nowFlag != "" {
+		fixedNow, err = time.Parse(time.RFC3339, *nowFlag)
+	}
+	return err
+}
+
+// now returns time for generated timestamps. To keep output
+// reproducible, it's the date of the latest converted entry unless -now
+// is given.
+func now() time.Time {
+	if !fixedNow.IsZero() {
+		return fixedNow
+	}
+	return latestDate
+}
+
+func readTree(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[rel], err = os.ReadFile(path)
+		return err
+	})
+	return files, err
+}
+
+// verifyReproducible runs conversion with the same flags twice into
+// temporary directories and compares results.
+func verifyReproducible() error {
+	var args []string
+	for _, a := range os.Args[1 : len(os.Args)-flag.NArg()] {
+		if !strings.HasPrefix(strings.TrimLeft(a, "-"), "verify-reproducible") {
+			args = append(args, a)
+		}
+	}
+	tmp, err := os.MkdirTemp("", "mt2kkr-verify-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	// Save input, so that both runs read the same data.
+	in, err := openInput(context.Background(), inputName)
+	if err != nil {
+		return err
+	}
+	input := filepath.Join(tmp, "input.txt")
+	b, err := io.ReadAll(in)
+	in.Close()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(input, b, 0644); err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	var trees [2]map[string][]byte
+	for i := range trees {
+		dir := filepath.Join(tmp, fmt.Sprintf("run%d", i+1))
+		cmd := exec.Command(self, append(args, dir, input)...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("run %d failed: %s\n%s", i+1, err, stderr.String())
+		}
+		if trees[i], err = readTree(dir); err != nil {
+			return err
+		}
+	}
+	diffs := 0
+	for name, data := range trees[0] {
+		other, ok := trees[1][name]
+		switch {
+		case !ok:
+			log.Printf("%s: only in first run", name)
+		case !bytes.Equal(data, other):
+			log.Printf("%s: differs", name)
+		default:
+			continue
+		}
+		diffs++
+	}
+	for name := range trees[1] {
+		if _, ok := trees[0][name]; !ok {
+			log.Printf("%s: only in second run", name)
+			diffs++
+		}
+	}
+	if diffs > 0 {
+		return fmt.Errorf("output is not reproducible: %d files differ", diffs)
+	}
+	log.Printf("Output is reproducible (%d files)", len(trees[0]))
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var rewriteAssetsFlag listFlag
+
+func init() {
+	flag.Var(&rewriteAssetsFlag, "rewrite-assets", "rewrite asset URLs (src, srcset and link href) in bodies and comments with `old=new` prefix rule, or ~regexp=replacement (can repeat)")
+}
+
+// rewriteRule replaces URL prefix Old with New, or, if re is set,
+// rewrites URLs matching it with New as replacement template.
+type rewriteRule struct {
+	Old, New string
+	re       *regexp.Regexp
+}
+
+var rewriteRules []rewriteRule
+
+func setupRewriteAssets() error {
+	for _, s := range rewriteAssetsFlag {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("bad -rewrite-assets rule `%s`", s)
+		}
+		r := rewriteRule{Old: kv[0], New: kv[1]}
+		if strings.HasPrefix(r.Old, "~") {
+			re, err := regexp.Compile(r.Old[1:])
+			if err != nil {
+				return err
+			}
+			r.re = re
+		}
+		rewriteRules = append(rewriteRules, r)
+	}
+	return nil
+}
+
+func (r *rewriteRule) rewrite(u string) (string, bool) {
+	if r.re != nil {
+		if !r.re.MatchString(u) {
+			return u, false
+		}
+		return r.re.ReplaceAllString(u, r.New), true
+	}
+	if !strings.HasPrefix(u, r.Old) {
+		return u, false
+	}
+	return r.New + u[len(r.Old):], true
+}
+
+var (
+	tagRe       = regexp.MustCompile(`(?i)<([a-z][a-z0-9]*)\b[^>]*>`)
+	assetAttrRe = regexp.MustCompile(`(?i)(\s(src|srcset|href)\s*=\s*)("[^"]*"|'[^']*'|[^\s>]+)`)
+)
+
+// rewriteAssetURLs applies -rewrite-assets rules to src and srcset
+// attributes and to href of link elements in s, leaving links to pages
+// alone. The first matching rule wins.
+func rewriteAssetURLs(s string) (string, int) {
+	if len(rewriteRules) == 0 {
+		return s, 0
+	}
+	n := 0
+	rewrite := func(u string) string {
+		for _, r := range rewriteRules {
+			if nu, ok := r.rewrite(u); ok {
+				n++
+				return nu
+			}
+		}
+		return u
+	}
+	s = tagRe.ReplaceAllStringFunc(s, func(tag string) string {
+		link := strings.EqualFold(tagRe.FindStringSubmatch(tag)[1], "link")
+		return assetAttrRe.ReplaceAllStringFunc(tag, func(attr string) string {
+			sm := assetAttrRe.FindStringSubmatch(attr)
+			name := strings.ToLower(sm[2])
+			if name == "href" && !link {
+				return attr
+			}
+			u := html.UnescapeString(strings.Trim(sm[3], `"'`))
+			var nu string
+			if name == "srcset" {
+				// Candidates are URLs with optional descriptors.
+				cs := strings.Split(u, ",")
+				for i, c := range cs {
+					if f := strings.Fields(c); len(f) > 0 {
+						cs[i] = strings.Replace(c, f[0], rewrite(f[0]), 1)
+					}
+				}
+				nu = strings.Join(cs, ",")
+			} else {
+				nu = rewrite(u)
+			}
+			if nu == u {
+				return attr
+			}
+			return sm[1] + `"` + html.EscapeString(nu) + `"`
+		})
+	})
+	return s, n
+}
+
+// rewriteAssets applies -rewrite-assets rules to body and comments.
+func (e *entry) rewriteAssets(filename string, body []byte) []byte {
+	if len(rewriteRules) == 0 {
+		return body
+	}
+	s, total := rewriteAssetURLs(string(body))
+	for _, c := range e.Comments {
+		var n int
+		c.Content, n = rewriteAssetURLs(c.Content)
+		total += n
+	}
+	if total > 0 {
+		rep.addf(filename, "assets", "rewrote %d asset URLs", total)
+	}
+	return []byte(s)
+}
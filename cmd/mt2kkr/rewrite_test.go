@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func TestRewriteAssetURLs(t *testing.T) {
+	defer func() { rewriteRules = nil }()
+	rewriteRules = []rewriteRule{{Old: "http://old.example.com/", New: "https://cdn.example.com/"}}
+	in := `<a href="http://old.example.com/page.html"><IMG SRC=http://old.example.com/a.png srcset="http://old.example.com/a2.png 2x, /b.png 3x"></a>` +
+		`<link rel="stylesheet" href='http://old.example.com/s.css'>`
+	want := `<a href="http://old.example.com/page.html"><IMG SRC="https://cdn.example.com/a.png" srcset="https://cdn.example.com/a2.png 2x, /b.png 3x"></a>` +
+		`<link rel="stylesheet" href="https://cdn.example.com/s.css">`
+	got, n := rewriteAssetURLs(in)
+	if got != want || n != 3 {
+		t.Errorf("rewriteAssetURLs =\n%s, %d, want\n%s, 3", got, n, want)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dchest/mt2kkr/output"
+)
+
+var routesFlag = flag.String("routes", "", "route entries by category or tag to directories, file names and presets from JSON `file`")
+
+// setupRoutes loads routes from file into output options. The file
+// contains an array of objects with keys named after output.Route
+// fields in lower case, e.g.
+//
+//	[{"category": "linklog", "dir": "links", "prefix": "", "preset": "hugo"}]
+//
+// The first matching route wins. Omitted prefix, ext and preset are
+// taken from flags.
+func setupRoutes() error {
+	if *routesFlag == "" {
+		return nil
+	}
+	b, err := os.ReadFile(*routesFlag)
+	if err != nil {
+		return err
+	}
+	var rs []map[string]string
+	if err := json.Unmarshal(b, &rs); err != nil {
+		return fmt.Errorf("%s: %s", *routesFlag, err)
+	}
+	for i, m := range rs {
+		r := &output.Route{Prefix: "2006-01-02-", Ext: *extFlag}
+		for k, v := range m {
+			switch k {
+			case "category":
+				r.Category = v
+			case "tag":
+				r.Tag = v
+			case "dir":
+				r.Dir = filepath.Clean(v)
+				if !filepath.IsLocal(r.Dir) {
+					return fmt.Errorf("%s: route %d: dir %q is outside of output directory", *routesFlag, i+1, v)
+				}
+			case "prefix":
+				r.Prefix = v
+			case "ext":
+				r.Ext = v
+			case "preset":
+				r.Preset = v
+			default:
+				return fmt.Errorf("%s: route %d: unknown key %q", *routesFlag, i+1, k)
+			}
+		}
+		if (r.Category == "") == (r.Tag == "") {
+			return fmt.Errorf("%s: route %d: expecting either category or tag", *routesFlag, i+1)
+		}
+		ro := outputOpts
+		if r.Preset != "" {
+			ro.Preset = r.Preset
+		}
+		if _, err := ro.Templates(); err != nil {
+			return fmt.Errorf("%s: route %d: %s", *routesFlag, i+1, err)
+		}
+		outputOpts.Routes = append(outputOpts.Routes, r)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+)
+
+// ConvertArgs are arguments of Converter.Convert RPC method.
+type ConvertArgs struct {
+	Export  []byte              `json:"export"`  // MT export, base64-encoded in JSON
+	Options map[string][]string `json:"options"` // options named after flags
+}
+
+// ConvertReply is the result of Converter.Convert RPC method.
+type ConvertReply struct {
+	Files  map[string][]byte `json:"files"` // converted files by path
+	Report string            `json:"report"`
+}
+
+// rpcConverter is the JSON-RPC service of "mt2kkr serve".
+type rpcConverter struct{}
+
+// Convert converts export into a set of files and the report.
+func (rpcConverter) Convert(args *ConvertArgs, reply *ConvertReply) error {
+	opts, err := serveArgs(args.Options)
+	if err != nil {
+		return err
+	}
+	return convertExport(context.Background(), bytes.NewReader(args.Export), opts, func(out string) error {
+		reply.Files = make(map[string][]byte)
+		return filepath.Walk(out, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(out, path)
+			if err != nil {
+				return err
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if rel == "report.txt" {
+				reply.Report = string(b)
+			} else {
+				reply.Files[filepath.ToSlash(rel)] = b
+			}
+			return nil
+		})
+	})
+}
+
+// rpcConn is a connection of a single JSON-RPC request over HTTP.
+type rpcConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (rpcConn) Close() error { return nil }
+
+// rpcHandler serves JSON-RPC requests POSTed to it, one per request,
+// e.g. {"method": "Converter.Convert", "params": [{"export": "..."}], "id": 1}.
+func rpcHandler(maxUpload int64) http.HandlerFunc {
+	server := rpc.NewServer()
+	server.RegisterName("Converter", rpcConverter{})
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "POST a JSON-RPC request", http.StatusMethodNotAllowed)
+			return
+		}
+		// Export is base64-encoded.
+		body := http.MaxBytesReader(w, r.Body, maxUpload/3*4+4096)
+		w.Header().Set("Content-Type", "application/json")
+		server.ServeRequest(jsonrpc.NewServerCodec(rpcConn{body, w}))
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+var (
+	sampleFlag     = flag.Int("sample", 0, "also copy a random sample of `n` converted posts, stratified by year and markup, into qa/ for review")
+	sampleSeedFlag = flag.Uint64("sample-seed", 1, "random `seed` for -sample; change it to get another sample")
+)
+
+// sampleStrata are output files of written entries by year and markup,
+// in order of writing.
+var (
+	sampleStrata = make(map[string][]string)
+	sampleKeys   []string
+)
+
+// addSample records written file for -sample.
+func (e *entry) addSample(filename string) {
+	if *sampleFlag <= 0 {
+		return
+	}
+	markup := e.Markup
+	if markup == "" {
+		markup = "html"
+	}
+	key := strconv.Itoa(e.Date.Year()) + " " + markup
+	if _, ok := sampleStrata[key]; !ok {
+		sampleKeys = append(sampleKeys, key)
+	}
+	sampleStrata[key] = append(sampleStrata[key], filename)
+}
+
+// pickSample returns up to n files, first one from each stratum,
+// largest first, and then the rest in proportion to stratum sizes.
+func pickSample(n int, rnd *rand.Rand) []string {
+	type stratum struct {
+		files []string
+		taken int
+	}
+	strata := make([]*stratum, len(sampleKeys))
+	for i, k := range sampleKeys {
+		files := append([]string(nil), sampleStrata[k]...)
+		rnd.Shuffle(len(files), func(i, j int) { files[i], files[j] = files[j], files[i] })
+		strata[i] = &stratum{files: files}
+	}
+	sort.SliceStable(strata, func(i, j int) bool { return len(strata[i].files) > len(strata[j].files) })
+	var picked []string
+	take := func(s *stratum) {
+		picked = append(picked, s.files[s.taken])
+		s.taken++
+	}
+	for _, s := range strata {
+		if len(picked) == n {
+			return picked
+		}
+		take(s)
+	}
+	for len(picked) < n {
+		// Take from the stratum with the highest share of files per
+		// taken one.
+		var best *stratum
+		for _, s := range strata {
+			if s.taken < len(s.files) && (best == nil || len(s.files)*best.taken > len(best.files)*s.taken) {
+				best = s
+			}
+		}
+		if best == nil {
+			break
+		}
+		take(best)
+	}
+	return picked
+}
+
+// writeSample copies sampled files into dir/qa.
+func writeSample(dir string) error {
+	if *sampleFlag <= 0 {
+		return nil
+	}
+	files := pickSample(*sampleFlag, rand.New(rand.NewPCG(*sampleSeedFlag, 0)))
+	sort.Strings(files)
+	for _, f := range files {
+		b, err := os.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dir, "qa", f)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, b, 0644); err != nil {
+			return err
+		}
+	}
+	log.Printf("Copied %d sample posts of %d strata into %s", len(files), len(sampleKeys), filepath.Join(dir, "qa"))
+	return nil
+}
+
+func setupSample() error {
+	if *sampleFlag > 0 && *outFlag != "files" {
+		return errors.New("-sample requires -out files")
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package main
+
+import "flag"
+
+var (
+	sandboxFlag       = flag.Bool("sandbox", false, "run external converters without network, with read-only file system and resource limits (Linux only)")
+	sandboxMemoryFlag = flag.Int("sandbox-memory", 1024, "address space limit of sandboxed converters in `megabytes`")
+	sandboxCPUFlag    = flag.Int("sandbox-cpu", 60, "CPU time limit of sandboxed converters in `seconds`")
+)
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+func setupSandbox() error {
+	return nil
+}
+
+// sandboxCommand returns command running name in new user, mount,
+// network and IPC namespaces. The sandbox-exec helper finishes setup
+// inside the namespaces and executes the converter.
+func sandboxCommand(ctx context.Context, name string, args ...string) (*exec.Cmd, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	helperArgs := append([]string{"sandbox-exec",
+		"-memory", strconv.Itoa(*sandboxMemoryFlag),
+		"-cpu", strconv.Itoa(*sandboxCPUFlag),
+		"--", name}, args...)
+	cmd := exec.CommandContext(ctx, self, helperArgs...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC,
+		// Run as nobody in the new namespace, so that the converter
+		// doesn't get capabilities after exec. The helper keeps
+		// CAP_SYS_ADMIN to remount file systems.
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: sandboxID, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: sandboxID, HostID: os.Getgid(), Size: 1}},
+		AmbientCaps: []uintptr{capSysAdmin},
+	}
+	return cmd, nil
+}
+
+// mountAttr is struct mount_attr of mount_setattr(2).
+type mountAttr struct {
+	set, clr, propagation, userns uint64
+}
+
+const (
+	sandboxID   = 65534
+	capSysAdmin = 21
+
+	sysMountSetattr = 442 // same number on all architectures
+	atRecursive     = 0x8000
+	mountAttrRdonly = 0x1
+
+	prSetNoNewPrivs      = 38
+	prCapAmbient         = 47
+	prCapAmbientClearAll = 4
+)
+
+var atFDCWD = -100
+
+// sandboxExec is the helper run in new namespaces by sandboxCommand. It
+// makes all mounts read-only, sets resource limits and executes the
+// command. It's invoked as "mt2kkr sandbox-exec -memory MB -cpu sec --
+// command [args]".
+func sandboxExec(args []string) {
+	fs := flag.NewFlagSet("sandbox-exec", flag.ExitOnError)
+	memory := fs.Int("memory", 1024, "address space limit in `megabytes`")
+	cpu := fs.Int("cpu", 60, "CPU time limit in `seconds`")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		log.Fatal("usage: mt2kkr sandbox-exec [-memory MB] [-cpu sec] -- command [args]")
+	}
+	// Don't propagate changes to the parent namespace.
+	if err := syscall.Mount("none", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		log.Fatalf("sandbox: %s", err)
+	}
+	root := []byte("/\x00")
+	attr := mountAttr{set: mountAttrRdonly}
+	if _, _, errno := syscall.Syscall6(sysMountSetattr, uintptr(atFDCWD),
+		uintptr(unsafe.Pointer(&root[0])), atRecursive,
+		uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0); errno != 0 {
+		log.Fatalf("sandbox: making file system read-only: %s (needs Linux 5.12)", errno)
+	}
+	limits := []struct {
+		resource int
+		value    uint64
+	}{
+		{syscall.RLIMIT_AS, uint64(*memory) << 20},
+		{syscall.RLIMIT_CPU, uint64(*cpu)},
+		{syscall.RLIMIT_FSIZE, 0},
+	}
+	for _, l := range limits {
+		if err := syscall.Setrlimit(l.resource, &syscall.Rlimit{Cur: l.value, Max: l.value}); err != nil {
+			log.Fatalf("sandbox: %s", err)
+		}
+	}
+	// Drop capabilities on exec.
+	for _, a := range [][2]uintptr{{prCapAmbient, prCapAmbientClearAll}, {prSetNoNewPrivs, 1}} {
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, a[0], a[1], 0); errno != 0 {
+			log.Fatalf("sandbox: prctl: %s", errno)
+		}
+	}
+	path, err := exec.LookPath(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("sandbox: %s", err)
+	}
+	err = syscall.Exec(path, fs.Args(), os.Environ())
+	log.Fatal(fmt.Errorf("sandbox: %s", err))
+}
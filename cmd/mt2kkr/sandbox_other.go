@@ -0,0 +1,25 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os/exec"
+)
+
+func setupSandbox() error {
+	if *sandboxFlag {
+		return errors.New("-sandbox is only supported on Linux")
+	}
+	return nil
+}
+
+func sandboxCommand(ctx context.Context, name string, args ...string) (*exec.Cmd, error) {
+	return nil, errors.New("sandbox is not supported")
+}
+
+func sandboxExec(args []string) {
+	log.Fatal("sandbox is only supported on Linux")
+}
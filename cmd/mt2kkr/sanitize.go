@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dchest/mt2kkr/output"
+)
+
+var profileFlag = flag.String("profile", "archive", "entry body sanitizer `profile`: archive (faithful) or publish (keep only safe elements, attributes and URLs)")
+
+// profiles are sanitizers of entry bodies selected by -profile.
+var profiles = map[string]output.Sanitizer{
+	"archive": {},
+	"publish": output.Publish,
+}
+
+var profile output.Sanitizer
+
+func setupProfile() error {
+	p, ok := profiles[*profileFlag]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for k := range profiles {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown profile %q (available: %s)", *profileFlag, strings.Join(names, ", "))
+	}
+	profile = p
+	return nil
+}
+
+// sanitizeBody applies passes of the selected profile to body,
+// reporting what was removed.
+func sanitizeBody(filename string, body []byte) []byte {
+	return profile.Apply(body, func(kind string, n int) {
+		if n > 0 {
+			rep.addf(filename, "sanitize", "removed %d %s", n, kind)
+		}
+	})
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var scaffoldFlag = flag.Bool("scaffold", false, "treat output directory as site root: write posts into its content directory and add a minimal site skeleton for -preset hugo, jekyll or eleventy")
+
+// scaffoldCSS styles converted posts and their comment blocks.
+const scaffoldCSS = `body { max-width: 40em; margin: 2em auto; padding: 0 1em; font-family: sans-serif; line-height: 1.5; }
+.comments { margin-top: 3em; border-top: 1px solid #ccc; }
+.comment { margin: 1.5em 0; }
+.comment header, .comment-header { color: #666; font-size: 0.9em; }
+.comment.by-author { padding-left: 1em; border-left: 3px solid #ccc; }
+`
+
+// scaffold is the site skeleton of a generator.
+type scaffold struct {
+	content string            // directory of posts
+	files   map[string]string // file contents by name, with {title} and {url}
+}
+
+var scaffolds = map[string]scaffold{
+	"hugo": {"content/posts", map[string]string{
+		"hugo.toml": `baseURL = {url}
+title = {title}
+
+[taxonomies]
+category = "categories"
+tag = "tags"
+
+# Converted posts are HTML.
+[markup.goldmark.renderer]
+unsafe = true
+`,
+		"archetypes/default.md": `---
+title: "{{ replace .File.ContentBaseName "-" " " | title }}"
+date: {{ .Date }}
+draft: true
+---
+`,
+		"layouts/_default/baseof.html": `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ if not .IsHome }}{{ .Title }} - {{ end }}{{ .Site.Title }}</title>
+<link rel="stylesheet" href="{{ "style.css" | relURL }}">
+</head>
+<body>
+<header><a href="{{ "/" | relURL }}">{{ .Site.Title }}</a></header>
+<main>{{ block "main" . }}{{ end }}</main>
+</body>
+</html>
+`,
+		"layouts/_default/single.html": `{{ define "main" }}
+<article>
+<h1>{{ .Title }}</h1>
+<p><time datetime="{{ .Date.Format "2006-01-02T15:04:05Z07:00" }}">{{ .Date.Format "January 2, 2006" }}</time>
+{{ range .Params.categories }}<a href="{{ printf "/categories/%s/" (urlize .) | relURL }}">{{ . }}</a> {{ end }}</p>
+{{/* Content includes the comments block. */}}
+{{ .Content }}
+</article>
+{{ end }}
+`,
+		"layouts/_default/list.html": `{{ define "main" }}
+<h1>{{ if .IsHome }}Archive{{ else }}{{ .Title }}{{ end }}</h1>
+{{ $pages := .Pages }}{{ if .IsHome }}{{ $pages = where .Site.RegularPages "Section" "posts" }}{{ end }}
+<ul>
+{{ range $pages }}<li><time>{{ .Date.Format "2006-01-02" }}</time> <a href="{{ .RelPermalink }}">{{ .Title }}</a></li>
+{{ end }}</ul>
+{{ end }}
+`,
+		"static/style.css": scaffoldCSS,
+	}},
+	"jekyll": {"_posts", map[string]string{
+		"_config.yml": `title: {title}
+url: {url}
+permalink: /:year/:month/:title/
+`,
+		"_layouts/default.html": `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{% if page.title %}{{ page.title }} - {% endif %}{{ site.title }}</title>
+<link rel="stylesheet" href="{{ "/style.css" | relative_url }}">
+</head>
+<body>
+<header><a href="{{ "/" | relative_url }}">{{ site.title }}</a></header>
+<main>{{ content }}</main>
+</body>
+</html>
+`,
+		"_layouts/post.html": `---
+layout: default
+---
+<article>
+<h1>{{ page.title }}</h1>
+<p><time datetime="{{ page.date | date_to_xmlschema }}">{{ page.date | date: "%B %-d, %Y" }}</time>
+{% for c in page.categories %}{{ c }} {% endfor %}</p>
+{% comment %}Content includes the comments block.{% endcomment %}
+{{ content }}
+</article>
+`,
+		"index.html": `---
+layout: default
+title: Archive
+---
+<h1>Archive</h1>
+<ul>
+{% for post in site.posts %}<li><time>{{ post.date | date: "%Y-%m-%d" }}</time> <a href="{{ post.url | relative_url }}">{{ post.title }}</a></li>
+{% endfor %}</ul>
+`,
+		"style.css": scaffoldCSS,
+	}},
+	"eleventy": {"posts", map[string]string{
+		"eleventy.config.js": `module.exports = function (eleventyConfig) {
+	eleventyConfig.addPassthroughCopy("style.css");
+	eleventyConfig.addFilter("isoDate", (d) => d.toISOString());
+	eleventyConfig.addFilter("shortDate", (d) => d.toISOString().slice(0, 10));
+	return { dir: { input: ".", includes: "_includes" } };
+};
+`,
+		"_data/site.json": `{"title": {title}, "url": {url}}
+`,
+		"posts/posts.json": `{"tags": ["post"]}
+`,
+		"_includes/base.njk": `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{% if title %}{{ title }} - {% endif %}{{ site.title }}</title>
+<link rel="stylesheet" href="/style.css">
+</head>
+<body>
+<header><a href="/">{{ site.title }}</a></header>
+<main>{{ content | safe }}</main>
+</body>
+</html>
+`,
+		"_includes/post.njk": `---
+layout: base.njk
+---
+<article>
+<h1>{{ title }}</h1>
+<p><time datetime="{{ page.date | isoDate }}">{{ page.date | shortDate }}</time>
+{% for c in categories %}{{ c }} {% endfor %}</p>
+{# Content includes the comments block. #}
+{{ content | safe }}
+</article>
+`,
+		"index.njk": `---
+layout: base.njk
+---
+<h1>Archive</h1>
+<ul>
+{% for post in collections.post | reverse %}<li><time>{{ post.date | shortDate }}</time> <a href="{{ post.url }}">{{ post.data.title }}</a></li>
+{% endfor %}</ul>
+`,
+		"style.css": scaffoldCSS,
+	}},
+}
+
+func setupScaffold() error {
+	if !*scaffoldFlag {
+		return nil
+	}
+	s, ok := scaffolds[*presetFlag]
+	if !ok {
+		return errors.New("-scaffold requires -preset hugo, jekyll or eleventy")
+	}
+	if *outFlag != "files" {
+		return errors.New("-scaffold requires -out files")
+	}
+	if outputOpts.PostDir == "" {
+		outputOpts.PostDir = s.content
+	}
+	return nil
+}
+
+// writeScaffold writes site skeleton files into dir, keeping existing
+// ones.
+func writeScaffold(dir string) error {
+	if !*scaffoldFlag {
+		return nil
+	}
+	files := scaffolds[*presetFlag].files
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	r := strings.NewReplacer("{title}", strconv.Quote(*feedTitleFlag), "{url}", strconv.Quote(siteURL("/")))
+	for _, name := range names {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if _, err := os.Stat(file); err == nil {
+			log.Printf("Keeping existing %s", name)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, []byte(r.Replace(files[name])), 0644); err != nil {
+			return fmt.Errorf("scaffold: %s", err)
+		}
+	}
+	return nil
+}
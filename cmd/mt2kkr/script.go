@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dchest/mt2kkr/mt"
+	"github.com/dchest/mt2kkr/script"
+)
+
+var scriptFlag listFlag
+
+func init() {
+	flag.Var(&scriptFlag, "script", "call transform(entry) of Starlark `file` on each entry, which returns it changed, or None to drop it (can repeat)")
+}
+
+// scriptTransform returns a transform calling function transform of
+// script file with the entry as a dict, with the keys of -filter JSON.
+func scriptTransform(filename string) (mt.Transform, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := script.Load(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := prog.Global("transform").(*script.Function); !ok {
+		return nil, fmt.Errorf("-script %s: no function transform", filename)
+	}
+	return func(e *mt.Entry) (*mt.Entry, error) {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, err
+		}
+		// Give lists instead of None, so that scripts can iterate them.
+		for _, k := range []string{"categories", "tags", "keywords", "comments"} {
+			if m[k] == nil {
+				m[k] = []interface{}{}
+			}
+		}
+		in, err := script.FromGo(m)
+		if err != nil {
+			return nil, err
+		}
+		out, err := prog.Call("transform", in)
+		if err != nil {
+			return nil, fmt.Errorf("-script: %s: %s", e.Slug, err)
+		}
+		if out == nil {
+			return nil, nil
+		}
+		v, err := script.ToGo(out)
+		if err == nil {
+			b, err = json.Marshal(v)
+		}
+		var ne mt.Entry
+		if err == nil {
+			err = json.Unmarshal(b, &ne)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("-script %s: %s: transform result: %s", filename, e.Slug, err)
+		}
+		keepHidden(e, &ne)
+		return &ne, nil
+	}, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+func TestScriptTransform(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "fixup.star")
+	src := `
+def transform(entry):
+    if "Spam" in entry["categories"]:
+        return None
+    entry["title"] = entry["title"].strip()
+    entry["body"] = re.sub(r"http://old\.example\.com/", "/", entry["body"])
+    entry["tags"] = [t.lower() for t in entry["tags"]]
+    return entry
+`
+	if err := os.WriteFile(filename, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := scriptTransform(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &mt.Entry{
+		Title:    " Hello ",
+		Body:     `<a href="http://old.example.com/x.html">x</a>`,
+		Tags:     []string{"Go", "MT"},
+		Password: "secret",
+		Comments: []*mt.Comment{{Author: "a", Content: "c", SrcStart: 10, SrcEnd: 12}},
+	}
+	got, err := tr(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Hello" || got.Body != `<a href="/x.html">x</a>` || !reflect.DeepEqual(got.Tags, []string{"go", "mt"}) {
+		t.Errorf("got %+v", got)
+	}
+	if got.Password != "secret" || got.Comments[0].SrcStart != 10 {
+		t.Errorf("hidden fields weren't kept: %+v", got)
+	}
+	got, err = tr(&mt.Entry{Categories: []string{"Spam"}})
+	if err != nil || got != nil {
+		t.Errorf("spam entry: got %v, %v; want nil", got, err)
+	}
+}
+
+func TestScriptTransformErrors(t *testing.T) {
+	dir := t.TempDir()
+	for src, want := range map[string]string{
+		"x = 1\n": "-script " + dir + "/s.star: no function transform",
+		"def transform(e):\n  return e['nope']\n":    "-script: slug: " + dir + `/s.star:2: key "nope" not in dict`,
+		"def transform(e):\n  return {'title': 1}\n": "-script " + dir + "/s.star: slug: transform result: json: cannot unmarshal number into Go struct field Entry.title of type string",
+		"def transform(e)\n  return e\n":             dir + "/s.star:1: expected :, got newline",
+	} {
+		filename := filepath.Join(dir, "s.star")
+		if err := os.WriteFile(filename, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		tr, err := scriptTransform(filename)
+		if err == nil {
+			_, err = tr(&mt.Entry{Slug: "slug"})
+		}
+		if err == nil || err.Error() != want {
+			t.Errorf("%q: got error %v, want %s", src, err, want)
+		}
+	}
+}
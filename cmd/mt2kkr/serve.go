@@ -0,0 +1,230 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// serveAPI runs HTTP server converting uploaded MT exports, with an
+// upload form at /, JSON-RPC service at /rpc and metrics at /metrics. It's invoked as
+// "mt2kkr serve [-addr addr]" or "mt2kkr serve-api [-addr addr]".
+func serveAPI(name string, args []string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "listen on `address`")
+	maxUpload := fs.Int64("max-upload", 100<<20, "maximum upload size in `bytes`")
+	fs.Parse(args)
+	http.HandleFunc("/convert", func(w http.ResponseWriter, r *http.Request) {
+		convertHandler(w, r, *maxUpload)
+	})
+	http.HandleFunc("/rpc", rpcHandler(*maxUpload))
+	http.HandleFunc("/metrics", metricsHandler)
+	http.HandleFunc("/", uploadFormHandler)
+	log.Printf("Listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+// uploadForm is the page for converting exports from a browser.
+const uploadForm = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>mt2kkr</title></head>
+<body>
+<h1>Convert Movable Type export</h1>
+<form method="post" action="/convert" enctype="multipart/form-data">
+<p><input type="file" name="file" required></p>
+<p><label>Preset <select name="preset">
+<option value="">kkr (default)</option>
+<option>jekyll</option>
+<option>hugo</option>
+<option>eleventy</option>
+<option>html</option>
+</select></label></p>
+<p><button type="submit">Convert and download zip</button></p>
+</form>
+</body></html>
+`
+
+func uploadFormHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, uploadForm)
+}
+
+// serveFlags are flags that API clients can set. They only change how
+// entries are converted; flags that read or write local files, fetch
+// URLs, run commands or use the server's secrets are left out, as are
+// file name patterns of output.
+var serveFlags = map[string]bool{
+	"archive-meta": true, "author-email": true, "auto-more": true,
+	"canonical-base": true, "canonical-path": true, "checksums": true,
+	"comment-charsets": true, "comment-feeds": true, "comments-feed-size": true,
+	"cw-field": true, "date-offset": true, "dialect": true, "digest": true,
+	"drop-field": true, "featured": true, "featured-weight": true,
+	"feed": true, "feed-size": true, "feed-title": true, "fix-mojibake": true,
+	"footer-field": true, "from": true, "heading-offset": true,
+	"html-report": true, "json-feed": true, "linklog-category": true,
+	"linklog-words": true, "markers": true, "max-slug": true, "mf2": true,
+	"modernize": true, "more-marker": true, "noindex-before": true,
+	"noindex-category": true, "normalize-headings": true,
+	"normalize-unicode": true, "now": true, "passes": true,
+	"photo-category": true, "photo-words": true, "pii": true,
+	"platform": true, "preset": true, "profile": true, "protected": true,
+	"quote-posts": true, "redirect-status": true, "rename-slug": true,
+	"repair-comments": true, "sample": true, "sample-seed": true,
+	"scaffold": true, "site-url": true, "slug-rule": true,
+	"slug-strategy": true, "source-map": true, "toc": true,
+	"toc-headings": true, "toc-length": true, "translation-lang": true,
+	"translation-match": true, "url-pattern": true, "uuid": true,
+	"uuid-namespace": true,
+}
+
+// serveFlagAllowed reports whether flag can be set by API clients.
+func serveFlagAllowed(name string) bool {
+	return serveFlags[name] && flag.Lookup(name) != nil
+}
+
+// serveArgs returns command-line arguments for options, skipping the
+// "file" field.
+func serveArgs(params map[string][]string) ([]string, error) {
+	var args []string
+	names := make([]string, 0, len(params))
+	for k := range params {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if k == "file" {
+			continue
+		}
+		if !serveFlagAllowed(k) {
+			return nil, fmt.Errorf("option %q is not allowed", k)
+		}
+		for _, v := range params[k] {
+			args = append(args, "-"+k+"="+v)
+		}
+	}
+	return args, nil
+}
+
+// errConversion is returned by convertExport when conversion failed.
+var errConversion = errors.New("conversion failed")
+
+// convertExport converts MT export from input with args in a temporary
+// directory, calling done with the output directory. On failure, it
+// returns errConversion wrapped with converter log.
+func convertExport(ctx context.Context, input io.Reader, args []string, done func(out string) error) error {
+	dir, err := os.MkdirTemp("", "mt2kkr-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	// Convert in a child process, so that conversions don't share
+	// state and a fatal error in one doesn't stop the server.
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	statsFile := filepath.Join(dir, "stats.json")
+	args = append(args, "-report", filepath.Join(out, "report.txt"), "-stats", statsFile, out)
+	cmd := exec.CommandContext(ctx, self, args...)
+	// When the client goes away, interrupt the child so that it stops
+	// its converters, and kill it if it doesn't exit.
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = 5 * time.Second
+	cmd.Stdin = input
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	start := time.Now()
+	err = cmd.Run()
+	metrics.observe(time.Since(start), err != nil, statsFile)
+	if err != nil {
+		return fmt.Errorf("%w:\n%s", errConversion, stderr.String())
+	}
+	return done(out)
+}
+
+// convertHandler accepts MT export as "file" field of multipart form or
+// as request body, and responds with zip of converted files. Query
+// parameters and other form fields set options named after flags.
+func convertHandler(w http.ResponseWriter, r *http.Request, maxUpload int64) {
+	if r.Method != "POST" {
+		http.Error(w, "POST an MT export to convert", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxUpload)
+	var input io.Reader = r.Body
+	params := r.URL.Query()
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		input = f
+		params = r.Form
+	}
+	args, err := serveArgs(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = convertExport(r.Context(), input, args, func(out string) error {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="mt2kkr.zip"`)
+		if err := writeZip(w, out); err != nil {
+			log.Printf("serve-api: %s", err)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errConversion):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeZip writes files from dir into zip archive.
+func writeZip(w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		zf, err := zw.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(zf, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
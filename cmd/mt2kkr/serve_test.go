@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestServeRejectsUnsafeFlags checks that flags touching the server's
+// files, network or commands can't be set by API clients.
+func TestServeRejectsUnsafeFlags(t *testing.T) {
+	unsafe := map[string]string{
+		"spill-dir":          "/tmp/victimdir",
+		"max-section-size":   "1",
+		"spell-dict":         "en=/etc/passwd",
+		"verify-live":        "http://169.254.169.254/{basename}",
+		"live-content":       "x",
+		"mirror-assets":      "/tmp/x",
+		"assets-state":       "/tmp/x",
+		"assets-map":         "/tmp/x",
+		"cookie-jar":         "/tmp/x",
+		"http-header":        "X:y",
+		"basic-auth":         "a:b",
+		"template":           "/etc/passwd",
+		"template-dir":       "/etc",
+		"routes":             "/etc/passwd",
+		"config":             "/etc/passwd",
+		"filter":             "rm -rf /",
+		"script":             "/etc/passwd",
+		"pre-convert":        "true",
+		"post-write":         "true",
+		"converter-fallback": "true",
+		"report":             "/tmp/x",
+		"stats":              "/tmp/x",
+		"access-log":         "/var/log/access.log",
+		"archive-ids":        "/tmp/x",
+		"footer":             "/etc/passwd",
+		"pii-rules":          "/etc/passwd",
+		"skip-list":          "/etc/passwd",
+		"translit":           "/etc/passwd",
+		"modernize-map":      "/etc/passwd",
+		"cw-terms":           "/etc/passwd",
+		"passphrase-file":    "/etc/passwd",
+		"encrypt-category":   "Private",
+		"tumblr-media":       "/etc",
+		"comments-feed":      "../x.xml",
+		"comment-feed-name":  "../{name}.xml",
+		"ext":                "/../x",
+		"out":                "stdout",
+		"sandbox":            "false",
+		"no-such-flag":       "1",
+	}
+	for name, value := range unsafe {
+		q := url.Values{name: {value}}
+		r := httptest.NewRequest("POST", "/convert?"+q.Encode(), strings.NewReader(""))
+		w := httptest.NewRecorder()
+		convertHandler(w, r, 1<<20)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want %d", name, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestServeArgsAllowed(t *testing.T) {
+	args, err := serveArgs(map[string][]string{"preset": {"hugo"}, "file": {"x"}, "slug-strategy": {"title"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(args, " "); got != "-preset=hugo -slug-strategy=title" {
+		t.Errorf("got args %q", got)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+var skipListFlag = flag.String("skip-list", "", "YAML `file` listing entries to skip, with basename, date or title, and reason")
+
+// skipRule is an item of the skip list. Entry is skipped if it matches
+// all non-empty fields.
+type skipRule struct {
+	Basename string
+	Date     string // 2006-01-02 15:04:05
+	Title    string
+	Reason   string
+}
+
+var skipRules []*skipRule
+
+// loadSkipList parses a skip list: a YAML sequence of flat mappings
+// with basename, date, title and reason keys.
+func loadSkipList(filename string) error {
+	lines, err := readLines(filename)
+	if err != nil {
+		return err
+	}
+	var r *skipRule
+	for _, line := range lines {
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			r = new(skipRule)
+			skipRules = append(skipRules, r)
+			line = strings.TrimSpace(line[1:])
+			if line == "" {
+				continue
+			}
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok || r == nil {
+			return fmt.Errorf("%s: bad line %q", filename, line)
+		}
+		v, err := yamlScalar(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("%s: %s: %s", filename, k, err)
+		}
+		switch strings.TrimSpace(k) {
+		case "basename":
+			r.Basename = v
+		case "date":
+			r.Date = v
+		case "title":
+			r.Title = v
+		case "reason":
+			r.Reason = v
+		default:
+			return fmt.Errorf("%s: unknown key %q", filename, k)
+		}
+	}
+	for _, r := range skipRules {
+		if r.Basename == "" && r.Date == "" && r.Title == "" {
+			return fmt.Errorf("%s: item without basename, date or title", filename)
+		}
+	}
+	return nil
+}
+
+// yamlScalar returns value of a plain or quoted YAML scalar.
+func yamlScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("bad quoted value %s", s)
+		}
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1), nil
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s, nil
+}
+
+// knownSkip returns the skip list rule matching entry, or nil.
+func (e *entry) knownSkip(basename string) *skipRule {
+	title, _ := strconv.Unquote(e.Header["title"])
+	for _, r := range skipRules {
+		if (r.Basename == "" || r.Basename == basename) &&
+			(r.Date == "" || r.Date == e.Date.Format("2006-01-02 15:04:05")) &&
+			(r.Title == "" || r.Title == title) {
+			return r
+		}
+	}
+	return nil
+}
+
+// skipKnown reports whether entry is in the skip list. Known skips are
+// reported separately from new problems and don't produce warnings.
+func (e *entry) skipKnown(basename string) bool {
+	r := e.knownSkip(basename)
+	if r == nil {
+		return false
+	}
+	id := basename
+	if id == "" {
+		id = e.Date.Format("2006-01-02 15:04:05")
+	}
+	log.Printf("Skipping %s: %s", id, r.Reason)
+	rep.addKnownSkip(id, r.Reason)
+	return true
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var translitFlag = flag.String("translit", "", "read extra slug transliteration table from `file`, e.g. for CJK characters, which have no built-in table")
+
+// translit maps characters to their Latin transliterations for slugs.
+var translit = make(map[rune]string)
+
+func addTranslit(pairs string) {
+	f := strings.Fields(pairs)
+	for i := 0; i+1 < len(f); i += 2 {
+		r, _ := utf8.DecodeRuneInString(f[i])
+		translit[r] = f[i+1]
+	}
+}
+
+func init() {
+	// Latin with diacritics.
+	addTranslit(`à a á a â a ã a ä a å a æ ae ç c è e é e ê e ë e ì i í i î i ï i
+		ñ n ò o ó o ô o õ o ö o ø o ù u ú u û u ü u ý y ÿ y ß ss
+		ā a ă a ą a ć c č c ď d đ d ē e ę e ě e ğ g ī i ı i ł l ń n ň n
+		ō o ő o œ oe ř r ś s ş s š s ť t ū u ů u ű u ź z ż z ž z`)
+	// Cyrillic.
+	addTranslit(`а a б b в v г g д d е e ё yo ж zh з z и i й y к k л l м m
+		н n о o п p р r с s т t у u ф f х kh ц ts ч ch ш sh щ shch ъ _
+		ы y ь _ э e ю yu я ya є ye і i ї yi ґ g ў u`)
+	// Greek.
+	addTranslit(`α a β v γ g δ d ε e ζ z η i θ th ι i κ k λ l μ m ν n ξ x
+		ο o π p ρ r σ s ς s τ t υ y φ f χ ch ψ ps ω o ά a έ e ή i ί i
+		ό o ύ y ώ o ϊ i ϋ y ΐ i ΰ y`)
+	// "_" stands for an empty transliteration.
+	for r, s := range translit {
+		if s == "_" {
+			translit[r] = ""
+		}
+	}
+}
+
+// loadTranslit reads transliteration table from file, with each line
+// containing a character and its transliteration separated by space,
+// e.g. "中 zhong".
+func loadTranslit(filename string) error {
+	lines, err := readLines(filename)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		f := strings.Fields(line)
+		if len(f) != 2 || utf8.RuneCountInString(f[0]) != 1 {
+			return fmt.Errorf("%s: bad transliteration `%s`", filename, line)
+		}
+		addTranslit(line)
+	}
+	return nil
+}
+
+// slugify returns a slug made of lowercase ASCII letters, digits and
+// dashes. Non-ASCII characters are transliterated, and those missing
+// from the table, such as CJK characters, which need a -translit table,
+// are dropped. The second result reports whether
+// transliteration was used.
+func slugify(s string) (slug string, transliterated bool) {
+	var b strings.Builder
+	dash := false
+	put := func(s string) {
+		for _, r := range s {
+			if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+				if dash && b.Len() > 0 {
+					b.WriteByte('-')
+				}
+				dash = false
+				b.WriteRune(r)
+			} else {
+				dash = true
+			}
+		}
+	}
+	for _, r := range strings.ToLower(s) {
+		if r < utf8.RuneSelf {
+			put(string(r))
+			continue
+		}
+		if t, ok := translit[r]; ok {
+			transliterated = true
+			put(t)
+			continue
+		}
+		dash = true
+	}
+	return b.String(), transliterated
+}
+
+// titleSlug makes a slug from the entry title, falling back to a
+// date-based slug. Fallbacks are reported by prepare, once the output
+// file name is known.
+func (e *entry) titleSlug() string {
+	title, _ := strconv.Unquote(e.Header["title"])
+	slug, tr := slugify(title)
+	why := "title slug"
+	if e.Slug == "" {
+		why = "no basename"
+	}
+	if slug == "" {
+		slug = e.Date.Format("150405")
+		e.slugNote = fmt.Sprintf("%s, using date-based slug for %q", why, title)
+		return slug
+	}
+	if tr {
+		e.slugNote = fmt.Sprintf("%s, transliterated %q", why, title)
+	}
+	return slug
+}
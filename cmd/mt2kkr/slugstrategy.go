@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var slugStrategyFlag = flag.String("slug-strategy", "basename", "how to make output file names: basename (falling back to title), title, date-counter (number of entry in its day) or hash (of basename or title)")
+
+var slugRuleFlag listFlag
+
+func init() {
+	flag.Var(&slugRuleFlag, "slug-rule", "use slug strategy for entries since date or for the entry with basename, as `date=strategy` or `basename=strategy` (can repeat)")
+}
+
+// slugStrategies make names of entries.
+var slugStrategies = map[string]func(e *entry) string{
+	"basename": func(e *entry) string {
+		if e.Slug == "" {
+			return e.titleSlug()
+		}
+		return strings.Replace(e.Slug, "_", "-", -1)
+	},
+	"title": (*entry).titleSlug,
+	"date-counter": func(e *entry) string {
+		e.counterDay = e.Date.Format("2006-01-02")
+		return strconv.Itoa(dayCounts[e.counterDay] + 1)
+	},
+	"hash": func(e *entry) string {
+		s := e.Slug
+		if s == "" {
+			s = e.Date.Format(time.RFC3339) + " " + e.Title
+		}
+		sum := sha1.Sum([]byte(s))
+		return hex.EncodeToString(sum[:4])
+	},
+}
+
+// dayCounts are numbers of entries by day for date-counter. Entries
+// are counted when they pass conversion, so that skipped entries leave
+// no gaps.
+var dayCounts = make(map[string]int)
+
+// slugEra is a slug strategy for entries since date.
+type slugEra struct {
+	since    time.Time
+	strategy string
+}
+
+var (
+	slugEras      []slugEra         // sorted by date
+	entryStrategy map[string]string // by basename
+)
+
+func setupSlugStrategy() error {
+	if slugStrategies[*slugStrategyFlag] == nil {
+		return fmt.Errorf("unknown -slug-strategy %q", *slugStrategyFlag)
+	}
+	slugEras, entryStrategy = nil, make(map[string]string)
+	for _, r := range slugRuleFlag {
+		k, s, ok := strings.Cut(r, "=")
+		if !ok || slugStrategies[s] == nil {
+			return fmt.Errorf("bad -slug-rule %q", r)
+		}
+		if t, err := time.ParseInLocation("2006-01-02", k, time.Local); err == nil {
+			slugEras = append(slugEras, slugEra{t, s})
+		} else {
+			entryStrategy[k] = s
+		}
+	}
+	sort.Slice(slugEras, func(i, j int) bool { return slugEras[i].since.Before(slugEras[j].since) })
+	return nil
+}
+
+// slugStrategy returns the name of slug strategy for entry.
+func (e *entry) slugStrategy() string {
+	if s, ok := entryStrategy[e.Slug]; ok && e.Slug != "" {
+		return s
+	}
+	s := *slugStrategyFlag
+	for _, era := range slugEras {
+		if e.Date.Before(era.since) {
+			break
+		}
+		s = era.strategy
+	}
+	return s
+}
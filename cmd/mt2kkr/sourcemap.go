@@ -0,0 +1,8 @@
+package main
+
+import "flag"
+
+var sourceMapFlag = flag.Bool("source-map", false, "write .map.json next to each output file mapping it to input lines")
+
+// inputName is the name of input file or URL, empty for stdin.
+var inputName string
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var spellDictFlag listFlag
+
+func init() {
+	flag.Var(&spellDictFlag, "spell-dict", "report unknown words using word list `lang=file`, one word per line (can repeat)")
+}
+
+// spellDicts maps language names to sets of lowercase words.
+var spellDicts = make(map[string]map[string]bool)
+
+func setupSpell() error {
+	for _, d := range spellDictFlag {
+		lang, file, ok := strings.Cut(d, "=")
+		if !ok {
+			return fmt.Errorf("bad -spell-dict %q, must be lang=file", d)
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		words := make(map[string]bool)
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			// Hunspell .dic files have flags after slash.
+			w, _, _ := strings.Cut(strings.TrimSpace(s.Text()), "/")
+			if w != "" {
+				words[strings.ToLower(w)] = true
+			}
+		}
+		f.Close()
+		if err := s.Err(); err != nil {
+			return err
+		}
+		spellDicts[lang] = words
+	}
+	return nil
+}
+
+// spellWords returns words of text, skipping numbers, URLs and emails.
+func spellWords(text string) []string {
+	var words []string
+	for _, w := range strings.Fields(text) {
+		w = strings.TrimFunc(w, unicode.IsPunct)
+		if w == "" || strings.IndexFunc(w, unicode.IsLetter) < 0 ||
+			strings.IndexFunc(w, unicode.IsDigit) >= 0 || strings.ContainsAny(w, "/@:.=<>") {
+			continue
+		}
+		words = append(words, w)
+	}
+	return words
+}
+
+// damagedWord reports whether word looks like a result of encoding
+// damage: it has the replacement character, mojibake sequences or a
+// mix of scripts.
+func damagedWord(w string) bool {
+	if strings.ContainsRune(w, unicode.ReplacementChar) || hasMojibake(w) {
+		return true
+	}
+	var latin, cyrillic, greek bool
+	for _, r := range w {
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin = true
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic = true
+		case unicode.Is(unicode.Greek, r):
+			greek = true
+		}
+	}
+	n := 0
+	for _, b := range []bool{latin, cyrillic, greek} {
+		if b {
+			n++
+		}
+	}
+	return n > 1
+}
+
+// spellCheck reports words of title and body missing from the
+// dictionary of the entry language, which is guessed as the one
+// knowing the most words, and words that look damaged.
+func (e *entry) spellCheck(filename string, body []byte) {
+	if len(spellDicts) == 0 {
+		return
+	}
+	title, _ := strconv.Unquote(e.Header["title"])
+	words := spellWords(title + "\n" + stripTags(string(body)))
+	lang, best := "", -1
+	for l, dict := range spellDicts {
+		n := 0
+		for _, w := range words {
+			if dict[strings.ToLower(w)] {
+				n++
+			}
+		}
+		if n > best || n == best && l < lang {
+			lang, best = l, n
+		}
+	}
+	var unknown, damaged []string
+	seen := make(map[string]bool)
+	for _, w := range words {
+		lw := strings.ToLower(w)
+		if seen[lw] {
+			continue
+		}
+		seen[lw] = true
+		switch {
+		case damagedWord(w):
+			damaged = append(damaged, w)
+		case len([]rune(w)) > 1 && !spellDicts[lang][lw]:
+			unknown = append(unknown, w)
+		}
+	}
+	if len(damaged) > 0 {
+		rep.addf(filename, "spell", "possibly damaged words: %s", wordList(damaged))
+	}
+	if len(unknown) > 0 {
+		rep.addf(filename, "spell", "%d unknown words (%s): %s", len(unknown), lang, wordList(unknown))
+	}
+}
+
+// wordList returns up to 20 first words sorted.
+func wordList(words []string) string {
+	more := ""
+	if len(words) > 20 {
+		more = fmt.Sprintf(" and %d more", len(words)-20)
+		words = words[:20]
+	}
+	words = append([]string(nil), words...)
+	sort.Strings(words)
+	return strings.Join(words, ", ") + more
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"flag"
+	"io"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+var (
+	maxSectionFlag = flag.Int("max-section-size", 0, "keep at most `MB` of each entry section in memory, saving longer sections (e.g. with embedded base64 media) to -spill-dir and failing their entries after converting the rest; 0 means no limit")
+	spillDirFlag   = flag.String("spill-dir", "", "`directory` for sections longer than -max-section-size (default is system temporary directory)")
+)
+
+// newParser returns MT export parser with memory limits and
+// transforms from flags.
+func newParser(r io.Reader) *mt.Parser {
+	p := mt.NewParser(r)
+	p.Transforms = transforms
+	p.MaxSection = *maxSectionFlag << 20
+	p.SpillDir = *spillDirFlag
+	p.Dialect = *dialectFlag
+	return p
+}
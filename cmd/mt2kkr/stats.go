@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+var statsFlag = flag.String("stats", "", "write conversion statistics as JSON to `file`")
+
+// convStats are counters of a conversion run.
+type convStats struct {
+	Entries       atomic.Int64
+	Comments      atomic.Int64
+	AssetsFetched atomic.Int64
+	AssetsFailed  atomic.Int64
+	AssetRetries  atomic.Int64
+	AssetBytes    atomic.Int64
+}
+
+var stats convStats
+
+// statsSnapshot is the JSON form of convStats.
+type statsSnapshot struct {
+	Entries       int64 `json:"entries"`
+	Comments      int64 `json:"comments"`
+	AssetsFetched int64 `json:"assets_fetched"`
+	AssetsFailed  int64 `json:"assets_failed"`
+	AssetRetries  int64 `json:"asset_retries"`
+	AssetBytes    int64 `json:"asset_bytes"`
+}
+
+func (s *convStats) snapshot() statsSnapshot {
+	return statsSnapshot{
+		Entries:       s.Entries.Load(),
+		Comments:      s.Comments.Load(),
+		AssetsFetched: s.AssetsFetched.Load(),
+		AssetsFailed:  s.AssetsFailed.Load(),
+		AssetRetries:  s.AssetRetries.Load(),
+		AssetBytes:    s.AssetBytes.Load(),
+	}
+}
+
+// countingReader counts bytes read into n.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// writeStats writes statistics to the file given by -stats, if any.
+func writeStats() error {
+	if *statsFlag == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(stats.snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*statsFlag, append(b, '\n'), 0644)
+}
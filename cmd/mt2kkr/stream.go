@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dchest/mt2kkr/output"
+)
+
+var (
+	outFlag          = flag.String("out", "files", "output `mode`: files (write to outdir), stream (write all entries to stdout) or stdout (convert single entry to stdout)")
+	streamFormatFlag = flag.String("stream-format", "yaml", "`format` for -out stream: yaml (multi-document) or ndjson")
+)
+
+var stdout = bufio.NewWriter(os.Stdout)
+
+func setupOutput() error {
+	switch *outFlag {
+	case "files", "stream", "stdout":
+	default:
+		return fmt.Errorf("unknown -out mode %q", *outFlag)
+	}
+	switch *streamFormatFlag {
+	case "yaml", "ndjson":
+	default:
+		return fmt.Errorf("unknown -stream-format %q", *streamFormatFlag)
+	}
+	return nil
+}
+
+// stdoutWriter writes converted entry to stdout, as it would be
+// written to file by fw. It's an error to have more than one entry.
+type stdoutWriter struct {
+	fw    *output.FileWriter
+	wrote bool
+}
+
+func (w *stdoutWriter) WriteEntry(p *output.Entry) error {
+	if w.wrote {
+		return errors.New("-out stdout: input has more than one entry")
+	}
+	w.wrote = true
+	b, err := w.fw.Render(p)
+	if err != nil {
+		return err
+	}
+	stdout.Write(b)
+	return stdout.Flush()
+}
+
+func (w *stdoutWriter) Close() error { return nil }
+
+// streamEntry is the representation of entry in -out stream.
+type streamEntry struct {
+	File     string                 `json:"file"`
+	Header   map[string]interface{} `json:"header"`
+	Body     string                 `json:"body"`
+	Comments []streamComment        `json:"comments,omitempty"`
+}
+
+type streamComment struct {
+	Author  string    `json:"author"`
+	Email   string    `json:"email,omitempty"`
+	URL     string    `json:"url,omitempty"`
+	Date    time.Time `json:"date"`
+	Content string    `json:"content"`
+}
+
+// headerValue converts front matter value to a plain value.
+func headerValue(v string) interface{} {
+	if s, err := strconv.Unquote(v); err == nil {
+		return s
+	}
+	var x interface{}
+	if err := json.Unmarshal([]byte(v), &x); err == nil {
+		return x
+	}
+	return v
+}
+
+// streamWriter writes converted entries to stdout as -stream-format
+// documents.
+type streamWriter struct{}
+
+func (streamWriter) Close() error { return nil }
+
+func (streamWriter) WriteEntry(p *output.Entry) error {
+	filename := p.Filename
+	log.Printf("Streaming %s", filename)
+	body := p.Content
+	if *streamFormatFlag == "ndjson" {
+		se := streamEntry{
+			File:   filename,
+			Header: make(map[string]interface{}, len(p.Header)),
+			Body:   string(body),
+		}
+		for k, v := range p.Header {
+			se.Header[k] = headerValue(v)
+		}
+		for _, c := range p.Source.Comments {
+			se.Comments = append(se.Comments, streamComment{c.Author, c.Email, c.URL, c.Date, c.Content})
+		}
+		b, err := json.Marshal(se)
+		if err != nil {
+			return err
+		}
+		stdout.Write(b)
+		stdout.WriteByte('\n')
+		return stdout.Flush()
+	}
+	// YAML document. Header values are already YAML scalars.
+	fmt.Fprintf(stdout, "---\nfile: %s\n", strconv.Quote(filename))
+	keys := make([]string, 0, len(p.Header))
+	for k := range p.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	stdout.WriteString("header:\n")
+	for _, k := range keys {
+		fmt.Fprintf(stdout, "  %s: %s\n", k, p.Header[k])
+	}
+	stdout.WriteString("body: " + yamlBlock(string(body), "  "))
+	if len(p.Source.Comments) > 0 {
+		stdout.WriteString("comments:\n")
+		for _, c := range p.Source.Comments {
+			fmt.Fprintf(stdout, "  - author: %s\n", strconv.Quote(c.Author))
+			fmt.Fprintf(stdout, "    email: %s\n", strconv.Quote(c.Email))
+			fmt.Fprintf(stdout, "    url: %s\n", strconv.Quote(c.URL))
+			fmt.Fprintf(stdout, "    date: %s\n", c.Date.Format(time.RFC3339))
+			stdout.WriteString("    content: " + yamlBlock(c.Content, "      "))
+		}
+	}
+	return stdout.Flush()
+}
+
+// yamlBlock formats s as YAML literal block scalar with the given
+// indentation, including the final newline.
+func yamlBlock(s, indent string) string {
+	if s == "" {
+		return "\"\"\n"
+	}
+	var b strings.Builder
+	b.WriteString("|")
+	if strings.HasPrefix(s, " ") || strings.HasPrefix(s, "\n") {
+		b.WriteString("2")
+	}
+	if !strings.HasSuffix(s, "\n") {
+		b.WriteString("-")
+	} else if strings.HasSuffix(s, "\n\n") {
+		b.WriteString("+")
+	}
+	b.WriteString("\n")
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		if line != "" {
+			b.WriteString(indent)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
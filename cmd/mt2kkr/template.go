@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/dchest/mt2kkr/output"
+)
+
+var (
+	templateFlag    = flag.String("template", "", "render output files with Go text/template from `file`")
+	templateDirFlag = flag.String("template-dir", "", "read post, comments, comment and index templates from `dir`")
+	presetFlag      = flag.String("preset", "", "render output files with built-in templates for `generator`: kkr, jekyll, hugo, eleventy or html (standalone documents)")
+	cssFlag         = flag.String("css", "", "stylesheet `url` for templates, such as -preset html")
+	mf2Flag         = flag.Bool("mf2", false, "add microformats2 classes (h-entry, h-card, etc.) to HTML output")
+)
+
+var authorEmailFlag listFlag
+
+func init() {
+	flag.Var(&authorEmailFlag, "author-email", "treat comments with `email` as written by the post author (can repeat)")
+}
+
+// templates is the output template set with "post", "comments",
+// "comment" and optionally "index" templates, or nil for the default
+// output.
+var templates *template.Template
+
+// outputOpts are output options from flags.
+var outputOpts output.Options
+
+// setupTemplate sets output options and builds the template set from
+// the -preset built-ins (kkr by default), overridden by templates from
+// -template-dir, and then by -template for posts. Templates in dir are
+// named after files without extension, e.g. post.tmpl defines "post".
+func setupTemplate() error {
+	outputOpts = output.Options{
+		Preset:      *presetFlag,
+		TemplateDir: *templateDirFlag,
+		Template:    *templateFlag,
+		CSS:         *cssFlag,
+		MF2:         *mf2Flag,
+		Markers:     *markersFlag,
+
+		AuthorEmails: authorEmailFlag,
+
+		Merge:     *mergeFlag,
+		SourceMap: *sourceMapFlag,
+
+		Ext:     *extFlag,
+		MaxSlug: *maxSlugFlag,
+		MaxPath: *maxPathFlag,
+	}
+	var err error
+	templates, err = outputOpts.Templates()
+	return err
+}
+
+// indexData is passed to the "index" template.
+type indexData struct {
+	Entries []*output.Post
+	Meta    *archiveMeta
+	CSS     string
+}
+
+// writeIndex renders "index" template, if any, with the list of
+// converted entries into index file in dir.
+func writeIndex(dir string) error {
+	if templates == nil || templates.Lookup("index") == nil {
+		return nil
+	}
+	buf := new(bytes.Buffer)
+	data := &indexData{files.Posts, computeArchiveMeta(), outputOpts.CSS}
+	if err := templates.ExecuteTemplate(buf, "index", data); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "index"+*extFlag), buf.Bytes(), 0644)
+}
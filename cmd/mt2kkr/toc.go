@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	tocFlag         = flag.String("toc", "", "for long posts, add heading IDs and table of contents as `mode`: block or front")
+	tocLengthFlag   = flag.Int("toc-length", 4000, "minimum body length in `bytes` for -toc")
+	tocHeadingsFlag = flag.Int("toc-headings", 3, "minimum `number` of headings for -toc")
+)
+
+var (
+	headingRe = regexp.MustCompile(`(?is)<h([1-6])([^>]*)>(.*?)</h[1-6]\s*>`)
+	idAttrRe  = regexp.MustCompile(`(?i)\sid\s*=\s*["']?([^"'\s>]+)`)
+)
+
+type heading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// stripTags returns text with HTML tags removed and entities unescaped.
+func stripTags(s string) string {
+	return html.UnescapeString(anyTagRe.ReplaceAllString(s, ""))
+}
+
+// addHeadingIDs adds id attributes to headings in body that don't have
+// them, returning the new body and the list of headings.
+func addHeadingIDs(body []byte) ([]byte, []heading) {
+	var headings []heading
+	used := make(map[string]bool)
+	body = headingRe.ReplaceAllFunc(body, func(h []byte) []byte {
+		sm := headingRe.FindSubmatch(h)
+		level, _ := strconv.Atoi(string(sm[1]))
+		text := strings.TrimSpace(stripTags(string(sm[3])))
+		if m := idAttrRe.FindSubmatch(sm[2]); m != nil {
+			headings = append(headings, heading{level, string(m[1]), text})
+			used[string(m[1])] = true
+			return h
+		}
+		id, _ := slugify(text)
+		if id == "" {
+			id = "section"
+		}
+		base := id
+		for i := 2; used[id]; i++ {
+			id = fmt.Sprintf("%s-%d", base, i)
+		}
+		used[id] = true
+		headings = append(headings, heading{level, id, text})
+		return []byte(fmt.Sprintf(`<h%d id="%s"%s>%s</h%d>`, level, id, sm[2], sm[3], level))
+	})
+	return body, headings
+}
+
+// tocBlock returns the table of contents as a nested list.
+func tocBlock(headings []heading) string {
+	var buf bytes.Buffer
+	buf.WriteString("<nav class=\"toc\">\n")
+	depth := 0
+	base := headings[0].Level
+	for _, h := range headings {
+		if h.Level < base {
+			base = h.Level
+		}
+	}
+	for _, h := range headings {
+		level := h.Level - base + 1
+		for depth < level {
+			buf.WriteString("<ul>\n")
+			depth++
+		}
+		for depth > level {
+			buf.WriteString("</ul>\n")
+			depth--
+		}
+		fmt.Fprintf(&buf, "<li><a href=\"#%s\">%s</a></li>\n", h.ID, html.EscapeString(h.Text))
+	}
+	for ; depth > 0; depth-- {
+		buf.WriteString("</ul>\n")
+	}
+	buf.WriteString("</nav>\n")
+	return buf.String()
+}
+
+// addTOC adds heading IDs and table of contents to long posts.
+func (e *entry) addTOC(body []byte) []byte {
+	if *tocFlag == "" || len(body) < *tocLengthFlag {
+		return body
+	}
+	withIDs, headings := addHeadingIDs(body)
+	if len(headings) < *tocHeadingsFlag {
+		return body
+	}
+	switch *tocFlag {
+	case "front":
+		e.Header["toc"] = "true"
+		return withIDs
+	default:
+		return append([]byte(tocBlock(headings)), withIDs...)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+var (
+	renameSlugFlag listFlag
+	dropFieldFlag  listFlag
+	dateOffsetFlag = flag.Duration("date-offset", 0, "add `duration` to entry and comment dates, e.g. -5h to fix server time zone")
+)
+
+func init() {
+	flag.Var(&renameSlugFlag, "rename-slug", "change entry basename `old=new` before conversion (can repeat)")
+	flag.Var(&dropFieldFlag, "drop-field", "clear entry `field` before conversion: author, status, category, primary_category, tags, keywords, excerpt or comments (can repeat)")
+}
+
+// transforms are parser transforms from flags.
+var transforms []mt.Transform
+
+func setupTransforms() (err error) {
+	transforms, err = flagTransforms()
+	return err
+}
+
+func flagTransforms() ([]mt.Transform, error) {
+	var ts []mt.Transform
+	if len(renameSlugFlag) > 0 {
+		slugs := make(map[string]string)
+		for _, s := range renameSlugFlag {
+			old, new, ok := strings.Cut(s, "=")
+			if !ok {
+				return nil, fmt.Errorf("-rename-slug %q: expecting old=new", s)
+			}
+			slugs[old] = new
+		}
+		ts = append(ts, func(e *mt.Entry) (*mt.Entry, error) {
+			if s, ok := slugs[e.Slug]; ok {
+				e.Slug = s
+			}
+			return e, nil
+		})
+	}
+	for _, field := range dropFieldFlag {
+		drop, ok := fieldDroppers[field]
+		if !ok {
+			return nil, fmt.Errorf("-drop-field: unknown field %q", field)
+		}
+		ts = append(ts, func(e *mt.Entry) (*mt.Entry, error) {
+			drop(e)
+			return e, nil
+		})
+	}
+	if d := *dateOffsetFlag; d != 0 {
+		ts = append(ts, func(e *mt.Entry) (*mt.Entry, error) {
+			e.Date = e.Date.Add(d)
+			for _, c := range e.Comments {
+				c.Date = c.Date.Add(d)
+			}
+			return e, nil
+		})
+	}
+	for _, command := range filterFlag {
+		ts = append(ts, filterTransform(command))
+	}
+	for _, filename := range scriptFlag {
+		t, err := scriptTransform(filename)
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+// fieldDroppers clear entry fields for -drop-field.
+var fieldDroppers = map[string]func(e *mt.Entry){
+	"author":           func(e *mt.Entry) { e.Author = "" },
+	"status":           func(e *mt.Entry) { e.Status = "" },
+	"category":         func(e *mt.Entry) { e.Categories = nil },
+	"primary_category": func(e *mt.Entry) { e.PrimaryCategory = "" },
+	"tags":             func(e *mt.Entry) { e.Tags = nil },
+	"keywords":         func(e *mt.Entry) { e.Keywords = nil },
+	"excerpt":          func(e *mt.Entry) { e.Excerpt = "" },
+	"comments":         func(e *mt.Entry) { e.Comments = nil },
+}
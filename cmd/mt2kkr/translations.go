@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dchest/mt2kkr/output"
+)
+
+var translationMatchFlag = flag.String("translation-match", "date", "how to pair translations of -translation-lang: date (posted the same day) or basename (same basename without -lang or _lang suffix)")
+
+var translationLangFlag listFlag
+
+func init() {
+	flag.Var(&translationLangFlag, "translation-lang", "`lang=category`: entries in category are in language lang; pair them with translations, adding translationKey and translations: to front matter (can repeat)")
+}
+
+type translationLang struct{ lang, category string }
+
+var translationLangs []translationLang
+
+// translation is a written entry in one of -translation-lang languages.
+type translation struct {
+	lang, key string
+	filename  string
+	url       string
+}
+
+var (
+	translationsMu sync.Mutex
+	translations   []translation
+)
+
+func setupTranslations() error {
+	translationLangs = nil
+	for _, s := range translationLangFlag {
+		lang, category, ok := strings.Cut(s, "=")
+		if !ok || lang == "" || category == "" {
+			return fmt.Errorf("bad -translation-lang %q: want lang=category", s)
+		}
+		translationLangs = append(translationLangs, translationLang{lang, category})
+	}
+	switch *translationMatchFlag {
+	case "date", "basename":
+		return nil
+	}
+	return fmt.Errorf("unknown -translation-match %q (available: date, basename)", *translationMatchFlag)
+}
+
+// addTranslationKey adds language and translation key to header of
+// entry in one of -translation-lang categories. Translations have the
+// same key, so they're paired by Hugo even without translations:.
+func (e *entry) addTranslationKey() {
+	for _, tl := range translationLangs {
+		if !e.inCategory([]string{tl.category}) {
+			continue
+		}
+		key := e.Date.Format("2006-01-02")
+		if *translationMatchFlag == "basename" {
+			key = e.Slug
+			if key == "" {
+				key = e.name
+			}
+			for _, sep := range []string{"-", "_"} {
+				key = strings.TrimSuffix(key, sep+tl.lang)
+			}
+		}
+		e.Header["lang"] = strconv.Quote(tl.lang)
+		e.Header["translationKey"] = strconv.Quote(key)
+		return
+	}
+}
+
+// addTranslation remembers written entry for pairing.
+func (e *entry) addTranslation(filename string) {
+	lang, _ := strconv.Unquote(e.Header["lang"])
+	key, _ := strconv.Unquote(e.Header["translationKey"])
+	if key == "" {
+		return
+	}
+	translationsMu.Lock()
+	translations = append(translations, translation{lang, key, filename, e.newURL()})
+	translationsMu.Unlock()
+}
+
+// writeTranslations adds translations: with URLs of other languages to
+// front matter of paired entries, and reports unpaired ones.
+func writeTranslations(dir string) error {
+	byKey := make(map[string][]translation)
+	for _, t := range translations {
+		byKey[t.key] = append(byKey[t.key], t)
+	}
+	keys := make([]string, 0, len(byKey))
+	for k := range byKey {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+outer:
+	for _, k := range keys {
+		group := byKey[k]
+		langs := make(map[string]bool)
+		for _, t := range group {
+			if langs[t.lang] {
+				for _, t := range group {
+					rep.addf(t.filename, "translation", "unpaired: %d entries share translation key %q", len(group), k)
+				}
+				continue outer
+			}
+			langs[t.lang] = true
+		}
+		if len(group) == 1 {
+			rep.addf(group[0].filename, "translation", "unpaired: no translation with key %q", k)
+			continue
+		}
+		for _, t := range group {
+			others := make(map[string]string)
+			for _, o := range group {
+				if o.lang != t.lang {
+					others[o.lang] = o.url
+				}
+			}
+			// A JSON object is a YAML flow mapping.
+			b, err := json.Marshal(others)
+			if err != nil {
+				return err
+			}
+			if err := addFrontMatterLine(dir, t.filename, "translations: "+string(b)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addFrontMatterLine inserts line into front matter of output file, in
+// the order of keys of WriteFrontMatter, updating its source map.
+func addFrontMatterLine(dir, filename, line string) error {
+	file := filepath.Join(dir, filename)
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(b, []byte("---\n")) {
+		rep.addf(filename, "translation", "no front matter for %s", line)
+		return nil
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	i := 1
+	for i < len(lines) && lines[i] != "---\n" && lines[i] < line {
+		i++
+	}
+	lines = append(lines[:i], append([]string{line + "\n"}, lines[i:]...)...)
+	if err := os.WriteFile(file, []byte(strings.Join(lines, "")), 0644); err != nil {
+		return err
+	}
+	if !*sourceMapFlag {
+		return nil
+	}
+	var smap output.SourceMap
+	mb, err := os.ReadFile(file + ".map.json")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(mb, &smap); err != nil {
+		return err
+	}
+	smap.Shift(1)
+	return smap.Write(dir)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+var normalizeUnicodeFlag = flag.Bool("normalize-unicode", false, "compose decomposed accented letters (as NFC does) and remove zero-width and bidi control characters, reporting them")
+
+// invisibleChars are removed by -normalize-unicode. Zero-width joiners
+// are kept, as they are needed in emoji and some scripts.
+var invisibleChars = map[rune]bool{
+	'\u200b': true,                 // zero width space
+	'\u2060': true,                 // word joiner
+	'\ufeff': true,                 // byte order mark
+	'\u061c': true,                 // arabic letter mark
+	'\u200e': true, '\u200f': true, // left-to-right and right-to-left marks
+	'\u202a': true, '\u202b': true, '\u202c': true, '\u202d': true, '\u202e': true, // embeddings and overrides
+	'\u2066': true, '\u2067': true, '\u2068': true, '\u2069': true, // isolates
+}
+
+func needsNormalization(r rune) bool {
+	return r >= 0x300 && (invisibleChars[r] || unicode.In(r, unicode.Mn, unicode.Mc))
+}
+
+// normalizeText returns s with invisible characters removed and
+// combining marks composed with preceding letters, and the number of
+// removed characters. Unlike full NFC, it doesn't reorder marks.
+func normalizeText(s string) (string, int) {
+	if strings.IndexFunc(s, needsNormalization) < 0 {
+		return s, 0
+	}
+	b := make([]byte, 0, len(s))
+	removed := 0
+	last := utf8.RuneError // last written rune
+	for _, r := range s {
+		if invisibleChars[r] {
+			removed++
+			continue
+		}
+		if c, ok := compositions[[2]rune{last, r}]; ok {
+			b = b[:len(b)-utf8.RuneLen(last)]
+			r = c
+		}
+		b = utf8.AppendRune(b, r)
+		last = r
+	}
+	return string(b), removed
+}
+
+// normalizeEntry normalizes text fields of entry and its comments,
+// returning the number of removed characters.
+func normalizeEntry(e *mt.Entry) int {
+	removed := 0
+	norm := func(s *string) {
+		var n int
+		*s, n = normalizeText(*s)
+		removed += n
+	}
+	for _, s := range []*string{&e.Title, &e.Author, &e.Slug, &e.PrimaryCategory, &e.Body, &e.Extended, &e.Excerpt} {
+		norm(s)
+	}
+	for _, list := range [][]string{e.Categories, e.Tags, e.Keywords} {
+		for i := range list {
+			norm(&list[i])
+		}
+	}
+	if *repairCommentsFlag {
+		// Comments are normalized one by one.
+		return removed
+	}
+	for _, c := range e.Comments {
+		norm(&c.Author)
+		norm(&c.Content)
+	}
+	return removed
+}
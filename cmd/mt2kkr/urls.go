@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+var (
+	siteURLFlag    = flag.String("site-url", "", "base `url` of the new site, for feeds and digests")
+	urlPatternFlag = flag.String("url-pattern", "{file}", "`pattern` of post URLs on the new site, with {file}, {name}, {slug}, {year}, {month} and {day}")
+)
+
+// newURL returns the URL of converted entry on the new site.
+func (e *entry) newURL() string {
+	r := strings.NewReplacer(
+		"{file}", e.filename,
+		"{name}", strings.TrimSuffix(e.filename, e.route.Ext),
+		"{slug}", e.slug,
+		"{year}", e.Date.Format("2006"),
+		"{month}", e.Date.Format("01"),
+		"{day}", e.Date.Format("02"),
+	)
+	return siteURL(r.Replace(*urlPatternFlag))
+}
+
+// siteURL returns absolute URL of path on the new site.
+func siteURL(path string) string {
+	if *siteURLFlag == "" {
+		return path
+	}
+	return strings.TrimSuffix(*siteURLFlag, "/") + "/" + strings.TrimPrefix(path, "/")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	uuidFlag          = flag.Bool("uuid", false, "add uuid: field with UUID version 5 derived from the original entry URL, and write manifest.json of entries by UUID")
+	uuidNamespaceFlag = flag.String("uuid-namespace", "6ba7b811-9dad-11d1-80b4-00c04fd430c8", "`namespace` for -uuid: a UUID, or any string to derive one from")
+)
+
+type uuid [16]byte
+
+// namespaceURL is the RFC 4122 name space for URLs.
+var namespaceURL = uuid{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+var uuidNamespace uuid
+
+func parseUUID(s string) (u uuid, err error) {
+	h := strings.Replace(s, "-", "", -1)
+	if len(h) != 32 {
+		return u, fmt.Errorf("bad UUID %q", s)
+	}
+	_, err = hex.Decode(u[:], []byte(h))
+	return u, err
+}
+
+// newUUID5 returns RFC 4122 version 5 UUID for name in namespace.
+func newUUID5(ns uuid, name string) uuid {
+	h := sha1.New()
+	h.Write(ns[:])
+	h.Write([]byte(name))
+	var u uuid
+	copy(u[:], h.Sum(nil))
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	return u
+}
+
+func (u uuid) String() string {
+	b := hex.EncodeToString(u[:])
+	return b[:8] + "-" + b[8:12] + "-" + b[12:16] + "-" + b[16:20] + "-" + b[20:]
+}
+
+func setupUUID() (err error) {
+	uuidNamespace, err = parseUUID(*uuidNamespaceFlag)
+	if err != nil {
+		// Not a UUID, derive one.
+		uuidNamespace, err = newUUID5(namespaceURL, *uuidNamespaceFlag), nil
+	}
+	return err
+}
+
+// entryUUID returns stable identifier of the entry with the given MT
+// basename, derived from its original URL.
+func (e *entry) entryUUID(basename string) string {
+	return newUUID5(uuidNamespace, e.canonicalURL(basename)).String()
+}
+
+// addUUID sets UUID of entry, which identifies it in feeds and the
+// manifest, adding it to front matter with -uuid.
+func (e *entry) addUUID(basename string) {
+	e.uuid = e.entryUUID(basename)
+	if *uuidFlag {
+		e.Header["uuid"] = strconv.Quote(e.uuid)
+	}
+}
+
+// manifestEntry is an entry of manifest.json.
+type manifestEntry struct {
+	File   string `json:"file"`
+	URL    string `json:"url"` // original URL
+	NewURL string `json:"new_url"`
+}
+
+var (
+	manifestMu sync.Mutex
+	manifest   = make(map[string]manifestEntry) // by UUID
+)
+
+// addManifestEntry records written entry for manifest.json.
+func (e *entry) addManifestEntry() {
+	if !*uuidFlag {
+		return
+	}
+	basename := e.Slug
+	if basename == "" {
+		basename = e.name
+	}
+	manifestMu.Lock()
+	manifest[e.uuid] = manifestEntry{e.filename, e.canonicalURL(basename), e.newURL()}
+	manifestMu.Unlock()
+}
+
+// writeManifest writes manifest.json, mapping UUIDs of entries to their
+// files and URLs, into dir.
+func writeManifest(dir string) error {
+	if !*uuidFlag {
+		return nil
+	}
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "manifest.json"), append(b, '\n'), 0644)
+}
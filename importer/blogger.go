@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+const (
+	bloggerKindScheme  = "http://schemas.google.com/g/2005#kind"
+	bloggerLabelScheme = "http://www.blogger.com/atom/ns#"
+)
+
+type atomCategory struct {
+	Scheme string `xml:"scheme,attr"`
+	Term   string `xml:"term,attr"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+	URI   string `xml:"uri"`
+}
+
+type bloggerEntry struct {
+	ID         string         `xml:"id"`
+	Published  string         `xml:"published"`
+	Title      string         `xml:"title"`
+	Content    string         `xml:"content"`
+	Author     atomPerson     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Links      []atomLink     `xml:"link"`
+	Draft      string         `xml:"control>draft"`
+	InReplyTo  struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"in-reply-to"`
+	// Newer exports have these instead of kind categories.
+	Type     string `xml:"type"`
+	Status   string `xml:"status"`
+	Filename string `xml:"filename"`
+	Parent   string `xml:"parent"`
+}
+
+// kind returns "post", "comment" or another kind of entry.
+func (be *bloggerEntry) kind() string {
+	for _, c := range be.Categories {
+		if c.Scheme == bloggerKindScheme {
+			return c.Term[strings.LastIndex(c.Term, "#")+1:]
+		}
+	}
+	return strings.ToLower(be.Type)
+}
+
+// slug returns file name of post URL without extension.
+func (be *bloggerEntry) slug() string {
+	name := be.Filename
+	for _, l := range be.Links {
+		if l.Rel == "alternate" {
+			name = l.Href
+		}
+	}
+	if name == "" {
+		return ""
+	}
+	return strings.TrimSuffix(path.Base(name), path.Ext(name))
+}
+
+// ReadBlogger reads posts of Blogger Atom export, with their labels as
+// categories and comments.
+func ReadBlogger(r io.Reader) (Reader, error) {
+	var feed struct {
+		Entries []bloggerEntry `xml:"entry"`
+	}
+	if err := xml.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("blogger: %s", err)
+	}
+	var entries []*mt.Entry
+	posts := make(map[string]*mt.Entry)
+	parse := func(be *bloggerEntry) (time.Time, error) {
+		t, err := time.Parse(time.RFC3339, be.Published)
+		if err != nil {
+			return t, fmt.Errorf("blogger: %s: %s", be.ID, err)
+		}
+		return t, nil
+	}
+	for i := range feed.Entries {
+		be := &feed.Entries[i]
+		if be.kind() != "post" {
+			continue
+		}
+		e := &mt.Entry{
+			Title:  be.Title,
+			Author: be.Author.Name,
+			Slug:   be.slug(),
+			Status: "Publish",
+			Body:   be.Content,
+		}
+		if be.Draft == "yes" || be.Status != "" && be.Status != "LIVE" {
+			e.Status = "Draft"
+		}
+		var err error
+		if e.Date, err = parse(be); err != nil {
+			return nil, err
+		}
+		for _, c := range be.Categories {
+			if c.Scheme == bloggerLabelScheme {
+				e.Categories = append(e.Categories, c.Term)
+			}
+		}
+		if len(e.Categories) > 0 {
+			e.PrimaryCategory = e.Categories[0]
+		}
+		entries = append(entries, e)
+		posts[be.ID] = e
+	}
+	for i := range feed.Entries {
+		be := &feed.Entries[i]
+		if be.kind() != "comment" {
+			continue
+		}
+		ref := be.InReplyTo.Ref
+		if ref == "" {
+			ref = be.Parent
+		}
+		e := posts[ref]
+		if e == nil {
+			continue
+		}
+		d, err := parse(be)
+		if err != nil {
+			return nil, err
+		}
+		e.Comments = append(e.Comments, &mt.Comment{
+			Author:  be.Author.Name,
+			Email:   be.Author.Email,
+			URL:     be.Author.URI,
+			Date:    d,
+			Content: be.Content,
+		})
+	}
+	return &sliceReader{entries}, nil
+}
@@ -0,0 +1,188 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// drupalTypes are node types imported as entries.
+var drupalTypes = map[string]bool{"article": true, "blog": true, "story": true}
+
+// drupalTables are names of tables in Drupal 6, 7 and 8+ databases.
+var drupalTables = []string{
+	// Drupal 6
+	"node", "node_revisions", "comments", "users", "url_alias", "term_node", "term_data",
+	// Drupal 7
+	"field_data_body", "comment", "field_data_comment_body", "taxonomy_index", "taxonomy_term_data",
+	// Drupal 8+
+	"node_field_data", "node__body", "comment_field_data", "comment__comment_body",
+	"users_field_data", "path_alias", "taxonomy_term_field_data",
+}
+
+// first returns the first present value of keys in row.
+func (r sqlRow) first(keys ...string) string {
+	for _, k := range keys {
+		if v, ok := r[k]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// unixTime parses Unix timestamp.
+func unixTime(s string) (time.Time, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad timestamp %q", s)
+	}
+	return time.Unix(n, 0).UTC(), nil
+}
+
+// wrapUnlessHTML wraps lines into paragraphs, as Drupal and similar
+// engines do on display, unless text already has paragraphs.
+func wrapUnlessHTML(text string) string {
+	if strings.Contains(text, "<p") || strings.Contains(text, "<P") {
+		return text
+	}
+	return mt.WrapBreaks(text)
+}
+
+// ReadDrupal reads blog posts, articles and stories with their terms
+// as tags and published comments from MySQL dump of Drupal 6, 7 or 8+
+// database without table prefix.
+func ReadDrupal(r io.Reader) (Reader, error) {
+	t, err := readSQLDump(r, drupalTables...)
+	if err != nil {
+		return nil, fmt.Errorf("drupal: %s", err)
+	}
+	users := make(map[string]string)
+	for _, u := range append(t["users"], t["users_field_data"]...) {
+		users[u["uid"]] = u["name"]
+	}
+	aliases := make(map[string]string)
+	for _, a := range t["url_alias"] {
+		aliases[strings.TrimPrefix(a.first("source", "src"), "/")] = a.first("alias", "dst")
+	}
+	for _, a := range t["path_alias"] {
+		aliases[strings.TrimPrefix(a["path"], "/")] = a["alias"]
+	}
+	terms := make(map[string]string)
+	for _, term := range append(append(t["term_data"], t["taxonomy_term_data"]...), t["taxonomy_term_field_data"]...) {
+		terms[term["tid"]] = term["name"]
+	}
+	nodeTerms := make(map[string][]string)
+	for _, ti := range append(t["term_node"], t["taxonomy_index"]...) {
+		if name, ok := terms[ti["tid"]]; ok && !contains(nodeTerms[ti["nid"]], name) {
+			nodeTerms[ti["nid"]] = append(nodeTerms[ti["nid"]], name)
+		}
+	}
+	// Bodies are in revisions (6), field tables (7) or body tables
+	// (8+), by revision ID.
+	bodies := make(map[string]string)
+	for _, b := range t["node_revisions"] {
+		bodies[b["vid"]] = b["body"]
+	}
+	for _, b := range append(t["field_data_body"], t["node__body"]...) {
+		if b["delta"] == "0" && b["deleted"] != "1" {
+			bodies[b["revision_id"]] = b["body_value"]
+		}
+	}
+	nodes := t["node_field_data"]
+	if nodes == nil {
+		nodes = t["node"]
+	}
+	var entries []*mt.Entry
+	byNID := make(map[string]*mt.Entry)
+	for _, n := range nodes {
+		if !drupalTypes[n["type"]] || byNID[n["nid"]] != nil {
+			continue // not a post or a translation
+		}
+		e := &mt.Entry{
+			Title:  n["title"],
+			Author: users[n["uid"]],
+			Status: "Draft",
+			Body:   bodies[n["vid"]],
+			Tags:   nodeTerms[n["nid"]],
+		}
+		if i := strings.Index(e.Body, "<!--break-->"); i >= 0 {
+			e.Body, e.Extended = e.Body[:i], e.Body[i+len("<!--break-->"):]
+		}
+		e.Body, e.Extended = wrapUnlessHTML(e.Body), wrapUnlessHTML(e.Extended)
+		if n["status"] == "1" {
+			e.Status = "Publish"
+		}
+		if alias, ok := aliases["node/"+n["nid"]]; ok {
+			e.Slug = path.Base(alias)
+		}
+		if e.Date, err = unixTime(n["created"]); err != nil {
+			return nil, fmt.Errorf("drupal: node %s: %s", n["nid"], err)
+		}
+		entries = append(entries, e)
+		byNID[n["nid"]] = e
+	}
+	// Comment bodies are in comment (6) or field tables (7, 8+).
+	commentBodies := make(map[string]string)
+	for _, b := range append(t["field_data_comment_body"], t["comment__comment_body"]...) {
+		commentBodies[b["entity_id"]] = b["comment_body_value"]
+	}
+	type drupalComment struct {
+		nid string
+		c   *mt.Comment
+	}
+	var comments []drupalComment
+	for _, table := range []string{"comments", "comment", "comment_field_data"} {
+		for _, c := range t[table] {
+			// Published comments have status 0 in Drupal 6.
+			published := "1"
+			if table == "comments" {
+				published = "0"
+			}
+			nid := c.first("nid", "entity_id")
+			if c["status"] != published || byNID[nid] == nil || table == "comment_field_data" && c["entity_type"] != "node" {
+				continue
+			}
+			d, err := unixTime(c.first("created", "timestamp"))
+			if err != nil {
+				return nil, fmt.Errorf("drupal: comment %s: %s", c["cid"], err)
+			}
+			body, ok := c["comment"]
+			if !ok {
+				body = commentBodies[c["cid"]]
+			}
+			author := c["name"]
+			if name, ok := users[c["uid"]]; ok && c["uid"] != "0" {
+				author = name
+			}
+			comments = append(comments, drupalComment{nid, &mt.Comment{
+				Author:  author,
+				Email:   c["mail"],
+				URL:     c["homepage"],
+				Date:    d,
+				Content: wrapUnlessHTML(body),
+			}})
+		}
+	}
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i].c.Date.Before(comments[j].c.Date) })
+	for _, c := range comments {
+		e := byNID[c.nid]
+		e.Comments = append(e.Comments, c.c)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+	return &sliceReader{entries}, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
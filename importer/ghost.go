@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+type ghostPost struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Slug        string `json:"slug"`
+	HTML        string `json:"html"`
+	Excerpt     string `json:"custom_excerpt"`
+	Type        string `json:"type"` // missing in old exports
+	Page        bool   `json:"page"` // old exports
+	Status      string `json:"status"`
+	AuthorID    string `json:"author_id"` // old exports
+	CreatedAt   string `json:"created_at"`
+	PublishedAt string `json:"published_at"`
+}
+
+type ghostRelation struct {
+	PostID    string `json:"post_id"`
+	TagID     string `json:"tag_id"`
+	AuthorID  string `json:"author_id"`
+	SortOrder int    `json:"sort_order"`
+}
+
+type ghostData struct {
+	Posts []ghostPost `json:"posts"`
+	Tags  []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"tags"`
+	Users []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"users"`
+	PostsTags    []ghostRelation `json:"posts_tags"`
+	PostsAuthors []ghostRelation `json:"posts_authors"`
+}
+
+// ReadGhost reads posts of Ghost JSON export with their tags and
+// authors. The first tag, which is primary in Ghost, becomes the primary
+// category. Internal tags (starting with #) and pages are skipped.
+func ReadGhost(r io.Reader) (Reader, error) {
+	var export struct {
+		DB []struct {
+			Data ghostData `json:"data"`
+		} `json:"db"`
+		Data ghostData `json:"data"` // exports without db
+	}
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("ghost: %s", err)
+	}
+	data := export.Data
+	if len(export.DB) > 0 {
+		data = export.DB[0].Data
+	}
+	tags := make(map[string]string)
+	for _, t := range data.Tags {
+		tags[t.ID] = t.Name
+	}
+	users := make(map[string]string)
+	for _, u := range data.Users {
+		users[u.ID] = u.Name
+	}
+	sortRelations(data.PostsTags)
+	sortRelations(data.PostsAuthors)
+	postTags := make(map[string][]string)
+	for _, pt := range data.PostsTags {
+		if name, ok := tags[pt.TagID]; ok && !strings.HasPrefix(name, "#") {
+			postTags[pt.PostID] = append(postTags[pt.PostID], name)
+		}
+	}
+	postAuthors := make(map[string]string)
+	for _, pa := range data.PostsAuthors {
+		if _, ok := postAuthors[pa.PostID]; !ok {
+			postAuthors[pa.PostID] = users[pa.AuthorID]
+		}
+	}
+	var entries []*mt.Entry
+	for _, p := range data.Posts {
+		if p.Page || p.Type != "" && p.Type != "post" {
+			continue
+		}
+		e := &mt.Entry{
+			Title:   p.Title,
+			Author:  postAuthors[p.ID],
+			Slug:    p.Slug,
+			Status:  "Draft",
+			Body:    p.HTML,
+			Excerpt: p.Excerpt,
+			Tags:    postTags[p.ID],
+		}
+		if e.Author == "" {
+			e.Author = users[p.AuthorID]
+		}
+		if p.Status == "published" {
+			e.Status = "Publish"
+		}
+		date := p.PublishedAt
+		if date == "" {
+			date = p.CreatedAt
+		}
+		var err error
+		if e.Date, err = time.Parse(time.RFC3339, date); err != nil {
+			return nil, fmt.Errorf("ghost: %q: %s", p.Title, err)
+		}
+		if len(e.Tags) > 0 {
+			e.PrimaryCategory = e.Tags[0]
+			e.Categories = []string{e.Tags[0]}
+		}
+		entries = append(entries, e)
+	}
+	return &sliceReader{entries}, nil
+}
+
+func sortRelations(rs []ghostRelation) {
+	sort.SliceStable(rs, func(i, j int) bool { return rs[i].SortOrder < rs[j].SortOrder })
+}
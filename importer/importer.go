@@ -0,0 +1,72 @@
+// Package importer reads exports of other blog engines into MT entries,
+// so that they can be converted the same way as MT exports.
+package importer
+
+import (
+	"context"
+	"io"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// Reader reads entries until io.EOF. *mt.Parser is a Reader.
+type Reader interface {
+	NextContext(ctx context.Context) (*mt.Entry, error)
+}
+
+// Formats are importers by name.
+var Formats = map[string]func(r io.Reader) (Reader, error){
+	"blogger":     ReadBlogger,
+	"drupal":      ReadDrupal,
+	"ghost":       ReadGhost,
+	"joomla":      ReadJoomla,
+	"livejournal": ReadLiveJournal,
+	"medium":      ReadMedium,
+	"posterous":   ReadPosterous,
+	"serendipity": ReadSerendipity,
+	"substack":    ReadSubstack,
+	"textpattern": ReadTextpattern,
+	"tumblr":      ReadTumblr,
+	"wordpress":   ReadWordPress,
+}
+
+// sliceReader returns entries from slice.
+type sliceReader struct{ entries []*mt.Entry }
+
+func (r *sliceReader) NextContext(ctx context.Context) (*mt.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(r.entries) == 0 {
+		return nil, io.EOF
+	}
+	e := r.entries[0]
+	r.entries = r.entries[1:]
+	return e, nil
+}
+
+// transformReader applies transforms to entries of Reader.
+type transformReader struct {
+	r  Reader
+	ts []mt.Transform
+}
+
+func (r *transformReader) NextContext(ctx context.Context) (*mt.Entry, error) {
+	for {
+		e, err := r.r.NextContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if e, err = mt.ApplyTransforms(r.ts, e); err != nil || e != nil {
+			return e, err
+		}
+	}
+}
+
+// WithTransforms returns Reader applying transforms to entries of r.
+func WithTransforms(r Reader, ts []mt.Transform) Reader {
+	if len(ts) == 0 {
+		return r
+	}
+	return &transformReader{r, ts}
+}
@@ -0,0 +1,209 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// joomlaTables are names of tables in Joomla 1.5+ databases with
+// JComments comments.
+var joomlaTables = []string{"*_content", "*_categories", "*_users", "*_tags", "*_contentitem_tag_map", "*_jcomments"}
+
+// joomlaArticle is an article in J2XML export or content table.
+type joomlaArticle struct {
+	ID          string   `xml:"id"`
+	Title       string   `xml:"title"`
+	Alias       string   `xml:"alias"`
+	IntroText   string   `xml:"introtext"`
+	FullText    string   `xml:"fulltext"`
+	State       string   `xml:"state"`
+	Category    string   `xml:"catid"`
+	Created     string   `xml:"created"`
+	Author      string   `xml:"created_by"`
+	AuthorAlias string   `xml:"created_by_alias"`
+	PublishUp   string   `xml:"publish_up"`
+	MetaDesc    string   `xml:"metadesc"`
+	Tags        []string `xml:"tag"`
+}
+
+// entry returns article as entry with category name, or nil for trashed
+// articles. Archived articles are published.
+func (a *joomlaArticle) entry(category string) (*mt.Entry, error) {
+	if a.State == "-2" {
+		return nil, nil
+	}
+	e := &mt.Entry{
+		Title:    a.Title,
+		Author:   a.Author,
+		Slug:     a.Alias,
+		Status:   "Draft",
+		Body:     wrapUnlessHTML(a.IntroText),
+		Extended: wrapUnlessHTML(a.FullText),
+		Excerpt:  a.MetaDesc,
+		Tags:     a.Tags,
+	}
+	if a.AuthorAlias != "" {
+		e.Author = a.AuthorAlias
+	}
+	if a.State == "1" || a.State == "2" {
+		e.Status = "Publish"
+	}
+	if category != "" {
+		e.PrimaryCategory = category
+		e.Categories = []string{category}
+	}
+	var err error
+	if e.Date, err = sqlDateTime(a.Created); err != nil {
+		return nil, fmt.Errorf("article %s: %s", a.ID, err)
+	}
+	// Articles scheduled later appear on publishing.
+	if up, err := sqlDateTime(a.PublishUp); err == nil && up.After(e.Date) {
+		e.Date = up
+	}
+	return e, nil
+}
+
+// ReadJoomla reads articles from J2XML export or from MySQL dump of
+// Joomla database with any table prefix, with tags and categories,
+// and with comments if JComments is installed.
+func ReadJoomla(r io.Reader) (Reader, error) {
+	br := bufio.NewReader(r)
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("joomla: %s", err)
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == 0xEF || c == 0xBB || c == 0xBF {
+			continue
+		}
+		br.UnreadByte()
+		var entries []*mt.Entry
+		if c == '<' {
+			entries, err = readJ2XML(br)
+		} else {
+			entries, err = readJoomlaDump(br)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("joomla: %s", err)
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+		return &sliceReader{entries}, nil
+	}
+}
+
+func readJ2XML(r io.Reader) ([]*mt.Entry, error) {
+	var export struct {
+		Articles   []joomlaArticle `xml:"content"`
+		Categories []struct {
+			Path  string `xml:"path"`
+			Title string `xml:"title"`
+		} `xml:"category"`
+	}
+	if err := xml.NewDecoder(r).Decode(&export); err != nil {
+		return nil, err
+	}
+	// Articles refer to categories by path.
+	categories := make(map[string]string)
+	for _, c := range export.Categories {
+		categories[c.Path] = c.Title
+	}
+	var entries []*mt.Entry
+	for i := range export.Articles {
+		a := &export.Articles[i]
+		category, ok := categories[a.Category]
+		if !ok && a.Category != "" && a.Category != "uncategorised" {
+			category = path.Base(a.Category)
+		}
+		for i, t := range a.Tags {
+			a.Tags[i] = path.Base(t)
+		}
+		e, err := a.entry(category)
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func readJoomlaDump(r io.Reader) ([]*mt.Entry, error) {
+	t, err := readSQLDump(r, joomlaTables...)
+	if err != nil {
+		return nil, err
+	}
+	users := make(map[string]string)
+	for _, u := range t["users"] {
+		users[u["id"]] = u["name"]
+	}
+	categories := make(map[string]string)
+	for _, c := range t["categories"] {
+		// Joomla 1.6+ keeps categories of all components in one table.
+		if ext, ok := c["extension"]; (!ok || ext == "com_content") && c["alias"] != "uncategorised" {
+			categories[c["id"]] = c["title"]
+		}
+	}
+	tags := make(map[string]string)
+	for _, tag := range t["tags"] {
+		tags[tag["id"]] = tag["title"]
+	}
+	articleTags := make(map[string][]string)
+	for _, m := range t["contentitem_tag_map"] {
+		if name, ok := tags[m["tag_id"]]; ok && m["type_alias"] == "com_content.article" {
+			articleTags[m["content_item_id"]] = append(articleTags[m["content_item_id"]], name)
+		}
+	}
+	var entries []*mt.Entry
+	byID := make(map[string]*mt.Entry)
+	for _, c := range t["content"] {
+		a := &joomlaArticle{
+			ID:          c["id"],
+			Title:       c["title"],
+			Alias:       c["alias"],
+			IntroText:   c["introtext"],
+			FullText:    c["fulltext"],
+			State:       c["state"],
+			Created:     c["created"],
+			Author:      users[c["created_by"]],
+			AuthorAlias: c["created_by_alias"],
+			PublishUp:   c["publish_up"],
+			MetaDesc:    c["metadesc"],
+			Tags:        articleTags[c["id"]],
+		}
+		e, err := a.entry(categories[c["catid"]])
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			entries = append(entries, e)
+			byID[a.ID] = e
+		}
+	}
+	comments := t["jcomments"]
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i]["date"] < comments[j]["date"] })
+	for _, c := range comments {
+		e := byID[c["object_id"]]
+		if e == nil || c["object_group"] != "com_content" || c["published"] != "1" {
+			continue
+		}
+		d, err := sqlDateTime(c["date"])
+		if err != nil {
+			return nil, fmt.Errorf("comment %s: %s", c["id"], err)
+		}
+		e.Comments = append(e.Comments, &mt.Comment{
+			Author:  c["name"],
+			Email:   c["email"],
+			URL:     c["homepage"],
+			Date:    d,
+			Content: wrapUnlessHTML(c["comment"]),
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,155 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// ljEntry is an entry of LiveJournal export (<entry>) or of ljdump
+// (<event>).
+type ljEntry struct {
+	ItemID   string `xml:"itemid"`
+	Anum     string `xml:"anum"`
+	URL      string `xml:"url"`
+	Time     string `xml:"eventtime"`
+	Subject  string `xml:"subject"`
+	Event    string `xml:"event"`
+	Security string `xml:"security"`
+	Poster   string `xml:"poster"`
+	Tags     string `xml:"props>taglist"`
+	Pre      string `xml:"props>opt_preformatted"`
+}
+
+type ljComment struct {
+	ID       int    `xml:"id,attr"`
+	JItemID  string `xml:"jitemid,attr"`
+	PosterID string `xml:"posterid,attr"`
+	State    string `xml:"state,attr"`
+	Subject  string `xml:"subject"`
+	Body     string `xml:"body"`
+	Date     string `xml:"date"`
+}
+
+// ljDateLayout is the layout of entry times in LiveJournal exports.
+const ljDateLayout = "2006-01-02 15:04:05"
+
+// slug returns the number of entry in its URL, if known.
+func (le *ljEntry) slug() string {
+	if le.URL != "" {
+		return strings.TrimSuffix(path.Base(le.URL), ".html")
+	}
+	id, err1 := strconv.Atoi(le.ItemID)
+	anum, err2 := strconv.Atoi(le.Anum)
+	if err1 != nil || err2 != nil {
+		return ""
+	}
+	return strconv.Itoa(id*256 + anum)
+}
+
+// ReadLiveJournal reads posts of LiveJournal XML export with comments
+// from its comment export. As LiveJournal exports entries by month, r can
+// contain several export files one after another, followed by comment
+// files, e.g. from cat 20*.xml comments*.xml. Screened and deleted
+// comments are skipped, and threads are flattened.
+func ReadLiveJournal(r io.Reader) (Reader, error) {
+	var (
+		entries  []*mt.Entry
+		byItemID = make(map[string]*mt.Entry)
+		comments []ljComment
+		users    = make(map[string]string)
+	)
+	d := xml.NewDecoder(r)
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("livejournal: %s", err)
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "entry", "event":
+			var le ljEntry
+			if err := d.DecodeElement(&le, &se); err != nil {
+				return nil, fmt.Errorf("livejournal: %s", err)
+			}
+			e := &mt.Entry{
+				Title:  le.Subject,
+				Author: le.Poster,
+				Slug:   le.slug(),
+				Status: "Publish",
+				Body:   le.Event,
+			}
+			if le.Security != "" && le.Security != "public" {
+				e.Status = "Draft"
+			}
+			if e.Date, err = time.Parse(ljDateLayout, le.Time); err != nil {
+				// Older exports have no seconds.
+				if e.Date, err = time.Parse("2006-01-02 15:04", le.Time); err != nil {
+					return nil, fmt.Errorf("livejournal: entry %s: %s", le.ItemID, err)
+				}
+			}
+			if le.Pre == "" || le.Pre == "0" {
+				e.Body = mt.WrapBreaks(e.Body)
+				e.ConvertBreaks = true
+			}
+			for _, tag := range strings.Split(le.Tags, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					e.Tags = append(e.Tags, tag)
+				}
+			}
+			entries = append(entries, e)
+			byItemID[le.ItemID] = e
+		case "comment":
+			var c ljComment
+			if err := d.DecodeElement(&c, &se); err != nil {
+				return nil, fmt.Errorf("livejournal: %s", err)
+			}
+			comments = append(comments, c)
+		case "usermap":
+			var id, user string
+			for _, a := range se.Attr {
+				switch a.Name.Local {
+				case "id":
+					id = a.Value
+				case "user":
+					user = a.Value
+				}
+			}
+			users[id] = user
+		}
+	}
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	for _, c := range comments {
+		e := byItemID[c.JItemID]
+		if e == nil || c.State == "D" || c.State == "S" {
+			continue
+		}
+		d, err := time.Parse(time.RFC3339, c.Date)
+		if err != nil {
+			return nil, fmt.Errorf("livejournal: comment %d: %s", c.ID, err)
+		}
+		mc := &mt.Comment{Author: "Anonymous", Date: d, Content: mt.WrapBreaks(c.Body)}
+		if user, ok := users[c.PosterID]; ok {
+			mc.Author = user
+			mc.URL = "https://" + strings.Replace(user, "_", "-", -1) + ".livejournal.com/"
+		}
+		if c.Subject != "" {
+			mc.Content = "<p><strong>" + c.Subject + "</strong></p>\n" + mc.Content
+		}
+		e.Comments = append(e.Comments, mc)
+	}
+	return &sliceReader{entries}, nil
+}
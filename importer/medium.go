@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+var (
+	mediumTitleRe     = regexp.MustCompile(`(?s)<title>(.*?)</title>`)
+	mediumSubtitleRe  = regexp.MustCompile(`(?s)<section data-field="subtitle"[^>]*>(.*?)</section>`)
+	mediumBodyRe      = regexp.MustCompile(`(?s)<section data-field="body"[^>]*>(.*)</section>\s*<footer>`)
+	mediumTimeRe      = regexp.MustCompile(`<time class="dt-published" datetime="([^"]+)"`)
+	mediumAuthorRe    = regexp.MustCompile(`class="p-author h-card">(.*?)</a>`)
+	mediumCanonicalRe = regexp.MustCompile(`href="([^"]+)" class="p-canonical"`)
+	// mediumIDRe matches post ID at the end of file names and URLs.
+	mediumIDRe = regexp.MustCompile(`-[0-9a-f]{10,12}$`)
+
+	// Boilerplate of Medium post bodies.
+	mediumTitleGrafRe = regexp.MustCompile(`(?s)<h[1-4][^>]*graf--title[^>]*>.*?</h[1-4]>`)
+	mediumDividerRe   = regexp.MustCompile(`<div class="section-divider"><hr class="section-divider"></div>`)
+	mediumWrapperRe   = regexp.MustCompile(`</?(?:div|section)\b[^>]*>`)
+	mediumAttrRe      = regexp.MustCompile(`\s(?:name|id|class|data-[a-z-]+)="[^"]*"`)
+)
+
+// ReadMedium reads posts of Medium export zip, stripping Medium layout
+// markup from them. Published posts are in posts/YYYY-MM-DD_slug.html,
+// drafts in posts/draft_slug.html; there are no tags and comments.
+func ReadMedium(r io.Reader) (Reader, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, fmt.Errorf("medium: %s", err)
+	}
+	var entries []*mt.Entry
+	for _, f := range zr.File {
+		if path.Dir(f.Name) != "posts" || path.Ext(f.Name) != ".html" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("medium: %s", err)
+		}
+		page, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("medium: %s: %s", f.Name, err)
+		}
+		e, err := mediumEntry(f, string(page))
+		if err != nil {
+			return nil, fmt.Errorf("medium: %s: %s", f.Name, err)
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+	return &sliceReader{entries}, nil
+}
+
+func mediumEntry(f *zip.File, page string) (*mt.Entry, error) {
+	name := strings.TrimSuffix(path.Base(f.Name), ".html")
+	e := &mt.Entry{Status: "Publish"}
+	if strings.HasPrefix(name, "draft_") {
+		e.Status = "Draft"
+	}
+	if m := mediumTitleRe.FindStringSubmatch(page); m != nil {
+		e.Title = html.UnescapeString(strings.TrimSpace(m[1]))
+	}
+	if m := mediumSubtitleRe.FindStringSubmatch(page); m != nil {
+		e.Excerpt = html.UnescapeString(strings.TrimSpace(m[1]))
+	}
+	if m := mediumAuthorRe.FindStringSubmatch(page); m != nil {
+		e.Author = html.UnescapeString(m[1])
+	}
+	e.Date = f.Modified
+	if m := mediumTimeRe.FindStringSubmatch(page); m != nil {
+		d, err := time.Parse(time.RFC3339, m[1])
+		if err != nil {
+			return nil, err
+		}
+		e.Date = d
+	}
+	// Slug comes from the canonical URL, or from the file name, e.g.
+	// 2017-05-01_My-Post-1a2b3c4d5e6f.
+	slug := name[strings.Index(name, "_")+1:]
+	if m := mediumCanonicalRe.FindStringSubmatch(page); m != nil {
+		slug = path.Base(m[1])
+	}
+	e.Slug = strings.ToLower(mediumIDRe.ReplaceAllString(slug, ""))
+	m := mediumBodyRe.FindStringSubmatch(page)
+	if m == nil {
+		return nil, fmt.Errorf("no body")
+	}
+	body := mediumTitleGrafRe.ReplaceAllString(m[1], "")
+	body = mediumDividerRe.ReplaceAllString(body, "")
+	body = mediumWrapperRe.ReplaceAllString(body, "\n")
+	body = mediumAttrRe.ReplaceAllString(body, "")
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	e.Body = strings.Join(lines, "\n") + "\n"
+	return e, nil
+}
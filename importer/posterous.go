@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+var (
+	posterousBodyRe = regexp.MustCompile(`(?is)<body[^>]*>(.*)</body>`)
+	// posterousDateRe matches date prefix of post file names.
+	posterousDateRe = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}[-_]`)
+)
+
+// ReadPosterous reads Posterous backup zip. Metadata and comments of
+// posts are in its WordPress export (wordpress_export_1.xml); bodies
+// are taken from HTML files in posts/ with the post's slug as name,
+// falling back to the export's content.
+func ReadPosterous(r io.Reader) (Reader, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, fmt.Errorf("posterous: %s", err)
+	}
+	var entries []*mt.Entry
+	pages := make(map[string]string)
+	for _, f := range zr.File {
+		dir, name := path.Split(f.Name)
+		isExport := path.Ext(name) == ".xml" && strings.HasPrefix(name, "wordpress_export")
+		isPost := path.Ext(name) == ".html" && path.Base(dir) == "posts"
+		if !isExport && !isPost {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("posterous: %s", err)
+		}
+		if isExport {
+			wr, err := ReadWordPress(rc)
+			rc.Close()
+			if err != nil {
+				return nil, fmt.Errorf("posterous: %s: %s", f.Name, err)
+			}
+			entries = append(entries, wr.(*sliceReader).entries...)
+			continue
+		}
+		page, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("posterous: %s: %s", f.Name, err)
+		}
+		slug := posterousDateRe.ReplaceAllString(strings.TrimSuffix(name, ".html"), "")
+		pages[slug] = string(page)
+	}
+	if entries == nil {
+		return nil, fmt.Errorf("posterous: no wordpress_export XML file in backup")
+	}
+	for _, e := range entries {
+		page, ok := pages[e.Slug]
+		if !ok {
+			continue
+		}
+		if m := posterousBodyRe.FindStringSubmatch(page); m != nil {
+			page = m[1]
+		}
+		e.Body, e.Extended = wrapUnlessHTML(strings.TrimSpace(page)+"\n"), ""
+		e.ConvertBreaks = false
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+	return &sliceReader{entries}, nil
+}
@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// serendipityTables are names of tables in Serendipity database with
+// any prefix, by default "serendipity_".
+var serendipityTables = []string{"*_entries", "*_category", "*_entrycat", "*_comments", "*_permalinks", "*_entryproperties"}
+
+// serendipityIDRe matches entry ID prefix of permalinks.
+var serendipityIDRe = regexp.MustCompile(`^[0-9]+-`)
+
+// ReadSerendipity reads entries from MySQL dump of Serendipity (s9y)
+// database. Entries are in their categories and the parents of them;
+// approved comments are flattened with replies after their parents.
+func ReadSerendipity(r io.Reader) (Reader, error) {
+	t, err := readSQLDump(r, serendipityTables...)
+	if err != nil {
+		return nil, fmt.Errorf("serendipity: %s", err)
+	}
+	categories := make(map[string]sqlRow)
+	for _, c := range t["category"] {
+		categories[c["categoryid"]] = c
+	}
+	entryCategories := make(map[string][]string)
+	for _, ec := range t["entrycat"] {
+		entryCategories[ec["entryid"]] = append(entryCategories[ec["entryid"]], ec["categoryid"])
+	}
+	slugs := make(map[string]string)
+	for _, p := range t["permalinks"] {
+		if p["type"] == "entry" {
+			slugs[p["entry_id"]] = strings.ToLower(serendipityIDRe.ReplaceAllString(strings.TrimSuffix(path.Base(p["permalink"]), ".html"), ""))
+		}
+	}
+	// Line breaks are converted on display, unless disabled for entry.
+	noBreaks := make(map[string]bool)
+	for _, p := range t["entryproperties"] {
+		if p["property"] == "ep_no_nl2br" && p["value"] == "true" {
+			noBreaks[p["entryid"]] = true
+		}
+	}
+	var entries []*mt.Entry
+	byID := make(map[string]*mt.Entry)
+	for _, s := range t["entries"] {
+		e := &mt.Entry{
+			Title:    s["title"],
+			Author:   s["author"],
+			Slug:     slugs[s["id"]],
+			Status:   "Publish",
+			Body:     s["body"],
+			Extended: s["extended"],
+		}
+		if !noBreaks[s["id"]] {
+			e.Body, e.Extended = wrapUnlessHTML(e.Body), wrapUnlessHTML(e.Extended)
+		}
+		if s["isdraft"] == "true" {
+			e.Status = "Draft"
+		}
+		for _, id := range entryCategories[s["id"]] {
+			if c, ok := categories[id]; ok && e.PrimaryCategory == "" {
+				e.PrimaryCategory = c["category_name"]
+			}
+			// Walk up to the root, guarding against cycles.
+			for seen := 0; categories[id] != nil && seen < len(categories); seen++ {
+				c := categories[id]
+				if !contains(e.Categories, c["category_name"]) {
+					e.Categories = append(e.Categories, c["category_name"])
+				}
+				id = c["parentid"]
+			}
+		}
+		if e.Date, err = unixTime(s["timestamp"]); err != nil {
+			return nil, fmt.Errorf("serendipity: entry %s: %s", s["id"], err)
+		}
+		entries = append(entries, e)
+		byID[s["id"]] = e
+	}
+	var comments []sqlRow
+	approved := make(map[string]bool)
+	for _, c := range t["comments"] {
+		if c["status"] == "approved" && c["type"] == "NORMAL" && byID[c["entry_id"]] != nil {
+			comments = append(comments, c)
+			approved[c["id"]] = true
+		}
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		a, b := comments[i]["timestamp"], comments[j]["timestamp"]
+		return len(a) < len(b) || len(a) == len(b) && a < b
+	})
+	// Replies to hidden comments become top-level.
+	replies := make(map[string][]sqlRow)
+	for _, c := range comments {
+		parent := c["parent_id"]
+		if !approved[parent] || parent == c["id"] {
+			parent = "0"
+		}
+		replies[parent] = append(replies[parent], c)
+	}
+	var addThread func(parent string) error
+	addThread = func(parent string) error {
+		for _, c := range replies[parent] {
+			d, err := unixTime(c["timestamp"])
+			if err != nil {
+				return fmt.Errorf("comment %s: %s", c["id"], err)
+			}
+			e := byID[c["entry_id"]]
+			e.Comments = append(e.Comments, &mt.Comment{
+				Author:  c["author"],
+				Email:   c["email"],
+				URL:     c["url"],
+				Date:    d,
+				Content: wrapUnlessHTML(c["body"]),
+			})
+			if err := addThread(c["id"]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := addThread("0"); err != nil {
+		return nil, fmt.Errorf("serendipity: %s", err)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+	return &sliceReader{entries}, nil
+}
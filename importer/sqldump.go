@@ -0,0 +1,367 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// sqlRow is a row of a table, with NULL values as empty strings.
+type sqlRow map[string]string
+
+// sqlDateTime parses DATETIME value as UTC.
+func sqlDateTime(s string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02 15:04:05", strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad date %q", s)
+	}
+	return t, nil
+}
+
+// readSQLDump reads rows of tables from MySQL dump, as written by
+// mysqldump or phpMyAdmin. Columns are named in INSERT statements or
+// taken from CREATE TABLE statements. Only tables in wanted are kept;
+// other statements are skipped. Wanted names starting with "*_" match
+// tables with any prefix, e.g. "*_content" matches "jos_content", and
+// are returned without it.
+func readSQLDump(r io.Reader, wanted ...string) (map[string][]sqlRow, error) {
+	s := &sqlScanner{r: bufio.NewReader(r)}
+	keep := make(map[string]bool)
+	for _, t := range wanted {
+		keep[t] = true
+	}
+	columns := make(map[string][]string)
+	tables := make(map[string][]sqlRow)
+	for {
+		stmt, err := s.statement()
+		if err == io.EOF {
+			return tables, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		words := strings.Fields(stmt)
+		switch {
+		case len(words) > 2 && strings.EqualFold(words[0], "CREATE") && strings.EqualFold(words[1], "TABLE"):
+			name, cols := sqlCreateTable(stmt)
+			columns[name] = cols
+		case len(words) > 2 && strings.EqualFold(words[0], "INSERT"):
+			// Check the table before parsing values, so that
+			// values of other tables don't matter.
+			p := &sqlParser{s: stmt}
+			name := p.insertTable()
+			cols, rows, err := p.insert(name)
+			if !keep[name] && !keep[sqlWildcard(name)] {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s (statement %d)", err, s.n)
+			}
+			if cols == nil {
+				cols = columns[name]
+			}
+			if !keep[name] {
+				name = name[strings.IndexByte(name, '_')+1:]
+			}
+			for _, values := range rows {
+				if len(values) != len(cols) {
+					return nil, fmt.Errorf("table %s: %d values for %d columns (statement %d)", name, len(values), len(cols), s.n)
+				}
+				row := make(sqlRow, len(cols))
+				for i, c := range cols {
+					row[c] = values[i]
+				}
+				tables[name] = append(tables[name], row)
+			}
+		}
+	}
+}
+
+// sqlScanner splits SQL dump into statements, skipping comments.
+type sqlScanner struct {
+	r *bufio.Reader
+	n int // number of statements read
+}
+
+func (s *sqlScanner) statement() (string, error) {
+	var b strings.Builder
+	var quote byte
+	for {
+		c, err := s.r.ReadByte()
+		if err == io.EOF && quote == 0 {
+			if strings.TrimSpace(b.String()) == "" {
+				return "", io.EOF
+			}
+			s.n++
+			return b.String(), nil
+		}
+		if err == io.EOF {
+			return "", fmt.Errorf("unterminated string (statement %d)", s.n+1)
+		}
+		if err != nil {
+			return "", err
+		}
+		if quote != 0 {
+			b.WriteByte(c)
+			if c == '\\' {
+				if c, err = s.r.ReadByte(); err != nil {
+					return "", err
+				}
+				b.WriteByte(c)
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+		case ';':
+			s.n++
+			return b.String(), nil
+		case '-', '/', '#':
+			next, _ := s.r.Peek(1)
+			switch {
+			case c == '#' || c == '-' && len(next) == 1 && next[0] == '-':
+				if _, err := s.r.ReadString('\n'); err != nil && err != io.EOF {
+					return "", err
+				}
+				b.WriteByte('\n')
+				continue
+			case c == '/' && len(next) == 1 && next[0] == '*':
+				s.r.ReadByte()
+				if err := s.skipComment(); err != nil {
+					return "", err
+				}
+				b.WriteByte(' ')
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+}
+
+func (s *sqlScanner) skipComment() error {
+	star := false
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if star && c == '/' {
+			return nil
+		}
+		star = c == '*'
+	}
+}
+
+// sqlName returns identifier without quotes.
+func sqlName(s string) string {
+	return strings.Trim(s, "`\"")
+}
+
+// sqlCreateTable returns name and column names of table from CREATE
+// TABLE statement.
+func sqlCreateTable(stmt string) (name string, cols []string) {
+	open := strings.IndexByte(stmt, '(')
+	if open < 0 {
+		return "", nil
+	}
+	f := strings.Fields(stmt[:open])
+	name = sqlName(f[len(f)-1])
+	for _, line := range strings.Split(stmt[open+1:], "\n") {
+		line = strings.TrimSpace(line)
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			continue
+		}
+		switch strings.ToUpper(f[0]) {
+		case "PRIMARY", "KEY", "UNIQUE", "INDEX", "CONSTRAINT", "FULLTEXT", "FOREIGN", "SPATIAL", "CHECK", ")":
+			continue
+		}
+		cols = append(cols, sqlName(f[0]))
+	}
+	return name, cols
+}
+
+// sqlWildcard returns wanted name matching table with prefix, e.g.
+// "*_content" for "jos_content", or "" if there's no prefix.
+func sqlWildcard(name string) string {
+	if i := strings.IndexByte(name, '_'); i > 0 {
+		return "*" + name[i:]
+	}
+	return ""
+}
+
+// insertTable parses the start of INSERT statement up to the table
+// name, which it returns.
+func (p *sqlParser) insertTable() string {
+	p.word() // INSERT
+	w := p.word()
+	for strings.EqualFold(w, "IGNORE") || strings.EqualFold(w, "LOW_PRIORITY") || strings.EqualFold(w, "DELAYED") {
+		w = p.word()
+	}
+	if strings.EqualFold(w, "INTO") {
+		w = p.word()
+	}
+	return sqlName(w)
+}
+
+// insert parses the rest of INSERT statement into table name, returning
+// column names, if given, and rows of values.
+func (p *sqlParser) insert(name string) (cols []string, rows [][]string, err error) {
+	p.space()
+	if p.peek() == '(' {
+		p.i++
+		for {
+			p.space()
+			cols = append(cols, sqlName(p.word()))
+			p.space()
+			if c := p.next(); c == ')' {
+				break
+			} else if c != ',' {
+				return nil, nil, fmt.Errorf("table %s: bad column list", name)
+			}
+		}
+	}
+	if w := p.word(); !strings.EqualFold(w, "VALUES") && !strings.EqualFold(w, "VALUE") {
+		return nil, nil, fmt.Errorf("table %s: expected VALUES, got %q", name, w)
+	}
+	for {
+		p.space()
+		if p.next() != '(' {
+			return nil, nil, fmt.Errorf("table %s: expected (", name)
+		}
+		var values []string
+		for {
+			p.space()
+			v, err := p.value()
+			if err != nil {
+				return nil, nil, fmt.Errorf("table %s: %s", name, err)
+			}
+			values = append(values, v)
+			p.space()
+			if c := p.next(); c == ')' {
+				break
+			} else if c != ',' {
+				return nil, nil, fmt.Errorf("table %s: expected , or ) in values", name)
+			}
+		}
+		rows = append(rows, values)
+		p.space()
+		if p.peek() != ',' {
+			return cols, rows, nil
+		}
+		p.i++
+	}
+}
+
+type sqlParser struct {
+	s string
+	i int
+}
+
+func (p *sqlParser) peek() byte {
+	if p.i < len(p.s) {
+		return p.s[p.i]
+	}
+	return 0
+}
+
+func (p *sqlParser) next() byte {
+	c := p.peek()
+	p.i++
+	return c
+}
+
+func (p *sqlParser) space() {
+	for p.i < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+// word returns the next identifier or keyword.
+func (p *sqlParser) word() string {
+	p.space()
+	start := p.i
+	if c := p.peek(); c == '`' || c == '"' {
+		p.i++
+		for p.i < len(p.s) && p.s[p.i] != c {
+			p.i++
+		}
+		p.i++
+		return p.s[start:min(p.i, len(p.s))]
+	}
+	for p.i < len(p.s) && strings.IndexByte(" \t\r\n(),", p.s[p.i]) < 0 {
+		p.i++
+	}
+	return p.s[start:p.i]
+}
+
+// sqlEscapes are characters of backslash escapes in MySQL strings.
+var sqlEscapes = map[byte]string{'0': "\x00", 'n': "\n", 'r': "\r", 't': "\t", 'Z': "\x1a", 'b': "\b"}
+
+// value returns string, number or NULL (as empty string). Strings may
+// have a character set introducer, e.g. _binary 'abc'; hexadecimal
+// literals, 0x00FF or X'00FF', are returned decoded.
+func (p *sqlParser) value() (string, error) {
+	if p.peek() == '_' {
+		p.word()
+		p.space()
+	}
+	if c := p.peek(); (c == 'x' || c == 'X') && p.i+1 < len(p.s) && p.s[p.i+1] == '\'' {
+		p.i++
+		v, err := p.value()
+		if err != nil {
+			return "", err
+		}
+		return sqlHex(v)
+	}
+	if p.peek() != '\'' {
+		start := p.i
+		for p.i < len(p.s) && strings.IndexByte(",) \t\r\n", p.s[p.i]) < 0 {
+			p.i++
+		}
+		v := p.s[start:p.i]
+		if strings.EqualFold(v, "NULL") {
+			return "", nil
+		}
+		if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+			return sqlHex(v[2:])
+		}
+		return v, nil
+	}
+	p.i++
+	var b strings.Builder
+	for p.i < len(p.s) {
+		c := p.next()
+		switch {
+		case c == '\\' && p.i < len(p.s):
+			e := p.next()
+			if s, ok := sqlEscapes[e]; ok {
+				b.WriteString(s)
+			} else {
+				b.WriteByte(e)
+			}
+		case c == '\'' && p.peek() == '\'':
+			p.i++
+			b.WriteByte('\'')
+		case c == '\'':
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+func sqlHex(s string) (string, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("bad hexadecimal literal %q", s)
+	}
+	return string(b), nil
+}
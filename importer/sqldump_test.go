@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadSQLDump(t *testing.T) {
+	dump := `-- MySQL dump 10.13
+/*!40101 SET NAMES utf8mb4 */;
+CREATE TABLE ` + "`jos_content`" + ` (
+  ` + "`id`" + ` int NOT NULL,
+  ` + "`title`" + ` varchar(255) NOT NULL,
+  ` + "`body`" + ` blob,
+  PRIMARY KEY (` + "`id`" + `)
+);
+INSERT INTO ` + "`jos_content`" + ` VALUES (1,'It''s \'here\'\n',_binary 'a,b'),(2,_utf8mb4 'Two',0x48690A),(3,X'4F4B',NULL);
+INSERT INTO cache (cid, data, hash, expire) VALUES ('k',_binary 'abc',0x00FF,1);
+INSERT INTO sessions VALUES (1,@broken 'x' y);
+`
+	tables, err := readSQLDump(strings.NewReader(dump), "*_content", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sqlRow{
+		{"id": "1", "title": "It's 'here'\n", "body": "a,b"},
+		{"id": "2", "title": "Two", "body": "Hi\n"},
+		{"id": "3", "title": "OK", "body": ""},
+	}
+	if !reflect.DeepEqual(tables["content"], want) {
+		t.Errorf("content: got %q, want %q", tables["content"], want)
+	}
+	wantCache := []sqlRow{{"cid": "k", "data": "abc", "hash": "\x00\xff", "expire": "1"}}
+	if !reflect.DeepEqual(tables["cache"], wantCache) {
+		t.Errorf("cache: got %q, want %q", tables["cache"], wantCache)
+	}
+	if _, ok := tables["sessions"]; ok {
+		t.Errorf("sessions table kept")
+	}
+}
+
+func TestReadSQLDumpErrors(t *testing.T) {
+	for _, dump := range []string{
+		"INSERT INTO t VALUES ('unterminated);",
+		"INSERT INTO t VALUES (0xZZ);",
+		"INSERT INTO t (a, b VALUES (1, 2);",
+		"INSERT INTO t VALUES (1 2);",
+	} {
+		if _, err := readSQLDump(strings.NewReader(dump), "t"); err == nil {
+			t.Errorf("%s: expected error", dump)
+		}
+	}
+}
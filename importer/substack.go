@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// ReadSubstack reads posts of Substack export zip: posts.csv with
+// metadata and posts/ID.slug.html with bodies. Subtitles become
+// excerpts.
+func ReadSubstack(r io.Reader) (Reader, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, fmt.Errorf("substack: %s", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	read := func(name string) ([]byte, error) {
+		f := files[name]
+		if f == nil {
+			return nil, fmt.Errorf("substack: no %s in export", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("substack: %s", err)
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	data, err := read("posts.csv")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("substack: posts.csv: %s", err)
+	}
+	if len(rows) == 0 {
+		return &sliceReader{}, nil
+	}
+	col := make(map[string]int)
+	for i, name := range rows[0] {
+		col[name] = i
+	}
+	for _, name := range []string{"post_id", "post_date", "is_published", "title", "subtitle"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("substack: posts.csv: no %s column", name)
+		}
+	}
+	var entries []*mt.Entry
+	for _, row := range rows[1:] {
+		id := row[col["post_id"]]
+		body, err := read("posts/" + id + ".html")
+		if err != nil {
+			return nil, err
+		}
+		e := &mt.Entry{
+			Title:   row[col["title"]],
+			Status:  "Draft",
+			Body:    strings.TrimSpace(string(body)) + "\n",
+			Excerpt: row[col["subtitle"]],
+		}
+		// Post IDs are like 123456.my-post.
+		if _, slug, ok := strings.Cut(id, "."); ok {
+			e.Slug = slug
+		}
+		if row[col["is_published"]] == "true" {
+			e.Status = "Publish"
+		}
+		if d := row[col["post_date"]]; d != "" {
+			if e.Date, err = time.Parse(time.RFC3339, d); err != nil {
+				return nil, fmt.Errorf("substack: post %s: %s", id, err)
+			}
+		} else {
+			e.Date = files["posts/"+id+".html"].Modified
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+	return &sliceReader{entries}, nil
+}
@@ -0,0 +1,125 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// textpatternTables are names of Textpattern tables, without prefix or
+// with any.
+var textpatternTables = []string{
+	"textpattern", "txp_category", "txp_discuss", "txp_users",
+	"*_textpattern", "*_txp_category", "*_txp_discuss", "*_txp_users",
+}
+
+// Values of textile_body of articles.
+const (
+	txpLeaveText     = "0"
+	txpUseTextile    = "1"
+	txpConvertBreaks = "2"
+)
+
+// Values of Status of articles and visible of comments.
+const (
+	txpStatusLive     = "4"
+	txpStatusSticky   = "5"
+	txpCommentVisible = "1"
+)
+
+// ReadTextpattern reads articles, with their categories, keywords as
+// tags and visible comments, from MySQL dump of Textpattern database.
+// Dates are in the server time zone, taken as UTC.
+// Bodies and excerpts written in Textile are left for the textile pass.
+func ReadTextpattern(r io.Reader) (Reader, error) {
+	t, err := readSQLDump(r, textpatternTables...)
+	if err != nil {
+		return nil, fmt.Errorf("textpattern: %s", err)
+	}
+	users := make(map[string]string)
+	for _, u := range t["txp_users"] {
+		users[u["name"]] = u["RealName"]
+	}
+	categories := make(map[string]string)
+	for _, c := range t["txp_category"] {
+		if c["type"] == "article" && c["title"] != "" {
+			categories[c["name"]] = c["title"]
+		}
+	}
+	var entries []*mt.Entry
+	byID := make(map[string]*mt.Entry)
+	for _, a := range t["textpattern"] {
+		e := &mt.Entry{
+			Title:   a["Title"],
+			Author:  a["AuthorID"],
+			Slug:    a["url_title"],
+			Status:  "Draft",
+			Body:    a["Body"],
+			Excerpt: a["Excerpt"],
+		}
+		if name := users[a["AuthorID"]]; name != "" {
+			e.Author = name
+		}
+		if a["Status"] == txpStatusLive || a["Status"] == txpStatusSticky {
+			e.Status = "Publish"
+		}
+		switch a["textile_body"] {
+		case txpUseTextile:
+			e.Markup = "textile"
+		case txpConvertBreaks:
+			e.Body = mt.WrapBreaks(e.Body)
+			e.ConvertBreaks = true
+		case txpLeaveText:
+		default:
+			// Filters of plugins: use their output.
+			e.Body = a["Body_html"]
+		}
+		for _, c := range []string{a["Category1"], a["Category2"]} {
+			if c == "" {
+				continue
+			}
+			if title, ok := categories[c]; ok {
+				c = title
+			}
+			if e.PrimaryCategory == "" {
+				e.PrimaryCategory = c
+			}
+			e.Categories = append(e.Categories, c)
+		}
+		for _, k := range strings.Split(a["Keywords"], ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				e.Tags = append(e.Tags, k)
+			}
+		}
+		if e.Date, err = sqlDateTime(a["Posted"]); err != nil {
+			return nil, fmt.Errorf("textpattern: article %s: %s", a["ID"], err)
+		}
+		entries = append(entries, e)
+		byID[a["ID"]] = e
+	}
+	comments := t["txp_discuss"]
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i]["posted"] < comments[j]["posted"] })
+	for _, c := range comments {
+		e := byID[c["parentid"]]
+		if e == nil || c["visible"] != txpCommentVisible {
+			continue
+		}
+		d, err := sqlDateTime(c["posted"])
+		if err != nil {
+			return nil, fmt.Errorf("textpattern: comment %s: %s", c["discussid"], err)
+		}
+		// Messages are stored as HTML.
+		e.Comments = append(e.Comments, &mt.Comment{
+			Author:  c["name"],
+			Email:   c["email"],
+			URL:     c["web"],
+			Date:    d,
+			Content: strings.TrimRight(c["message"], "\n") + "\n",
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+	return &sliceReader{entries}, nil
+}
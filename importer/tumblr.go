@@ -0,0 +1,356 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// tumblrPost is a post of either XML or JSON Tumblr export.
+type tumblrPost struct {
+	ID       string
+	Type     string
+	Slug     string
+	Date     string
+	State    string
+	Tags     []string
+	Title    string // text and link posts
+	Body     string // text posts, answers
+	Caption  string // photo, video and audio posts, link descriptions
+	Photos   []string
+	Quote    string
+	Source   string // of quote
+	URL      string // of link
+	Question string
+	Dialogue [][2]string // label and phrase
+	Player   string      // video or audio embed
+}
+
+type tumblrXMLPost struct {
+	ID           string   `xml:"id,attr"`
+	Type         string   `xml:"type,attr"`
+	Slug         string   `xml:"slug,attr"`
+	Date         string   `xml:"date-gmt,attr"`
+	State        string   `xml:"state,attr"`
+	Private      string   `xml:"private,attr"`
+	Tags         []string `xml:"tag"`
+	RegularTitle string   `xml:"regular-title"`
+	RegularBody  string   `xml:"regular-body"`
+	PhotoCaption string   `xml:"photo-caption"`
+	PhotoURLs    []struct {
+		MaxWidth int    `xml:"max-width,attr"`
+		URL      string `xml:",chardata"`
+	} `xml:"photo-url"`
+	Photoset []struct {
+		URLs []struct {
+			MaxWidth int    `xml:"max-width,attr"`
+			URL      string `xml:",chardata"`
+		} `xml:"photo-url"`
+	} `xml:"photoset>photo"`
+	QuoteText         string `xml:"quote-text"`
+	QuoteSource       string `xml:"quote-source"`
+	LinkText          string `xml:"link-text"`
+	LinkURL           string `xml:"link-url"`
+	LinkDescription   string `xml:"link-description"`
+	ConversationTitle string `xml:"conversation-title"`
+	Lines             []struct {
+		Label string `xml:"label,attr"`
+		Text  string `xml:",chardata"`
+	} `xml:"conversation>line"`
+	VideoCaption string `xml:"video-caption"`
+	VideoPlayer  string `xml:"video-player"`
+	AudioCaption string `xml:"audio-caption"`
+	AudioPlayer  string `xml:"audio-player"`
+	Question     string `xml:"question"`
+	Answer       string `xml:"answer"`
+}
+
+func (x *tumblrXMLPost) post() *tumblrPost {
+	p := &tumblrPost{
+		ID:       x.ID,
+		Type:     x.Type,
+		Slug:     x.Slug,
+		Date:     x.Date,
+		State:    x.State,
+		Tags:     x.Tags,
+		Title:    x.RegularTitle,
+		Body:     x.RegularBody,
+		Quote:    x.QuoteText,
+		Source:   x.QuoteSource,
+		URL:      x.LinkURL,
+		Question: x.Question,
+	}
+	if x.Private == "true" {
+		p.State = "private"
+	}
+	switch x.Type {
+	case "regular":
+		p.Type = "text"
+	case "photo":
+		p.Caption = x.PhotoCaption
+		// Photo URLs are given in several sizes; take the largest.
+		largest := func(urls []struct {
+			MaxWidth int    `xml:"max-width,attr"`
+			URL      string `xml:",chardata"`
+		}) {
+			best := -1
+			for i, u := range urls {
+				if best < 0 || u.MaxWidth > urls[best].MaxWidth {
+					best = i
+				}
+			}
+			if best >= 0 {
+				p.Photos = append(p.Photos, strings.TrimSpace(urls[best].URL))
+			}
+		}
+		if len(x.Photoset) > 0 {
+			for _, ph := range x.Photoset {
+				largest(ph.URLs)
+			}
+		} else {
+			largest(x.PhotoURLs)
+		}
+	case "link":
+		p.Title, p.Caption = x.LinkText, x.LinkDescription
+	case "conversation":
+		p.Type, p.Title = "chat", x.ConversationTitle
+		for _, l := range x.Lines {
+			p.Dialogue = append(p.Dialogue, [2]string{l.Label, l.Text})
+		}
+	case "video":
+		p.Caption, p.Player = x.VideoCaption, x.VideoPlayer
+	case "audio":
+		p.Caption, p.Player = x.AudioCaption, x.AudioPlayer
+	case "answer":
+		p.Body = x.Answer
+	}
+	return p
+}
+
+type tumblrJSONPost struct {
+	ID      json.Number `json:"id"`
+	Type    string      `json:"type"`
+	Slug    string      `json:"slug"`
+	Date    string      `json:"date"`
+	State   string      `json:"state"`
+	Tags    []string    `json:"tags"`
+	Title   string      `json:"title"`
+	Body    string      `json:"body"`
+	Caption string      `json:"caption"`
+	Photos  []struct {
+		Original struct {
+			URL string `json:"url"`
+		} `json:"original_size"`
+	} `json:"photos"`
+	Text        string `json:"text"`
+	Source      string `json:"source"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	Dialogue    []struct {
+		Label  string `json:"label"`
+		Phrase string `json:"phrase"`
+	} `json:"dialogue"`
+	Player   json.RawMessage `json:"player"` // array of embeds for video, string for audio
+	Question string          `json:"question"`
+	Answer   string          `json:"answer"`
+}
+
+func (j *tumblrJSONPost) post() *tumblrPost {
+	p := &tumblrPost{
+		ID:       j.ID.String(),
+		Type:     j.Type,
+		Slug:     j.Slug,
+		Date:     j.Date,
+		State:    j.State,
+		Tags:     j.Tags,
+		Title:    j.Title,
+		Body:     j.Body,
+		Caption:  j.Caption,
+		Quote:    j.Text,
+		Source:   j.Source,
+		URL:      j.URL,
+		Question: j.Question,
+	}
+	for _, ph := range j.Photos {
+		p.Photos = append(p.Photos, ph.Original.URL)
+	}
+	for _, d := range j.Dialogue {
+		p.Dialogue = append(p.Dialogue, [2]string{d.Label, d.Phrase})
+	}
+	switch j.Type {
+	case "link":
+		p.Caption = j.Description
+	case "answer":
+		p.Body = j.Answer
+	}
+	var embeds []struct {
+		Width int             `json:"width"`
+		Code  json.RawMessage `json:"embed_code"`
+	}
+	var code string
+	if json.Unmarshal(j.Player, &code) != nil && json.Unmarshal(j.Player, &embeds) == nil && len(embeds) > 0 {
+		// Take the widest embed; its code is false if unavailable.
+		sort.Slice(embeds, func(a, b int) bool { return embeds[a].Width > embeds[b].Width })
+		json.Unmarshal(embeds[0].Code, &code)
+	}
+	p.Player = code
+	return p
+}
+
+// tumblrDateLayout is the layout of dates in Tumblr exports.
+const tumblrDateLayout = "2006-01-02 15:04:05 MST"
+
+// entry returns post as MT entry, with photos replaced by files from
+// media, if any.
+func (p *tumblrPost) entry(media []string) (*mt.Entry, error) {
+	e := &mt.Entry{
+		Title:  p.Title,
+		Slug:   p.Slug,
+		Status: "Publish",
+		Tags:   p.Tags,
+	}
+	switch p.State {
+	case "", "published":
+	default:
+		e.Status = "Draft"
+	}
+	var err error
+	if e.Date, err = time.Parse(tumblrDateLayout, p.Date); err != nil {
+		return nil, fmt.Errorf("tumblr: post %s: %s", p.ID, err)
+	}
+	var b strings.Builder
+	switch p.Type {
+	case "text":
+		b.WriteString(p.Body)
+	case "photo":
+		for i, u := range p.Photos {
+			if i < len(media) {
+				u = media[i]
+			}
+			fmt.Fprintf(&b, "<p><img src=\"%s\" alt=\"\"></p>\n", html.EscapeString(u))
+		}
+		b.WriteString(p.Caption)
+	case "quote":
+		fmt.Fprintf(&b, "<blockquote>%s</blockquote>\n", p.Quote)
+		if p.Source != "" {
+			fmt.Fprintf(&b, "<p>— %s</p>\n", p.Source)
+		}
+	case "link":
+		if e.Title == "" {
+			e.Title = p.URL
+		}
+		fmt.Fprintf(&b, "<p><a href=\"%s\">%s</a></p>\n", html.EscapeString(p.URL), e.Title)
+		b.WriteString(p.Caption)
+	case "chat":
+		for _, d := range p.Dialogue {
+			fmt.Fprintf(&b, "<p><strong>%s</strong> %s</p>\n", html.EscapeString(d[0]), html.EscapeString(d[1]))
+		}
+	case "video", "audio":
+		fmt.Fprintf(&b, "%s\n%s", p.Player, p.Caption)
+	case "answer":
+		fmt.Fprintf(&b, "<blockquote>%s</blockquote>\n%s", html.EscapeString(p.Question), p.Body)
+		if e.Title == "" {
+			e.Title = p.Question
+		}
+	default:
+		return nil, nil
+	}
+	e.Body = strings.TrimSpace(b.String()) + "\n"
+	return e, nil
+}
+
+// ReadTumblr reads posts of Tumblr export, either posts.xml of backup
+// archive or JSON of API posts.
+func ReadTumblr(r io.Reader) (Reader, error) {
+	return ReadTumblrMedia(r, "")
+}
+
+// ReadTumblrMedia is like ReadTumblr, but replaces photo URLs with paths
+// of files from media directory of the archive, named by post ID.
+func ReadTumblrMedia(r io.Reader, media string) (Reader, error) {
+	files, err := tumblrMedia(media)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(r)
+	var posts []*tumblrPost
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("tumblr: %s", err)
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == 0xEF || c == 0xBB || c == 0xBF {
+			continue
+		}
+		br.UnreadByte()
+		if c == '{' {
+			var export struct {
+				Response struct {
+					Posts []tumblrJSONPost `json:"posts"`
+				} `json:"response"`
+				Posts []tumblrJSONPost `json:"posts"`
+			}
+			if err := json.NewDecoder(br).Decode(&export); err != nil {
+				return nil, fmt.Errorf("tumblr: %s", err)
+			}
+			for _, p := range append(export.Posts, export.Response.Posts...) {
+				posts = append(posts, p.post())
+			}
+		} else {
+			var export struct {
+				Posts []tumblrXMLPost `xml:"posts>post"`
+			}
+			if err := xml.NewDecoder(br).Decode(&export); err != nil {
+				return nil, fmt.Errorf("tumblr: %s", err)
+			}
+			for _, p := range export.Posts {
+				posts = append(posts, p.post())
+			}
+		}
+		break
+	}
+	var entries []*mt.Entry
+	for _, p := range posts {
+		e, err := p.entry(files[p.ID])
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			entries = append(entries, e)
+		}
+	}
+	return &sliceReader{entries}, nil
+}
+
+// tumblrMedia returns paths of files in media directory by post ID. Files
+// are named ID.ext, or ID_N.ext for photosets.
+func tumblrMedia(dir string) (map[string][]string, error) {
+	files := make(map[string][]string)
+	if dir == "" {
+		return files, nil
+	}
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, de := range des {
+		name := de.Name()
+		id := strings.TrimSuffix(name, path.Ext(name))
+		id, _, _ = strings.Cut(id, "_")
+		files[id] = append(files[id], path.Join(dir, name))
+	}
+	for _, f := range files {
+		sort.Slice(f, func(i, j int) bool { return len(f[i]) < len(f[j]) || len(f[i]) == len(f[j]) && f[i] < f[j] })
+	}
+	return files, nil
+}
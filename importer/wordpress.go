@@ -0,0 +1,195 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// wxrText is an element with text, such as content:encoded.
+type wxrText struct {
+	XMLName xml.Name
+	Text    string `xml:",chardata"`
+}
+
+type wxrCategory struct {
+	Domain string `xml:"domain,attr"`
+	Name   string `xml:",chardata"`
+}
+
+type wxrComment struct {
+	Author   string `xml:"comment_author"`
+	Email    string `xml:"comment_author_email"`
+	URL      string `xml:"comment_author_url"`
+	Date     string `xml:"comment_date"`
+	Content  string `xml:"comment_content"`
+	Approved string `xml:"comment_approved"`
+	Type     string `xml:"comment_type"`
+}
+
+type wxrItem struct {
+	Title      string        `xml:"title"`
+	Creator    string        `xml:"creator"`
+	Encoded    []wxrText     `xml:"encoded"` // content:encoded and excerpt:encoded
+	Name       string        `xml:"post_name"`
+	Date       string        `xml:"post_date"`
+	Status     string        `xml:"status"`
+	Type       string        `xml:"post_type"`
+	Categories []wxrCategory `xml:"category"`
+	Comments   []wxrComment  `xml:"comment"`
+}
+
+type wxrExport struct {
+	Items []wxrItem `xml:"channel>item"`
+}
+
+// wxrDateLayout is the layout of WordPress local dates.
+const wxrDateLayout = "2006-01-02 15:04:05"
+
+// ReadWordPress reads posts of WordPress eXtended RSS (WXR) export, with
+// their approved comments. Pages and attachments are skipped.
+func ReadWordPress(r io.Reader) (Reader, error) {
+	var export wxrExport
+	if err := xml.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("wordpress: %s", err)
+	}
+	var entries []*mt.Entry
+	for _, it := range export.Items {
+		if it.Type != "post" {
+			continue
+		}
+		e := &mt.Entry{
+			Title:         it.Title,
+			Author:        it.Creator,
+			Slug:          it.Name,
+			Status:        "Draft",
+			ConvertBreaks: true,
+		}
+		if it.Status == "publish" {
+			e.Status = "Publish"
+		}
+		var err error
+		if e.Date, err = time.Parse(wxrDateLayout, it.Date); err != nil {
+			return nil, fmt.Errorf("wordpress: %q: %s", it.Title, err)
+		}
+		for _, t := range it.Encoded {
+			if strings.Contains(t.XMLName.Space, "excerpt") {
+				e.Excerpt = strings.TrimSpace(t.Text)
+			} else {
+				e.Body = t.Text
+			}
+		}
+		// WordPress splits posts with <!--more-->.
+		if i := strings.Index(e.Body, "<!--more-->"); i >= 0 {
+			e.Body, e.Extended = e.Body[:i], strings.TrimPrefix(e.Body[i+len("<!--more-->"):], "\n")
+		}
+		e.Body, e.Extended = autop(e.Body), autop(e.Extended)
+		for _, c := range it.Categories {
+			switch c.Domain {
+			case "category":
+				if e.PrimaryCategory == "" {
+					e.PrimaryCategory = c.Name
+				}
+				e.Categories = append(e.Categories, c.Name)
+			case "post_tag":
+				e.Tags = append(e.Tags, c.Name)
+			}
+		}
+		for _, c := range it.Comments {
+			if c.Approved != "1" || c.Type == "pingback" || c.Type == "trackback" {
+				continue
+			}
+			d, err := time.Parse(wxrDateLayout, c.Date)
+			if err != nil {
+				return nil, fmt.Errorf("wordpress: %q: comment: %s", it.Title, err)
+			}
+			e.Comments = append(e.Comments, &mt.Comment{
+				Author:  c.Author,
+				Email:   c.Email,
+				URL:     c.URL,
+				Date:    d,
+				Content: autop(c.Content),
+			})
+		}
+		entries = append(entries, e)
+	}
+	return &sliceReader{entries}, nil
+}
+
+var (
+	// wpBlock matches opening and closing block-level tags, which are
+	// kept out of paragraphs.
+	wpBlock = regexp.MustCompile(`(?i)^</?(?:table|thead|tfoot|caption|col|colgroup|tbody|tr|td|th|div|dl|dd|dt|ul|ol|li|pre|form|map|area|blockquote|address|math|style|p|h[1-6]|hr|fieldset|legend|section|article|aside|hgroup|header|footer|nav|figure|figcaption|details|menu|summary|iframe|noscript|video|audio|object|embed)(?:\s[^>]*)?/?>`)
+	// wpKeep matches text left as is: preformatted blocks and block
+	// editor comments on their own lines.
+	wpKeep = regexp.MustCompile(`(?is)<(?:pre|script|style)[\s>].*?</(?:pre|script|style)>|(?m)^[ \t]*<!--.*?-->[ \t]*$`)
+	wpTag  = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	// wpBlockEnd matches a block-level closing tag ending a paragraph.
+	wpBlockEnd = regexp.MustCompile(`(?i)(?:</(?:table|thead|tfoot|caption|col|colgroup|tbody|tr|td|th|div|dl|dd|dt|ul|ol|li|pre|form|map|area|blockquote|address|math|style|p|h[1-6]|hr|fieldset|legend|section|article|aside|hgroup|header|footer|nav|figure|figcaption|details|menu|summary|iframe|noscript|video|audio|object|embed)>|<hr[^>]*>)$`)
+	wpParas    = regexp.MustCompile(`\n\s*\n`)
+)
+
+// autop wraps text separated by blank lines into paragraphs, with line
+// breaks inside them converted to <br />, as WordPress does on display.
+// Block-level tags, block editor comments and contents of <pre>,
+// <script> and <style> are left as they are.
+func autop(text string) string {
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	var kept []string
+	text = wpKeep.ReplaceAllStringFunc(text, func(s string) string {
+		kept = append(kept, strings.TrimSpace(s))
+		return fmt.Sprintf("\n\n\x00%d\x00\n\n", len(kept)-1)
+	})
+	// Start paragraphs before block-level opening tags and after
+	// closing ones.
+	text = wpTag.ReplaceAllStringFunc(text, func(s string) string {
+		switch {
+		case !wpBlock.MatchString(s):
+			return s
+		case strings.HasPrefix(s, "</") || strings.HasSuffix(s, "/>") || strings.HasPrefix(strings.ToLower(s), "<hr"):
+			return s + "\n\n"
+		}
+		return "\n\n" + s
+	})
+	var b strings.Builder
+	for _, para := range wpParas.Split(text, -1) {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if strings.HasPrefix(para, "\x00") {
+			var i int
+			fmt.Sscanf(para, "\x00%d\x00", &i)
+			b.WriteString(kept[i] + "\n")
+			continue
+		}
+		// A paragraph that is a block element as a whole is kept;
+		// otherwise text after a leading opening tag or before a
+		// trailing closing tag is wrapped.
+		start := wpBlock.FindString(para)
+		end := wpBlockEnd.FindString(para[len(start):])
+		if start != "" && end != "" {
+			b.WriteString(para + "\n")
+			continue
+		}
+		text := strings.TrimSpace(para[len(start) : len(para)-len(end)])
+		b.WriteString(start)
+		if text != "" {
+			lines := strings.Split(text, "\n")
+			for i, l := range lines {
+				lines[i] = strings.TrimSpace(l)
+			}
+			b.WriteString("<p>" + strings.Join(lines, "<br />\n") + "</p>")
+		}
+		b.WriteString(end + "\n")
+	}
+	return b.String()
+}
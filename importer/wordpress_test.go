@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWordPressGutenberg(t *testing.T) {
+	wxr := `<?xml version="1.0" encoding="UTF-8"?>
+<rss xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:wp="http://wordpress.org/export/1.2/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel><item>
+<title>Post</title>
+<dc:creator>admin</dc:creator>
+<content:encoded><![CDATA[<!-- wp:heading -->
+<h2>Intro</h2>
+<!-- /wp:heading -->
+
+<!-- wp:paragraph -->
+<p>Hello <em>world</em>.</p>
+<!-- /wp:paragraph -->
+
+<!-- wp:list -->
+<ul>
+<li>one</li>
+<li>two</li>
+</ul>
+<!-- /wp:list -->
+
+<!-- wp:code -->
+<pre class="wp-block-code"><code>a
+
+b
+</code></pre>
+<!-- /wp:code -->
+
+Classic paragraph
+with a line break.
+
+Second <!-- inline --> paragraph.]]></content:encoded>
+<wp:post_name>post</wp:post_name>
+<wp:post_date>2020-01-02 03:04:05</wp:post_date>
+<wp:status>publish</wp:status>
+<wp:post_type>post</wp:post_type>
+<wp:comment>
+<wp:comment_author>Bob</wp:comment_author>
+<wp:comment_date>2020-01-03 03:04:05</wp:comment_date>
+<wp:comment_content>Nice.
+
+Thanks!</wp:comment_content>
+<wp:comment_approved>1</wp:comment_approved>
+</wp:comment>
+</item></channel></rss>`
+	r, err := ReadWordPress(strings.NewReader(wxr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := r.NextContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<!-- wp:heading -->
+<h2>Intro</h2>
+<!-- /wp:heading -->
+<!-- wp:paragraph -->
+<p>Hello <em>world</em>.</p>
+<!-- /wp:paragraph -->
+<!-- wp:list -->
+<ul>
+<li>one</li>
+<li>two</li>
+</ul>
+<!-- /wp:list -->
+<!-- wp:code -->
+<pre class="wp-block-code"><code>a
+
+b
+</code></pre>
+<!-- /wp:code -->
+<p>Classic paragraph<br />
+with a line break.</p>
+<p>Second <!-- inline --> paragraph.</p>
+`
+	if e.Body != want {
+		t.Errorf("body:\n%s\nwant:\n%s", e.Body, want)
+	}
+	if got := e.Comments[0].Content; got != "<p>Nice.</p>\n<p>Thanks!</p>\n" {
+		t.Errorf("comment: %q", got)
+	}
+}
+
+func TestAutop(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"", ""},
+		{"one", "<p>one</p>\n"},
+		{"<blockquote>Quote\n\nMore</blockquote>", "<blockquote><p>Quote</p>\n<p>More</p></blockquote>\n"},
+		{"<div class=\"x\">text</div>", "<div class=\"x\">text</div>\n"},
+		{"<div>\n\ntext\n\n</div>", "<div>\n<p>text</p>\n</div>\n"},
+		{"a <strong>b</strong>\r\nc", "<p>a <strong>b</strong><br />\nc</p>\n"},
+		{"<hr />\ntext", "<hr />\n<p>text</p>\n"},
+	} {
+		if got := autop(tt.in); got != tt.want {
+			t.Errorf("autop(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package mt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchEntries returns n entries with long bodies and many comments.
+func benchEntries(n int) []*Entry {
+	para := strings.Repeat("Lorem ipsum dolor sit amet, <a href=\"http://example.com/\">consectetur</a> adipiscing elit. ", 8)
+	body := strings.Repeat("<p>"+para+"</p>\n", 20)
+	date := time.Date(2004, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := make([]*Entry, n)
+	for i := range entries {
+		e := &Entry{
+			Date:       date.Add(time.Duration(i) * time.Hour),
+			Title:      fmt.Sprintf("Entry %d", i),
+			Author:     "author",
+			Slug:       fmt.Sprintf("entry_%d", i),
+			Status:     "Publish",
+			Categories: []string{"News", "Misc"},
+			Tags:       []string{"go", "mt export"},
+			Body:       body,
+			Extended:   body,
+		}
+		for j := 0; j < 20; j++ {
+			e.Comments = append(e.Comments, &Comment{
+				Author:  fmt.Sprintf("commenter %d", j),
+				Email:   "c@example.com",
+				URL:     "http://example.com/",
+				Date:    e.Date.Add(time.Duration(j) * time.Minute),
+				Content: para,
+			})
+		}
+		entries[i] = e
+	}
+	return entries
+}
+
+func benchExport(b *testing.B, n int) []byte {
+	b.Helper()
+	data, err := WriteExport(benchEntries(n))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+func BenchmarkParse(b *testing.B) {
+	data := benchExport(b, 10000)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := NewParser(bytes.NewReader(data))
+		for {
+			_, err := p.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkWriteExport(b *testing.B) {
+	entries := benchEntries(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := WriteExport(entries); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package mt
+
+import "fmt"
+
+// ParseError is an error of parsing MT export.
+type ParseError struct {
+	Line    int    // input line number
+	Section string // "header", "body", "comment", etc., or empty between entries
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	if e.Section == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Section, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
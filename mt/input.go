@@ -0,0 +1,94 @@
+package mt
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// chunkSize is the size of input buffer: longer lines are read in
+// chunks of this size, so that their length is not limited.
+const chunkSize = 64 << 10
+
+// lineReader reads input lines in chunks.
+type lineReader struct {
+	r   *bufio.Reader
+	err error // read error other than io.EOF
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReaderSize(r, chunkSize)}
+}
+
+// chunk returns the next chunk of line without line ending, and whether
+// it is the last chunk of the line. It returns ok = false at the end of
+// input or on error.
+func (lr *lineReader) chunk() (b []byte, last, ok bool) {
+	if lr.err != nil {
+		return nil, false, false
+	}
+	b, err := lr.r.ReadSlice('\n')
+	switch err {
+	case nil:
+		b = b[:len(b)-1]
+		if n := len(b); n > 0 && b[n-1] == '\r' {
+			b = b[:n-1]
+		}
+		return b, true, true
+	case bufio.ErrBufferFull:
+		return b, false, true
+	case io.EOF:
+		// Last line without line ending.
+		return b, true, len(b) > 0
+	default:
+		lr.err = err
+		return nil, false, false
+	}
+}
+
+// spillBuffer keeps section text in memory up to max bytes, and in a
+// temporary file in dir after that. Zero max means no limit.
+type spillBuffer struct {
+	buf strings.Builder
+	f   *os.File
+	max int
+	dir string
+	err error
+}
+
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.f == nil {
+		if b.max <= 0 || b.buf.Len()+len(p) <= b.max {
+			return b.buf.Write(p)
+		}
+		if b.f, b.err = os.CreateTemp(b.dir, "mt-section-"); b.err != nil {
+			return 0, b.err
+		}
+		if _, b.err = io.WriteString(b.f, b.buf.String()); b.err != nil {
+			return 0, b.err
+		}
+		b.buf.Reset()
+	}
+	var n int
+	n, b.err = b.f.Write(p)
+	return n, b.err
+}
+
+func (b *spillBuffer) WriteString(s string) (int, error) {
+	return b.Write([]byte(s))
+}
+
+// result returns text kept in memory, or the name of file with text.
+func (b *spillBuffer) result() (text, file string, err error) {
+	if b.f == nil {
+		return b.buf.String(), "", b.err
+	}
+	if err := b.f.Close(); b.err == nil {
+		b.err = err
+	}
+	return "", b.f.Name(), b.err
+}
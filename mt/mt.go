@@ -0,0 +1,58 @@
+// Package mt reads and writes Movable Type export files.
+package mt
+
+import "time"
+
+const (
+	SectionMarker = "-----"
+	EntryMarker   = "--------"
+)
+
+// Comment is a comment of entry.
+type Comment struct {
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	URL     string    `json:"url"`
+	Date    time.Time `json:"date"`
+	Content string    `json:"content"`
+
+	SrcStart, SrcEnd int `json:"-"` // input lines
+}
+
+// SrcRange is a range of input lines of an entry section.
+type SrcRange struct {
+	Name       string
+	Start, End int
+}
+
+// Entry is an entry of MT export. With CONVERT BREAKS on, lines of
+// Body and Extended are wrapped into paragraphs when parsing.
+type Entry struct {
+	Date            time.Time  `json:"date"`
+	Title           string     `json:"title"`
+	Author          string     `json:"author"`
+	Slug            string     `json:"slug"` // BASENAME
+	Status          string     `json:"status"`
+	PrimaryCategory string     `json:"primary_category"`
+	Categories      []string   `json:"categories"`
+	Tags            []string   `json:"tags"`
+	Markup          string     `json:"markup"` // "markdown", "textile" or empty for HTML
+	ConvertBreaks   bool       `json:"convert_breaks"`
+	Body            string     `json:"body"`
+	Extended        string     `json:"extended"` // EXTENDED BODY
+	Excerpt         string     `json:"excerpt"`
+	Keywords        []string   `json:"keywords"`
+	Password        string     `json:"-"` // PASSWORD of entry protection plugins
+	Comments        []*Comment `json:"comments"`
+	Src             []SrcRange `json:"-"`
+	// Header has values of header keys as they were in export, e.g.
+	// "TAGS", so that output can keep them.
+	Header map[string]string `json:"-"`
+	// Spilled maps names of sections ("body", "extended body",
+	// "excerpt", "comment N") longer than Parser.MaxSection to files
+	// with their text. Such sections are empty in entry.
+	Spilled map[string]string `json:"spilled,omitempty"`
+}
+
+// Text returns body with extended body.
+func (e *Entry) Text() string { return e.Body + e.Extended }
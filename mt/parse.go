@@ -0,0 +1,530 @@
+package mt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parser reads entries from MT export.
+type Parser struct {
+	lr   *lineReader
+	text string // last read line
+	eof  bool
+	line int
+	err  error
+	ctx  context.Context // of the current NextContext call
+	// section is the name of section being parsed, for errors.
+	section string
+	// Lenient allows the last entry to end without entry marker and the
+	// last section without section marker.
+	Lenient bool
+	// MaxSection is the maximum size in bytes of section text kept in
+	// memory. Longer sections are saved into files in SpillDir (or the
+	// default temporary directory) listed in Entry.Spilled. Zero means
+	// no limit.
+	MaxSection int
+	SpillDir   string
+	// Transforms are applied in order to each parsed entry by Next.
+	Transforms []Transform
+	// Dialect is the variant of export format: "" for MT, or
+	// DialectTypePad, which allows unknown header keys, sections and
+	// markup, comment header keys in any order, and more date formats.
+	Dialect string
+}
+
+// DialectTypePad is the TypePad variant of MT export format.
+const DialectTypePad = "typepad"
+
+// dateLayouts are layouts of export dates; TypePad exports have all of
+// them.
+var dateLayouts = []string{
+	"01/02/2006 3:04:05 PM",
+	"01/02/2006 3:04 PM",
+	"01/02/2006 15:04:05",
+	"01/02/2006 15:04",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+}
+
+func (p *Parser) parseDate(s string) (time.Time, error) {
+	t, err := time.Parse(dateLayouts[0], s)
+	if err == nil || p.Dialect != DialectTypePad {
+		return t, err
+	}
+	for _, layout := range dateLayouts[1:] {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return t, err
+}
+
+// NewParser returns a parser reading MT export from r.
+func NewParser(r io.Reader) *Parser {
+	return &Parser{lr: newLineReader(r)}
+}
+
+// parseError is the panic value of parse failures, recovered by Next.
+type parseError struct{ err error }
+
+func (p *Parser) fail(err error) {
+	panic(parseError{&ParseError{Line: p.line, Section: p.section, Err: err}})
+}
+
+func (p *Parser) errorf(format string, args ...interface{}) {
+	p.fail(fmt.Errorf(format, args...))
+}
+
+// Line returns the number of the last read input line.
+func (p *Parser) Line() int { return p.line }
+
+// checkContext fails if the context of NextContext is done.
+func (p *Parser) checkContext() {
+	if p.ctx == nil {
+		return
+	}
+	if err := p.ctx.Err(); err != nil {
+		p.fail(err)
+	}
+}
+
+// maxLine is the maximum size in bytes of lines outside of sections,
+// such as header lines, without MaxSection.
+const maxLine = 1 << 20
+
+// scan reads the whole next line into p.text. Lines longer than
+// MaxSection or maxLine fail.
+func (p *Parser) scan() bool {
+	p.checkContext()
+	b, last, ok := p.lr.chunk()
+	if !ok {
+		return false
+	}
+	p.line++
+	if last {
+		p.text = string(b)
+		return true
+	}
+	max := p.MaxSection
+	if max <= 0 {
+		max = maxLine
+	}
+	line := append([]byte(nil), b...)
+	for !last {
+		if b, last, ok = p.lr.chunk(); !ok {
+			break
+		}
+		if len(line)+len(b) > max {
+			p.errorf("line is longer than %d bytes", max)
+		}
+		line = append(line, b...)
+	}
+	p.text = string(line)
+	return true
+}
+
+// Wrapping of section lines into paragraphs.
+const (
+	noWrap      = iota
+	wrapBreaks  // lines not starting with paragraph, as with CONVERT BREAKS
+	wrapAll     // all lines
+	wrapComment // all lines, unless the first is commentNoWrap
+)
+
+// commentNoWrap starts comment text that isn't wrapped when parsing.
+// It's not in MT exports; WriteExport writes it before comments that
+// aren't in paragraphs line by line.
+const commentNoWrap = "CONVERT BREAKS: 0"
+
+// WrapBreaks wraps lines of text not starting with paragraph into
+// paragraphs and drops empty lines, as the parser does with CONVERT
+// BREAKS on. Importers of other formats use it for text with line
+// breaks converted on display.
+func WrapBreaks(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		if strings.HasPrefix(line, "<p ") || strings.HasPrefix(line, "<p>") {
+			b.WriteString(line + "\n")
+		} else if line != "" {
+			b.WriteString("<p>" + line + "</p>\n")
+		}
+	}
+	return b.String()
+}
+
+// sectionText reads text of section, returning it or the name of file
+// it was saved to if it's longer than MaxSection. Empty lines are
+// dropped when wrapping. It returns terminated = false if input ended
+// before section marker.
+func (p *Parser) sectionText(wrap int) (text, file string, terminated bool) {
+	b := &spillBuffer{max: p.MaxSection, dir: p.SpillDir}
+	for {
+		p.checkContext()
+		first, last, ok := p.lr.chunk()
+		if !ok {
+			break
+		}
+		p.line++
+		if last && string(first) == SectionMarker {
+			terminated = true
+			break
+		}
+		if wrap == wrapComment {
+			if wrap = wrapAll; last && string(first) == commentNoWrap {
+				wrap = noWrap
+				continue
+			}
+		}
+		w := wrap == wrapAll || wrap == wrapBreaks && !bytes.HasPrefix(first, []byte("<p ")) && !bytes.HasPrefix(first, []byte("<p>"))
+		if w && last && len(first) == 0 {
+			continue
+		}
+		if w {
+			b.WriteString("<p>")
+		}
+		b.Write(first)
+		for chunk := first; !last; {
+			if chunk, last, ok = p.lr.chunk(); !ok {
+				break
+			}
+			b.Write(chunk)
+		}
+		if w {
+			b.WriteString("</p>")
+		}
+		b.WriteString("\n")
+	}
+	if p.lr.err != nil {
+		p.fail(p.lr.err)
+	}
+	text, file, err := b.result()
+	if err != nil {
+		p.fail(err)
+	}
+	return text, file, terminated
+}
+
+// spill records file with text of section name of entry.
+func spill(e *Entry, name, file string) {
+	if file == "" {
+		return
+	}
+	if e.Spilled == nil {
+		e.Spilled = make(map[string]string)
+	}
+	e.Spilled[name] = file
+}
+
+func (p *Parser) entryHeaderItem(e *Entry) bool {
+	if !p.scan() {
+		if p.lr.err == nil {
+			p.eof = true
+			return false
+		}
+		p.fail(p.lr.err)
+	}
+	text := p.text
+	if text == SectionMarker {
+		// End of section.
+		return false
+	}
+	if text == "" {
+		return true
+	}
+	kv := strings.SplitN(text, ":", 2)
+	if len(kv) != 2 {
+		p.errorf("unexpected `%s`", text)
+	}
+	val := strings.TrimSpace(kv[1])
+	if e.Header == nil {
+		e.Header = make(map[string]string)
+	}
+	e.Header[kv[0]] = val
+	switch kv[0] {
+	case "AUTHOR":
+		e.Author = val
+	case "TITLE":
+		e.Title = val
+	case "BASENAME":
+		e.Slug = val
+	case "STATUS":
+		e.Status = val
+	case "PRIMARY CATEGORY":
+		e.PrimaryCategory = val
+	case "CATEGORY":
+		e.Categories = append(e.Categories, val)
+	case "TAGS":
+		e.Tags = ParseTags(val)
+	case "ALLOW COMMENTS", "ALLOW PINGS":
+		// Ignored.
+	case "PASSWORD":
+		e.Password = val
+	case "DATE":
+		date, err := p.parseDate(val)
+		if err != nil {
+			p.fail(err)
+		}
+		e.Date = date
+	case "CONVERT BREAKS":
+		switch val {
+		case "markdown", "markdown_with_smartypants":
+			e.Markup = "markdown"
+		case "1", "__default__":
+			e.ConvertBreaks = true
+		case "0":
+			e.ConvertBreaks = false
+		case "textile", "textile_2":
+			e.Markup = "textile"
+		default:
+			if p.Dialect != DialectTypePad {
+				p.errorf("unsupported markup %s", val)
+			}
+		}
+	default:
+		if p.Dialect != DialectTypePad {
+			p.errorf("unknown header key `%s`", kv[0])
+		}
+	}
+	return true
+}
+
+func (p *Parser) entryHeader(e *Entry) {
+	for p.entryHeaderItem(e) {
+	}
+}
+
+func (p *Parser) nextSection() (name string, ok bool) {
+	for {
+		if !p.scan() {
+			if p.lr.err == nil && p.Lenient {
+				p.eof = true
+				return "", false
+			}
+			if p.lr.err == nil {
+				p.errorf("unexpected end of file")
+			}
+			p.fail(p.lr.err)
+		}
+		name = p.text
+		if name == EntryMarker {
+			return "", false
+		}
+		if name != "" {
+			return name, true
+		}
+	}
+}
+
+// entryBody returns body section text.
+func (p *Parser) entryBody(e *Entry, name string) string {
+	wrap := noWrap
+	if e.ConvertBreaks {
+		wrap = wrapBreaks
+	}
+	text, file, ok := p.sectionText(wrap)
+	if !ok && !(p.Lenient && p.lr.err == nil) {
+		p.errorf("unterminated body")
+	}
+	spill(e, name, file)
+	return text
+}
+
+func (p *Parser) scanCommentItem(key string) (value string) {
+	if !p.scan() {
+		p.errorf("expecting %s", key)
+	}
+	kv := strings.SplitN(p.text, ":", 2)
+	if len(kv) != 2 {
+		p.errorf("wrong format %s", key)
+	}
+	if kv[0] != key {
+		p.errorf("expected %s, got %s", key, kv[0])
+	}
+	return strings.TrimSpace(kv[1])
+}
+
+func (p *Parser) scanComment() (*Comment, string) {
+	// Header.
+	c := new(Comment)
+	var date time.Time
+	var err error
+	if p.Dialect == DialectTypePad {
+		date, err = p.scanTypePadCommentHeader(c)
+	} else {
+		c.Author = p.scanCommentItem("AUTHOR")
+		c.Email = p.scanCommentItem("EMAIL")
+		p.scanCommentItem("IP")
+		c.URL = p.scanCommentItem("URL")
+		date, err = p.parseDate(p.scanCommentItem("DATE"))
+	}
+	if err != nil {
+		p.errorf("parsing comment date: %s", err)
+	}
+	c.Date = date
+
+	text, file, ok := p.sectionText(wrapComment)
+	if !ok {
+		p.errorf("unterminated comment body")
+	}
+	c.Content = text
+	return c, file
+}
+
+// scanTypePadCommentHeader reads comment header keys in any order until
+// DATE, which ends the header.
+func (p *Parser) scanTypePadCommentHeader(c *Comment) (time.Time, error) {
+	for {
+		if !p.scan() {
+			p.errorf("expecting DATE")
+		}
+		key, val, ok := strings.Cut(p.text, ":")
+		if !ok {
+			p.errorf("unexpected `%s` in comment header", p.text)
+		}
+		val = strings.TrimSpace(val)
+		switch key {
+		case "AUTHOR":
+			c.Author = val
+		case "EMAIL":
+			c.Email = val
+		case "URL":
+			c.URL = val
+		case "DATE":
+			return p.parseDate(val)
+		}
+	}
+}
+
+func (p *Parser) sectionLines() []string {
+	var lines []string
+	for p.scan() {
+		if p.text == SectionMarker {
+			return lines
+		}
+		lines = append(lines, p.text)
+	}
+	if p.lr.err != nil {
+		p.fail(p.lr.err)
+	}
+	p.errorf("unexpected end of section")
+	return nil
+}
+
+func (p *Parser) skipSection() {
+	for p.scan() {
+		if p.text == SectionMarker {
+			return
+		}
+	}
+	if p.lr.err != nil {
+		p.fail(p.lr.err)
+	}
+	p.errorf("unexpected end of section")
+
+}
+
+// Next returns the next entry of export, after applying transforms and
+// skipping entries they drop. At the end of input, it returns io.EOF.
+// Parse errors are *ParseError; after one, it keeps returning it.
+func (p *Parser) Next() (*Entry, error) {
+	return p.NextContext(context.Background())
+}
+
+// NextContext is like Next, but stops reading input when ctx is done,
+// returning an error wrapping ctx.Err(). Entries returned before that are
+// complete; the parser keeps returning the error afterwards.
+func (p *Parser) NextContext(ctx context.Context) (*Entry, error) {
+	p.ctx = ctx
+	defer func() { p.ctx = nil }()
+	for {
+		e, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if e, err = p.transform(e); err != nil || e != nil {
+			return e, err
+		}
+	}
+}
+
+func (p *Parser) next() (e *Entry, err error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			pe, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			e, err, p.err = nil, pe.err, pe.err
+		}
+	}()
+	if e = p.entry(); e == nil {
+		p.err = io.EOF
+		return nil, io.EOF
+	}
+	return e, nil
+}
+
+func (p *Parser) entry() *Entry {
+	e := new(Entry)
+	start := p.line + 1
+	p.section = "header"
+	p.entryHeader(e)
+	if p.eof {
+		return nil
+	}
+	e.Src = append(e.Src, SrcRange{"header", start, p.line})
+	for {
+		p.section = ""
+		name, ok := p.nextSection()
+		if !ok {
+			break
+		}
+		start := p.line
+		p.section = strings.ToLower(strings.TrimSuffix(name, ":"))
+		switch name {
+		case "BODY:":
+			e.Body = p.entryBody(e, "body")
+		case "EXTENDED BODY:":
+			e.Extended = p.entryBody(e, "extended body")
+		case "EXCERPT:":
+			text, file, ok := p.sectionText(noWrap)
+			if !ok {
+				p.errorf("unexpected end of section")
+			}
+			e.Excerpt = strings.TrimSpace(text)
+			spill(e, "excerpt", file)
+		case "KEYWORDS:":
+			for _, line := range p.sectionLines() {
+				for _, k := range strings.Split(line, ",") {
+					if k = strings.TrimSpace(k); k != "" {
+						e.Keywords = append(e.Keywords, k)
+					}
+				}
+			}
+		case "PING:":
+			p.skipSection()
+		case "COMMENT:":
+			c, file := p.scanComment()
+			c.SrcStart, c.SrcEnd = start, p.line
+			e.Comments = append(e.Comments, c)
+			spill(e, "comment "+strconv.Itoa(len(e.Comments)), file)
+			continue
+		default:
+			if p.Dialect != DialectTypePad {
+				p.errorf("unknown section %s", name)
+			}
+			p.skipSection()
+		}
+		e.Src = append(e.Src, SrcRange{p.section, start, p.line})
+	}
+	p.section = ""
+	return e
+}
@@ -0,0 +1,55 @@
+package mt
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseLongLine(t *testing.T) {
+	export := func(title string) string {
+		return "TITLE: " + title + "\nDATE: 01/02/2006 03:04:05 PM\n-----\nBODY:\n<p>Hi</p>\n-----\n--------\n"
+	}
+	title := strings.Repeat("x", 3*chunkSize)
+	p := NewParser(strings.NewReader(export(title)))
+	e, err := p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Title != title {
+		t.Errorf("title of %d bytes, want %d", len(e.Title), len(title))
+	}
+
+	p = NewParser(strings.NewReader(export(title)))
+	p.MaxSection = 2 * chunkSize
+	if _, err := p.Next(); err == nil || !strings.Contains(err.Error(), "line is longer than") {
+		t.Errorf("line over MaxSection: error %v", err)
+	}
+	p = NewParser(strings.NewReader(export(strings.Repeat("x", maxLine+1))))
+	if _, err := p.Next(); err == nil || !strings.Contains(err.Error(), "line is longer than") {
+		t.Errorf("line over maxLine: error %v", err)
+	}
+}
+
+func TestParseSpill(t *testing.T) {
+	dir := t.TempDir()
+	body := strings.Repeat("<p>long</p>\n", 100)
+	export := "TITLE: Spill\nDATE: 01/02/2006 03:04:05 PM\n-----\nBODY:\n" + body + "-----\n--------\n"
+	p := NewParser(strings.NewReader(export))
+	p.MaxSection = 100
+	p.SpillDir = dir
+	e, err := p.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Body != "" {
+		t.Errorf("body of %d bytes kept in memory", len(e.Body))
+	}
+	b, err := os.ReadFile(e.Spilled["body"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != body {
+		t.Errorf("spilled body %q", b)
+	}
+}
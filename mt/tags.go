@@ -0,0 +1,34 @@
+package mt
+
+import (
+	"encoding/csv"
+	"strings"
+)
+
+// ParseTags splits MT tags, which are separated by commas and quoted if
+// they contain spaces.
+func ParseTags(s string) []string {
+	r := csv.NewReader(strings.NewReader(s))
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	tags, err := r.Read()
+	if err != nil {
+		return nil
+	}
+	for i := range tags {
+		tags[i] = strings.TrimSpace(tags[i])
+	}
+	return tags
+}
+
+// JoinTags joins tags as in MT export, quoting ones with spaces.
+func JoinTags(tags []string) string {
+	quoted := make([]string, len(tags))
+	for i, t := range tags {
+		if strings.ContainsAny(t, " ,\"") {
+			t = `"` + strings.Replace(t, `"`, `""`, -1) + `"`
+		}
+		quoted[i] = t
+	}
+	return strings.Join(quoted, ",")
+}
@@ -0,0 +1,22 @@
+package mt
+
+// Transform changes a parsed entry. It returns the entry to use, which
+// may be a new one, or nil to drop the entry.
+type Transform func(e *Entry) (*Entry, error)
+
+// transform applies parser transforms to entry.
+func (p *Parser) transform(e *Entry) (*Entry, error) {
+	return ApplyTransforms(p.Transforms, e)
+}
+
+// ApplyTransforms applies transforms in order to entry, returning nil if
+// one of them drops it.
+func ApplyTransforms(ts []Transform, e *Entry) (*Entry, error) {
+	for _, t := range ts {
+		var err error
+		if e, err = t(e); err != nil || e == nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,151 @@
+package mt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DateFormat is the format of dates in MT export.
+const DateFormat = "01/02/2006 03:04:05 PM"
+
+// WriteExport returns MT export text of entries, parsed or made by
+// importers, such that parsing it gives the same entries. Bodies are
+// written with CONVERT BREAKS: 0, as line breaks were already converted
+// when parsing, and so is comment text that isn't in paragraphs line by
+// line. (It's WriteMTExport of other tools: the package name says MT.)
+func WriteExport(entries []*Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		if err := e.writeMT(&buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (e *Entry) writeMT(buf *bytes.Buffer) error {
+	field := func(key, value string) error {
+		if value == "" {
+			return nil
+		}
+		return writeKey(buf, key, value)
+	}
+	for _, f := range []struct{ key, value string }{
+		{"AUTHOR", e.Author},
+		{"TITLE", e.Title},
+		{"BASENAME", e.Slug},
+		{"STATUS", e.Status},
+		{"PRIMARY CATEGORY", e.PrimaryCategory},
+		{"PASSWORD", e.Password},
+	} {
+		if err := field(f.key, f.value); err != nil {
+			return err
+		}
+	}
+	switch e.Markup {
+	case "markdown":
+		buf.WriteString("CONVERT BREAKS: markdown\n")
+	case "textile":
+		buf.WriteString("CONVERT BREAKS: textile_2\n")
+	default:
+		buf.WriteString("CONVERT BREAKS: 0\n")
+	}
+	for _, c := range e.Categories {
+		if err := field("CATEGORY", c); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(buf, "DATE: %s\n", e.Date.Format(DateFormat))
+	if err := field("TAGS", JoinTags(e.Tags)); err != nil {
+		return err
+	}
+	buf.WriteString(SectionMarker + "\n")
+	if err := writeMTSection(buf, "BODY:", e.Body); err != nil {
+		return err
+	}
+	if e.Extended != "" {
+		if err := writeMTSection(buf, "EXTENDED BODY:", e.Extended); err != nil {
+			return err
+		}
+	}
+	if e.Excerpt != "" {
+		if err := writeMTSection(buf, "EXCERPT:", e.Excerpt+"\n"); err != nil {
+			return err
+		}
+	}
+	if len(e.Keywords) > 0 {
+		if err := writeMTSection(buf, "KEYWORDS:", strings.Join(e.Keywords, "\n")+"\n"); err != nil {
+			return err
+		}
+	}
+	for i, c := range e.Comments {
+		var text bytes.Buffer
+		for _, f := range []struct{ key, value string }{
+			{"AUTHOR", c.Author},
+			{"EMAIL", c.Email},
+			{"IP", ""},
+			{"URL", c.URL},
+			{"DATE", c.Date.Format(DateFormat)},
+		} {
+			if err := writeKey(&text, f.key, f.value); err != nil {
+				return fmt.Errorf("comment %d: %s", i+1, err)
+			}
+		}
+		if content, ok := unwrapComment(c.Content); ok {
+			text.WriteString(content)
+		} else {
+			text.WriteString(commentNoWrap + "\n" + c.Content)
+		}
+		if err := writeMTSection(buf, "COMMENT:", text.String()); err != nil {
+			return err
+		}
+	}
+	buf.WriteString(EntryMarker + "\n")
+	return nil
+}
+
+// writeKey writes header line with key and value, which must have no
+// line breaks.
+func writeKey(w io.Writer, key, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%s has line break", key)
+	}
+	fmt.Fprintf(w, "%s: %s\n", key, value)
+	return nil
+}
+
+// unwrapComment returns comment text with paragraphs of its lines
+// unwrapped, as they're wrapped again when parsing, or false if it
+// isn't all such paragraphs.
+func unwrapComment(text string) (string, bool) {
+	var b strings.Builder
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		s := strings.TrimSuffix(strings.TrimPrefix(line, "<p>"), "</p>")
+		if len(s)+len("<p></p>") != len(line) || s == "" || s == SectionMarker || s == EntryMarker ||
+			i == 0 && s == commentNoWrap {
+			return "", false
+		}
+		b.WriteString(s + "\n")
+	}
+	return b.String(), true
+}
+
+// writeMTSection writes section with text, which must not contain
+// section or entry markers, ending it with a line break if needed.
+func writeMTSection(buf *bytes.Buffer, name, text string) error {
+	for _, line := range strings.Split(text, "\n") {
+		if line == SectionMarker || line == EntryMarker {
+			return fmt.Errorf("%s section has marker line %q", name, line)
+		}
+	}
+	buf.WriteString(name + "\n")
+	buf.WriteString(text)
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		buf.WriteString("\n")
+	}
+	buf.WriteString(SectionMarker + "\n")
+	return nil
+}
@@ -0,0 +1,218 @@
+package mt
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseAll(t *testing.T, data []byte) []*Entry {
+	t.Helper()
+	p := NewParser(bytes.NewReader(data))
+	var entries []*Entry
+	for {
+		e, err := p.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("%s\n%s", err, data)
+		}
+		// Source lines and header values differ between exports.
+		e.Src, e.Header = nil, nil
+		for _, c := range e.Comments {
+			c.SrcStart, c.SrcEnd = 0, 0
+		}
+		entries = append(entries, e)
+	}
+}
+
+var words = []string{"lorem", "ipsum", "--", "<b>dolor</b>", "sit", "amet,", `"quoted"`, "-----x", "ünïcode", "&amp;"}
+
+func randText(r *rand.Rand, n int, sep string) string {
+	w := make([]string, 1+r.Intn(n))
+	for i := range w {
+		w[i] = words[r.Intn(len(words))]
+	}
+	return strings.Join(w, sep)
+}
+
+func randLines(r *rand.Rand, wrap bool) string {
+	var b strings.Builder
+	for i := r.Intn(5); i >= 0; i-- {
+		line := randText(r, 6, " ")
+		if wrap {
+			line = "<p>" + line + "</p>"
+		} else if r.Intn(4) == 0 {
+			line = ""
+		}
+		b.WriteString(line + "\n")
+	}
+	// Importers often don't end text with a line break.
+	if r.Intn(2) == 0 {
+		return strings.TrimSuffix(b.String(), "\n")
+	}
+	return b.String()
+}
+
+func randEntry(r *rand.Rand) *Entry {
+	e := &Entry{
+		Date:   time.Date(2000+r.Intn(20), time.Month(1+r.Intn(12)), 1+r.Intn(28), r.Intn(24), r.Intn(60), r.Intn(60), 0, time.UTC),
+		Title:  randText(r, 5, " "),
+		Author: randText(r, 2, " "),
+		Slug:   randText(r, 1, ""),
+		Status: []string{"Publish", "Draft"}[r.Intn(2)],
+		Markup: []string{"", "markdown", "textile"}[r.Intn(3)],
+		Body:   randLines(r, false),
+	}
+	if r.Intn(2) == 0 {
+		e.PrimaryCategory = randText(r, 2, " ")
+		e.Password = randText(r, 1, "")
+	}
+	for i := r.Intn(3); i > 0; i-- {
+		e.Categories = append(e.Categories, randText(r, 2, " "))
+	}
+	for i := r.Intn(4); i > 0; i-- {
+		e.Tags = append(e.Tags, randText(r, 2, " "))
+	}
+	if r.Intn(2) == 0 {
+		e.Extended = randLines(r, false)
+	}
+	if r.Intn(2) == 0 {
+		e.Excerpt = randText(r, 8, " ")
+	}
+	for i := r.Intn(3); i > 0; i-- {
+		// Keywords are split at commas when parsing.
+		e.Keywords = append(e.Keywords, strings.Replace(randText(r, 2, " "), ",", "", -1))
+	}
+	for i := r.Intn(4); i > 0; i-- {
+		// Parsed comments are in paragraphs, importers give raw HTML.
+		e.Comments = append(e.Comments, &Comment{
+			Author:  randText(r, 2, " "),
+			Email:   "c@example.com",
+			URL:     "http://example.com/",
+			Date:    e.Date.Add(time.Duration(r.Intn(1000)) * time.Minute),
+			Content: randLines(r, r.Intn(2) == 0),
+		})
+	}
+	return e
+}
+
+// normalize returns entry as parsing gives it: sections end with line
+// breaks.
+func normalize(e *Entry) *Entry {
+	n := *e
+	nl := func(s string) string {
+		if s != "" && !strings.HasSuffix(s, "\n") {
+			return s + "\n"
+		}
+		return s
+	}
+	n.Body, n.Extended = nl(e.Body), nl(e.Extended)
+	n.Comments = nil
+	for _, c := range e.Comments {
+		nc := *c
+		nc.Content = nl(c.Content)
+		n.Comments = append(n.Comments, &nc)
+	}
+	return &n
+}
+
+func TestWriteExportRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		entries := []*Entry{randEntry(r), randEntry(r)}
+		data, err := WriteExport(entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := parseAll(t, data)
+		for j, e := range entries {
+			if want := normalize(e); !reflect.DeepEqual(got[j], want) {
+				t.Fatalf("entry %d:\n%s\ngot  %#v\nwant %#v", j, data, got[j], want)
+			}
+		}
+	}
+}
+
+func TestWriteExportConvertBreaks(t *testing.T) {
+	export := `AUTHOR: me
+TITLE: Breaks
+CONVERT BREAKS: 1
+DATE: 01/02/2006 03:04:05 PM
+TAGS: foo,"bar baz"
+-----
+BODY:
+First line
+
+<p class="x">Already a paragraph</p>
+Second line
+-----
+EXTENDED BODY:
+More
+-----
+COMMENT:
+AUTHOR: one
+EMAIL: 
+IP: 127.0.0.1
+URL: 
+DATE: 01/03/2006 10:00:00 AM
+Hello
+there
+-----
+COMMENT:
+AUTHOR: two
+EMAIL: two@example.com
+IP: 
+URL: http://example.com/
+DATE: 01/04/2006 10:00:00 AM
+Bye
+-----
+--------
+`
+	entries := parseAll(t, []byte(export))
+	if len(entries) != 1 || len(entries[0].Comments) != 2 {
+		t.Fatalf("parsed %d entries", len(entries))
+	}
+	data, err := WriteExport(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := parseAll(t, data)
+	// Breaks are already converted in the written export.
+	entries[0].ConvertBreaks = false
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("got %+v, want %+v\n%s", got[0], entries[0], data)
+	}
+	if want := "<p>First line</p>\n<p class=\"x\">Already a paragraph</p>\n<p>Second line</p>\n"; got[0].Body != want {
+		t.Errorf("body %q, want %q", got[0].Body, want)
+	}
+}
+
+func TestWriteExportComments(t *testing.T) {
+	d := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	e := &Entry{Date: d, Title: "T", Comments: []*Comment{
+		{Author: "a", Date: d, Content: "<p>Hello</p>\n<p>there</p>\n"},
+		{Author: "b", Date: d, Content: "Nice<br />\n\n<blockquote>q</blockquote>"},
+	}}
+	data, err := WriteExport([]*Entry{e})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"DATE: 01/02/2006 03:04:05 PM\nHello\nthere\n-----\n", "DATE: 01/02/2006 03:04:05 PM\nCONVERT BREAKS: 0\nNice<br />\n\n<blockquote>q</blockquote>\n-----\n"} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("export has no %q:\n%s", want, data)
+		}
+	}
+	if got := parseAll(t, data); !reflect.DeepEqual(got[0], normalize(e)) {
+		t.Errorf("got %#v, want %#v", got[0], normalize(e))
+	}
+	e.Comments[1].URL = "http://example.com/\nIP: 1.2.3.4"
+	if _, err := WriteExport([]*Entry{e}); err == nil || err.Error() != "comment 2: URL has line break" {
+		t.Errorf("got error %v, want URL has line break", err)
+	}
+}
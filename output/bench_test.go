@@ -0,0 +1,88 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// benchExport returns MT export of n entries with long bodies and many
+// comments.
+func benchExport(b *testing.B, n int) []byte {
+	b.Helper()
+	para := strings.Repeat("Lorem ipsum dolor sit amet, <a href=\"http://example.com/\">consectetur</a> adipiscing elit. ", 8)
+	body := strings.Repeat("<p>"+para+"</p>\n", 20)
+	date := time.Date(2004, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := make([]*mt.Entry, n)
+	for i := range entries {
+		e := &mt.Entry{
+			Date:       date.Add(time.Duration(i) * time.Hour),
+			Title:      fmt.Sprintf("Entry %d", i),
+			Author:     "author",
+			Slug:       fmt.Sprintf("entry_%d", i),
+			Status:     "Publish",
+			Categories: []string{"News", "Misc"},
+			Tags:       []string{"go", "mt export"},
+			Body:       body,
+			Extended:   body,
+		}
+		for j := 0; j < 20; j++ {
+			e.Comments = append(e.Comments, &mt.Comment{
+				Author:  fmt.Sprintf("commenter %d", j),
+				Email:   "c@example.com",
+				URL:     "http://example.com/",
+				Date:    e.Date.Add(time.Duration(j) * time.Minute),
+				Content: para,
+			})
+		}
+		entries[i] = e
+	}
+	data, err := mt.WriteExport(entries)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return data
+}
+
+// BenchmarkFormat measures conversion of parsed entries without file
+// output.
+func BenchmarkFormat(b *testing.B) {
+	var entries []*mt.Entry
+	p := mt.NewParser(bytes.NewReader(benchExport(b, 10000)))
+	for {
+		e, err := p.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	var o Options
+	var buf bytes.Buffer
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, e := range entries {
+			buf.Reset()
+			o.Format(&buf, &Entry{Filename: "x.html", Header: FrontMatter(e), Content: []byte(e.Text()), Source: e})
+		}
+	}
+}
+
+// BenchmarkConvert measures parsing, conversion and writing of files.
+func BenchmarkConvert(b *testing.B) {
+	data := benchExport(b, 10000)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Convert(bytes.NewReader(data), Options{Dir: b.TempDir()}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
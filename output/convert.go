@@ -0,0 +1,204 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// Reader reads entries, as mt.Parser and importers do.
+type Reader interface {
+	NextContext(ctx context.Context) (*mt.Entry, error)
+}
+
+// Pass is a step of entry conversion, changing its body or front
+// matter. It returns ErrSkip if the entry shouldn't be written.
+type Pass func(ctx context.Context, e *Entry) error
+
+// ErrSkip is returned by Options.New and passes to skip entry.
+var ErrSkip = errors.New("skip entry")
+
+// Redcloth converts textile to HTML with the redcloth command.
+func Redcloth(ctx context.Context, textile []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "redcloth")
+	cmd.Stdin = bytes.NewReader(textile)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("redcloth: %s: %s", err, msg)
+		}
+		return nil, fmt.Errorf("redcloth: %s", err)
+	}
+	return out.Bytes(), nil
+}
+
+// TextilePass returns pass converting bodies of textile entries to HTML
+// with convert, Redcloth if nil, and removing markup from their front
+// matter.
+func TextilePass(convert func(ctx context.Context, textile []byte) ([]byte, error)) Pass {
+	if convert == nil {
+		convert = Redcloth
+	}
+	return func(ctx context.Context, e *Entry) error {
+		if e.Header["markup"] != "textile" {
+			return nil
+		}
+		out, err := convert(ctx, e.Content)
+		if err != nil {
+			return err
+		}
+		e.Content = out
+		delete(e.Header, "markup")
+		return nil
+	}
+}
+
+// DefaultSlug returns entry basename with underscores replaced by
+// dashes, or entry time if it has no basename.
+func DefaultSlug(e *mt.Entry) string {
+	if e.Slug == "" {
+		return e.Date.Format("150405")
+	}
+	return strings.Replace(e.Slug, "_", "-", -1)
+}
+
+// Convert converts MT export from r into posts in opts.Dir.
+func Convert(r io.Reader, opts Options) error {
+	return ConvertContext(context.Background(), r, opts)
+}
+
+// ConvertContext is like Convert, but stops when ctx is done. Posts
+// written before that are complete. With opts.Report, entries that fail
+// to be written or have sections over opts.MaxSection are reported and
+// skipped, and their errors are returned together at the end.
+func ConvertContext(ctx context.Context, r io.Reader, opts Options) error {
+	var rd Reader
+	if opts.Reader != nil {
+		var err error
+		if rd, err = opts.Reader(r); err != nil {
+			return err
+		}
+	} else {
+		p := mt.NewParser(r)
+		p.Lenient = opts.Lenient
+		p.MaxSection = opts.MaxSection
+		p.SpillDir = opts.SpillDir
+		p.Dialect = opts.Dialect
+		p.Transforms = opts.Transforms
+		rd = p
+	}
+	w := opts.Writer
+	if w == nil {
+		if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+			return err
+		}
+		fw, err := NewFileWriter(opts.Dir, opts)
+		if err != nil {
+			return err
+		}
+		w = fw
+	}
+	err := convertEntries(ctx, rd, w, &opts)
+	return errors.Join(err, w.Close())
+}
+
+func convertEntries(ctx context.Context, rd Reader, w EntryWriter, opts *Options) error {
+	names := &Names{Dir: opts.Dir, MaxSlug: opts.MaxSlug, MaxPath: opts.MaxPath}
+	if opts.Report != nil {
+		names.Report = func(filename, msg string) { opts.Report(filename, "filename", msg) }
+	}
+	passes := opts.Passes
+	if passes == nil {
+		passes = []Pass{TextilePass(nil)}
+	}
+	var errs []error
+	for {
+		src, err := rd.NextContext(ctx)
+		if err == io.EOF {
+			return errors.Join(errs...)
+		}
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		e, err := opts.convert(ctx, src, names, passes)
+		if err == ErrSkip {
+			removeSpilled(src)
+			continue
+		}
+		if err == nil {
+			err = w.WriteEntry(e)
+		}
+		removeSpilled(src)
+		var we *WriteError
+		var se *SpillError
+		switch {
+		case err == nil:
+		case opts.Report != nil && errors.As(err, &we):
+			opts.Report(we.Filename, "write", "failed: "+we.Err.Error())
+			errs = append(errs, err)
+		case opts.Report != nil && errors.As(err, &se):
+			opts.Report(se.Filename, "input", "failed: "+strings.Join(se.Sections, ", ")+" over the section size limit")
+			errs = append(errs, err)
+		default:
+			return errors.Join(append(errs, err)...)
+		}
+	}
+}
+
+// convert returns named and converted entry for parsed entry src.
+func (o *Options) convert(ctx context.Context, src *mt.Entry, names *Names, passes []Pass) (*Entry, error) {
+	header := FrontMatter(src)
+	if o.DateFormat != "" && !src.Date.IsZero() {
+		header["date"] = src.Date.Format(o.DateFormat)
+	}
+	if o.NoComments {
+		c := *src
+		c.Comments = nil
+		src = &c
+	}
+	e := &Entry{Header: header, Content: []byte(src.Text()), Source: src}
+	if o.New != nil {
+		if err := o.New(e); err != nil {
+			return nil, err
+		}
+	}
+	var name string
+	switch {
+	case o.Name != nil:
+		name = o.Name(e)
+	case o.Slug != nil:
+		name = o.Slug(src)
+	default:
+		name = DefaultSlug(src)
+	}
+	e.Route = o.RouteFor(src)
+	prefix, ext := e.Route.FilePrefix(src), o.routeExt(e.Route)
+	if o.Encrypts(src) {
+		ext += EncryptedExt
+	}
+	var err error
+	if e.Filename, err = names.Unique(prefix, name, ext); err != nil {
+		return nil, err
+	}
+	e.Slug = strings.TrimSuffix(strings.TrimPrefix(e.Filename, prefix), ext)
+	if len(src.Spilled) > 0 {
+		names.Release(e.Filename)
+		return nil, spillError(e.Filename, src)
+	}
+	for _, p := range passes {
+		if err := p(ctx, e); err != nil {
+			names.Release(e.Filename)
+			return nil, err
+		}
+	}
+	return e, nil
+}
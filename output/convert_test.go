@@ -0,0 +1,149 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// TestConvertTraversal checks that entries are written inside the
+// output directory whatever their basenames are.
+func TestConvertTraversal(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "a", "out")
+	export := `TITLE: Escape
+BASENAME: ../../x
+DATE: 01/02/2006 03:04:05 PM
+-----
+BODY:
+<p>Hi</p>
+-----
+--------
+`
+	if err := Convert(strings.NewReader(export), Options{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() {
+			rel, _ := filepath.Rel(root, path)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if want := "a/out/2006-01-02-..-..-x.html"; len(got) != 1 || got[0] != want {
+		t.Errorf("written files %q, want %q", got, want)
+	}
+
+	w, err := NewFileWriter(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &mt.Entry{Date: time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)}
+	for _, name := range []string{"../x.html", "/tmp/x.html", "a/../../x.html"} {
+		if err := w.WriteEntry(&Entry{Filename: name, Source: src}); err == nil {
+			t.Errorf("%s: written outside of output directory", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "a", "x.html")); err == nil {
+		t.Error("a/x.html exists")
+	}
+}
+
+func TestTextilePass(t *testing.T) {
+	convert := func(ctx context.Context, textile []byte) ([]byte, error) {
+		return append([]byte("<p>"), append(bytes.TrimPrefix(textile, []byte("p. ")), "</p>"...)...), nil
+	}
+	pass := TextilePass(convert)
+	e := &Entry{Header: map[string]string{"markup": "textile"}, Content: []byte("p. hi")}
+	if err := pass(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if string(e.Content) != "<p>hi</p>" {
+		t.Errorf("content %q", e.Content)
+	}
+	if _, ok := e.Header["markup"]; ok {
+		t.Error("markup is kept")
+	}
+	e = &Entry{Header: map[string]string{}, Content: []byte("p. hi")}
+	pass(context.Background(), e)
+	if string(e.Content) != "p. hi" {
+		t.Errorf("converted HTML entry: %q", e.Content)
+	}
+}
+
+// TestConvertSpilled checks that entries with sections over MaxSection
+// fail and are reported, the rest is converted, and spill files are
+// removed.
+func TestConvertSpilled(t *testing.T) {
+	dir, spill := t.TempDir(), t.TempDir()
+	body := strings.Repeat("<p>long</p>\n", 100)
+	export := `TITLE: Spill
+BASENAME: spill
+DATE: 01/02/2006 03:04:05 PM
+-----
+BODY:
+` + body + `-----
+COMMENT:
+AUTHOR: a
+EMAIL: 
+IP: 
+URL: 
+DATE: 01/02/2006 04:04:05 PM
+` + body + `-----
+--------
+TITLE: Comment
+BASENAME: comment
+DATE: 01/03/2006 03:04:05 PM
+-----
+BODY:
+<p>short</p>
+-----
+COMMENT:
+AUTHOR: b
+EMAIL: 
+IP: 
+URL: 
+DATE: 01/03/2006 05:04:05 PM
+` + body + `-----
+--------
+TITLE: Short
+BASENAME: short
+DATE: 01/04/2006 03:04:05 PM
+-----
+BODY:
+<p>short</p>
+-----
+--------
+`
+	var reports []string
+	opts := Options{Dir: dir, MaxSection: 100, SpillDir: spill, Report: func(filename, kind, msg string) {
+		reports = append(reports, filename+": "+kind+": "+msg)
+	}}
+	err := Convert(strings.NewReader(export), opts)
+	var se *SpillError
+	if !errors.As(err, &se) {
+		t.Fatalf("error %v, want SpillError", err)
+	}
+	want := []string{
+		"2006-01-02-spill.html: input: failed: body of 1200 bytes, comment 1 of 1900 bytes over the section size limit",
+		"2006-01-03-comment.html: input: failed: comment 1 of 1900 bytes over the section size limit",
+	}
+	if strings.Join(reports, "\n") != strings.Join(want, "\n") {
+		t.Errorf("reports:\n%s\nwant:\n%s", strings.Join(reports, "\n"), strings.Join(want, "\n"))
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 || files[0].Name() != "2006-01-04-short.html" {
+		t.Errorf("written files %v, want only 2006-01-04-short.html", files)
+	}
+	if files, _ := os.ReadDir(spill); len(files) != 0 {
+		t.Errorf("spill files are left: %v", files)
+	}
+}
@@ -0,0 +1,82 @@
+package output
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+// Encrypted files are encMagic, salt, nonce and AES-256-GCM ciphertext
+// with the key derived from passphrase with PBKDF2-SHA256.
+const (
+	encMagic      = "MT2KKR\x00\x01"
+	encSaltSize   = 16
+	encIterations = 600000
+)
+
+// EncryptedExt is appended to names of encrypted files.
+const EncryptedExt = ".enc"
+
+// Encrypter encrypts output files with passphrase.
+type Encrypter struct {
+	salt []byte
+	aead cipher.AEAD
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, encIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// NewEncrypter returns encrypter with key derived from passphrase. The
+// key is derived once, so all files it encrypts share the salt.
+func NewEncrypter(passphrase string) (*Encrypter, error) {
+	salt := make([]byte, encSaltSize)
+	rand.Read(salt)
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	return &Encrypter{salt, aead}, nil
+}
+
+// Encrypt returns encrypted file contents.
+func (c *Encrypter) Encrypt(plaintext []byte) []byte {
+	out := append([]byte(encMagic), c.salt...)
+	nonce := make([]byte, c.aead.NonceSize())
+	rand.Read(nonce)
+	out = append(out, nonce...)
+	return c.aead.Seal(out, nonce, plaintext, []byte(encMagic))
+}
+
+// Decrypt returns decrypted contents of file encrypted with passphrase.
+func Decrypt(passphrase string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(encMagic)) || len(data) < len(encMagic)+encSaltSize {
+		return nil, errors.New("not an encrypted file")
+	}
+	salt := data[len(encMagic) : len(encMagic)+encSaltSize]
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	data = data[len(encMagic)+encSaltSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("truncated file")
+	}
+	out, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(encMagic))
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted file")
+	}
+	return out, nil
+}
@@ -0,0 +1,79 @@
+package output
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// BeginMarker returns the marker starting generated region name.
+func BeginMarker(name string) string {
+	return "<!-- mt2kkr:begin " + name + " -->\n"
+}
+
+// EndMarker returns the marker ending generated region name.
+func EndMarker(name string) string {
+	return "<!-- mt2kkr:end " + name + " -->\n"
+}
+
+var beginMarkerRe = regexp.MustCompile(`<!-- mt2kkr:begin ([\w-]+) -->\n`)
+
+// Region is a generated region of file.
+type Region struct {
+	Name               string
+	Start, End         int // of region including markers
+	TextStart, TextEnd int // of text between markers
+}
+
+// Regions returns generated regions of b in order.
+func Regions(b []byte) []Region {
+	var rs []Region
+	for pos := 0; ; {
+		m := beginMarkerRe.FindSubmatchIndex(b[pos:])
+		if m == nil {
+			return rs
+		}
+		r := Region{Name: string(b[pos+m[2] : pos+m[3]]), Start: pos + m[0], TextStart: pos + m[1]}
+		i := bytes.Index(b[r.TextStart:], []byte(EndMarker(r.Name)))
+		if i < 0 {
+			return rs
+		}
+		r.TextEnd = r.TextStart + i
+		r.End = r.TextEnd + len(EndMarker(r.Name))
+		rs = append(rs, r)
+		pos = r.End
+	}
+}
+
+// ReplaceRegions returns existing file with text of its generated
+// regions replaced by text of the same regions in converted file out.
+// Regions that existing file doesn't have are appended to it. If
+// existing file has no regions, it returns out.
+func ReplaceRegions(existing, out []byte) []byte {
+	old := Regions(existing)
+	if len(old) == 0 {
+		return out
+	}
+	text := make(map[string][]byte)
+	var order []string
+	for _, r := range Regions(out) {
+		text[r.Name] = out[r.TextStart:r.TextEnd]
+		order = append(order, r.Name)
+	}
+	var buf bytes.Buffer
+	pos := 0
+	for _, r := range old {
+		buf.Write(existing[pos:r.TextStart])
+		buf.Write(text[r.Name])
+		delete(text, r.Name)
+		pos = r.TextEnd
+	}
+	buf.Write(existing[pos:])
+	for _, name := range order {
+		if t, ok := text[name]; ok {
+			buf.WriteString(BeginMarker(name))
+			buf.Write(t)
+			buf.WriteString(EndMarker(name))
+		}
+	}
+	return buf.Bytes()
+}
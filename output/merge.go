@@ -0,0 +1,59 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+)
+
+// SplitFrontMatter splits file into front matter fields and the rest.
+// Each field is a top-level "key: value" line with any indented lines
+// following it. It returns ok = false if file has no front matter.
+func SplitFrontMatter(b []byte) (keys []string, fields map[string]string, rest []byte, ok bool) {
+	if !bytes.HasPrefix(b, []byte("---\n")) {
+		return nil, nil, nil, false
+	}
+	end := bytes.Index(b[4:], []byte("\n---\n"))
+	if end < 0 {
+		return nil, nil, nil, false
+	}
+	fm, rest := string(b[4:4+end+1]), b[4+end+5:]
+	fields = make(map[string]string)
+	key := ""
+	for _, line := range strings.SplitAfter(fm, "\n") {
+		if line == "" {
+			continue
+		}
+		if k, _, found := strings.Cut(line, ":"); found && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-") {
+			key = k
+			keys = append(keys, key)
+		}
+		fields[key] += line
+	}
+	return keys, fields, rest, true
+}
+
+// MergeFrontMatter returns converted file out with front matter fields
+// of existing file that the converter didn't generate.
+func MergeFrontMatter(existing, out []byte) []byte {
+	oldKeys, oldFields, _, ok := SplitFrontMatter(existing)
+	if !ok {
+		return out
+	}
+	newKeys, newFields, body, ok := SplitFrontMatter(out)
+	if !ok {
+		return out
+	}
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	for _, k := range newKeys {
+		buf.WriteString(newFields[k])
+	}
+	for _, k := range oldKeys {
+		if _, ok := newFields[k]; !ok {
+			buf.WriteString(oldFields[k])
+		}
+	}
+	buf.WriteString("---\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
@@ -0,0 +1,130 @@
+package output
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// reservedNames are file names reserved on Windows.
+var reservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true,
+	"com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true,
+	"lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// SafeName replaces characters not allowed in file names on common
+// filesystems, including path separators, and avoids OS-reserved names.
+func SafeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+	name = strings.TrimRight(name, ". ")
+	if reservedNames[strings.ToLower(name)] {
+		name += "-"
+	}
+	return name
+}
+
+// Names makes file names of entries that are safe, fit length limits
+// and don't collide.
+type Names struct {
+	Dir     string // output directory, for MaxPath
+	MaxSlug int    // maximum name length in bytes, 0 for no limit
+	MaxPath int    // maximum file path length in bytes, 0 for no limit
+	// Report, if not nil, is called with file name and message when a
+	// name is changed.
+	Report func(filename, msg string)
+
+	// used are lowercased names returned so far, so that names
+	// differing only in case don't clobber each other on
+	// case-insensitive filesystems.
+	used map[string]bool
+}
+
+func (n *Names) report(filename, format string, args ...interface{}) {
+	if n.Report != nil {
+		n.Report(filename, fmt.Sprintf(format, args...))
+	}
+}
+
+// Release makes filename returned by Unique available again, as the
+// entry it was made for isn't written.
+func (n *Names) Release(filename string) { delete(n.used, strings.ToLower(filename)) }
+
+// capName shortens name so that it's no longer than MaxSlug bytes and
+// the path made of prefix, name, reserve bytes of collision suffix and
+// ext no longer than MaxPath bytes. Shortened names get a hash suffix
+// derived from the full name, so that different long names with the
+// same beginning stay distinct.
+func (n *Names) capName(prefix, name, ext string, reserve int) (string, error) {
+	max := n.MaxSlug
+	if n.MaxPath > 0 {
+		path := filepath.Join(n.Dir, prefix+ext)
+		room := n.MaxPath - len(path) - reserve
+		if room <= 0 {
+			return "", fmt.Errorf("%s: no room for file name within maximum path length of %d bytes", path, n.MaxPath)
+		}
+		if max <= 0 || room < max {
+			max = room
+		}
+	}
+	if max <= 0 || len(name) <= max {
+		return name, nil
+	}
+	sum := sha1.Sum([]byte(name))
+	suffix := "-" + hex.EncodeToString(sum[:4])
+	if len(suffix) >= max {
+		// No room for hash, so collisions are left to Unique.
+		suffix = ""
+	}
+	i := max - len(suffix)
+	for i > 0 && !utf8.RuneStart(name[i]) {
+		i--
+	}
+	return strings.TrimRight(name[:i], "-") + suffix, nil
+}
+
+// Unique returns a file name made of prefix, safe name and ext that
+// fits length limits and doesn't collide, ignoring case, with
+// previously returned names. On collision, it appends "-2", "-3", etc.
+// to name in order of calls. It fails if MaxPath leaves no room for
+// the name.
+func (n *Names) Unique(prefix, name, ext string) (string, error) {
+	if n.used == nil {
+		n.used = make(map[string]bool)
+	}
+	safe := SafeName(name)
+	short, err := n.capName(prefix, safe, ext, 0)
+	if err != nil {
+		return "", err
+	}
+	first := prefix + short + ext
+	filename := first
+	for i := 2; n.used[strings.ToLower(filename)]; i++ {
+		suffix := fmt.Sprintf("-%d", i)
+		if short, err = n.capName(prefix, safe, ext, len(suffix)); err != nil {
+			return "", err
+		}
+		filename = prefix + short + suffix + ext
+	}
+	n.used[strings.ToLower(filename)] = true
+	if short != safe {
+		n.report(filename, "shortened long name %q", name)
+	}
+	if safe != name {
+		n.report(filename, "renamed unsafe name %q", name)
+	}
+	if filename != first {
+		n.report(filename, "renamed to avoid collision with %s", first)
+	}
+	return filename, nil
+}
@@ -0,0 +1,55 @@
+package output
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNamesMaxPath(t *testing.T) {
+	n := &Names{Dir: "out", MaxPath: 40}
+	seen := make(map[string]bool)
+	long := strings.Repeat("long-name-", 10)
+	for i := 0; i < 150; i++ {
+		f, err := n.Unique("2006-01-02-", long, ".html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := filepath.Join("out", f); len(p) > 40 {
+			t.Errorf("%s is longer than 40 bytes", p)
+		}
+		if seen[f] {
+			t.Errorf("%s is repeated", f)
+		}
+		seen[f] = true
+	}
+	if f, err := n.Unique("2006-01-02-", "short", ".html"); err != nil || f != "2006-01-02-short.html" {
+		t.Errorf("short name: %q, %v", f, err)
+	}
+
+	n = &Names{Dir: "out", MaxPath: 20}
+	if f, err := n.Unique("2006-01-02-", "x", ".html"); err == nil {
+		t.Errorf("no room: got %q", f)
+	}
+}
+
+func TestNamesReport(t *testing.T) {
+	var reports []string
+	n := &Names{MaxSlug: 10, Report: func(filename, msg string) {
+		reports = append(reports, filename+": "+msg)
+	}}
+	for _, name := range []string{"a/b", "a-b", "A-B", "very-long-name"} {
+		if _, err := n.Unique("", name, ".html"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		`a-b.html: renamed unsafe name "a/b"`,
+		`a-b-2.html: renamed to avoid collision with a-b.html`,
+		`A-B-3.html: renamed to avoid collision with A-B.html`,
+		`v-458f17b3.html: shortened long name "very-long-name"`,
+	}
+	if strings.Join(reports, "\n") != strings.Join(want, "\n") {
+		t.Errorf("reports:\n%s\nwant:\n%s", strings.Join(reports, "\n"), strings.Join(want, "\n"))
+	}
+}
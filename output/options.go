@@ -0,0 +1,206 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// Options are output and conversion settings. The zero value gives the
+// default kkr output.
+type Options struct {
+	Preset      string // built-in template set, see Presets; "kkr" if templates are used
+	TemplateDir string // directory with NAME.tmpl files overriding preset templates
+	Template    string // file with the "post" template
+	CSS         string // stylesheet URL for templates
+	MF2         bool   // add microformats2 classes
+	Markers     bool   // wrap generated regions in markers, replacing only them in existing files
+
+	// AuthorEmails are emails of post author, whose comments are
+	// marked in templates.
+	AuthorEmails []string
+	// Sanitize returns comment content safe for templates, which
+	// output it as is. If nil, it's SanitizeHTML, which keeps only
+	// elements, attributes and URLs allowed by Publish.
+	Sanitize func(html string) string
+
+	// Settings of FileWriter.
+	Merge     bool     // keep front matter fields added by hand to existing files, see MergeFrontMatter
+	SourceMap bool     // write source maps next to files, see SourceMap
+	Input     string   // input name for source maps, "-" if empty
+	Routes    []*Route // routes of entries, see RouteFor
+	PostDir   string   // subdirectory of entries not matching Routes
+	// EncryptCategories are categories of entries that Encrypter
+	// encrypts into files with EncryptedExt.
+	EncryptCategories []string
+	Encrypter         *Encrypter
+
+	// Settings of Convert.
+	Dir        string                 // output directory
+	Ext        string                 // file extension, ".html" if empty
+	DatePrefix string                 // date layout of file name prefix, "2006-01-02-" if empty
+	DateFormat string                 // layout of front matter date, see FrontMatter if empty
+	Slug       func(*mt.Entry) string // file name slug, see DefaultSlug if nil
+	MaxSlug    int                    // see Names
+	MaxPath    int                    // see Names
+	NoComments bool                   // don't write comments
+	Lenient    bool                   // see mt.Parser
+	MaxSection int                    // see mt.Parser; entries with longer sections fail, see SpillError
+	SpillDir   string                 // see mt.Parser
+	Dialect    string                 // see mt.Parser
+	Transforms []mt.Transform         // applied to parsed entries, see mt.Parser
+	// Reader, if not nil, returns reader of entries from input, e.g.
+	// of package importer, instead of mt.Parser.
+	Reader func(io.Reader) (Reader, error)
+	// New, if not nil, is called with each entry before it's named.
+	// It can change front matter and body, and set Data. It returns
+	// ErrSkip if the entry shouldn't be written.
+	New func(e *Entry) error
+	// Name, if not nil, returns file name slug of entry instead of
+	// Slug. Names are made safe and unique, see Names.
+	Name func(e *Entry) string
+	// Passes are steps of conversion of named entries, run in order;
+	// TextilePass(nil) if nil.
+	Passes []Pass
+	// Report, if not nil, is called with file name, kind and message
+	// of notes about conversion: renamed and skipped entries, and
+	// write errors, after which conversion continues.
+	Report func(filename, kind, msg string)
+	// Writer, if not nil, writes entries instead of FileWriter in
+	// Dir. It's closed at the end.
+	Writer EntryWriter
+}
+
+// Encrypts reports whether entry is written encrypted.
+func (o *Options) Encrypts(e *mt.Entry) bool {
+	if o.Encrypter == nil {
+		return false
+	}
+	for _, c := range e.Categories {
+		if contains(o.EncryptCategories, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unlisted reports whether entry is left out of indexes and feeds:
+// it's encrypted or password-protected.
+func (o *Options) Unlisted(e *mt.Entry) bool {
+	return e.Password != "" || o.Encrypts(e)
+}
+
+// UsesTemplates reports whether output is rendered with templates
+// instead of the default writer.
+func (o *Options) UsesTemplates() bool {
+	return o.Preset != "" || o.TemplateDir != "" || o.Template != ""
+}
+
+func (o *Options) preset() string {
+	if o.Preset == "" {
+		return "kkr"
+	}
+	return o.Preset
+}
+
+// Validate checks that preset exists and template files are readable.
+func (o *Options) Validate() error {
+	if _, ok := Presets[o.preset()]; !ok {
+		return fmt.Errorf("unknown preset %q", o.Preset)
+	}
+	if o.TemplateDir != "" {
+		fi, err := os.Stat(o.TemplateDir)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return errors.New("template dir " + o.TemplateDir + " is not a directory")
+		}
+	}
+	if o.Template != "" {
+		if _, err := os.Stat(o.Template); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Generated returns the marker starting generated region name, or an
+// empty string without Markers.
+func (o *Options) Generated(name string) string {
+	if !o.Markers {
+		return ""
+	}
+	return BeginMarker(name)
+}
+
+// EndGenerated returns the marker ending generated region name, or an
+// empty string without Markers.
+func (o *Options) EndGenerated(name string) string {
+	if !o.Markers {
+		return ""
+	}
+	return EndMarker(name)
+}
+
+// Templates returns the template set with "post", "comments", "comment"
+// and optionally "index" templates: preset ones, overridden by ones
+// from TemplateDir, and then by Template for posts. It returns nil if
+// options don't use templates.
+func (o *Options) Templates() (*template.Template, error) {
+	if !o.UsesTemplates() {
+		return nil, nil
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	t := template.New("").Funcs(Funcs).Funcs(template.FuncMap{
+		"mf2":          func() bool { return o.MF2 },
+		"generated":    o.Generated,
+		"endGenerated": o.EndGenerated,
+	})
+	set := Presets[o.preset()]
+	// Sort names for deterministic errors.
+	names := make([]string, 0, len(set))
+	for k := range set {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if _, err := t.New(k).Parse(set[k]); err != nil {
+			return nil, fmt.Errorf("preset %s: %s", o.preset(), err)
+		}
+	}
+	readTemplate := func(name, filename string) error {
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		_, err = t.New(name).Parse(string(b))
+		return err
+	}
+	if o.TemplateDir != "" {
+		files, err := filepath.Glob(filepath.Join(o.TemplateDir, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if err := readTemplate(strings.TrimSuffix(filepath.Base(f), ".tmpl"), f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if o.Template != "" {
+		if err := readTemplate("post", o.Template); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,114 @@
+// Package output writes kkr posts: front matter, comment markup and
+// templates for other generators.
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// FrontMatter returns front matter fields of entry with quoted values,
+// except for date and markup. Category is the last category. Empty
+// values and unchanged tags are written as they were in export.
+func FrontMatter(e *mt.Entry) map[string]string {
+	h := make(map[string]string)
+	// Empty values are kept if they were in export.
+	str := func(key, mtKey, value string) {
+		if raw, ok := e.Header[mtKey]; value != "" || ok && raw == "" {
+			h[key] = strconv.Quote(value)
+		}
+	}
+	str("author", "AUTHOR", e.Author)
+	str("title", "TITLE", e.Title)
+	str("status", "STATUS", e.Status)
+	str("primary_category", "PRIMARY CATEGORY", e.PrimaryCategory)
+	category := ""
+	if len(e.Categories) > 0 {
+		category = e.Categories[len(e.Categories)-1]
+	}
+	str("category", "CATEGORY", category)
+	// Tags are written as in export unless they were changed.
+	tags := mt.JoinTags(e.Tags)
+	if raw, ok := e.Header["TAGS"]; ok && equalTags(mt.ParseTags(raw), e.Tags) {
+		tags = raw
+	}
+	str("tags", "TAGS", tags)
+	if !e.Date.IsZero() {
+		h["date"] = e.Date.Format("2006-01-02 15:04:05 -07:00")
+	}
+	if e.Markup != "" {
+		h["markup"] = e.Markup
+	}
+	return h
+}
+
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteFrontMatter writes header, which has quoted values, as front
+// matter with keys in sorted order.
+func WriteFrontMatter(buf *bytes.Buffer, header map[string]string) {
+	lines := make([]string, 0, len(header))
+	for k, v := range header {
+		lines = append(lines, k+": "+v+"\n")
+	}
+	sort.Strings(lines)
+	buf.WriteString("---\n")
+	for _, v := range lines {
+		buf.WriteString(v)
+	}
+	buf.WriteString("---\n")
+}
+
+// WriteComment writes comment markup of the default output. With mf2,
+// it adds microformats2 classes.
+func WriteComment(buf *bytes.Buffer, c *mt.Comment, mf2 bool) {
+	if mf2 {
+		writeCommentMF2(buf, c)
+		return
+	}
+	buf.WriteString("<div class=\"comment\">\n")
+	buf.WriteString("<div class=\"comment-header\">\n")
+	buf.WriteString("<span class=\"comment-author\">")
+	if c.URL != "" {
+		fmt.Fprintf(buf, "<a rel=\"nofollow\" href=\"%s\">%s</a>", c.URL, c.Author)
+	} else {
+		buf.WriteString(c.Author)
+	}
+	fmt.Fprintf(buf, "</span> <span class=\"comment-date\">%s</span>\n", c.Date.Format("2006-01-02 15:04"))
+	buf.WriteString("</div>\n")
+	buf.WriteString("<div class=\"comment-body\">\n")
+	buf.WriteString(c.Content)
+	buf.WriteString("</div>\n")
+	buf.WriteString("</div>\n")
+}
+
+func writeCommentMF2(buf *bytes.Buffer, c *mt.Comment) {
+	buf.WriteString("<div class=\"comment p-comment h-cite\">\n")
+	buf.WriteString("<div class=\"comment-header\">\n")
+	buf.WriteString("<span class=\"comment-author p-author h-card\">")
+	if c.URL != "" {
+		fmt.Fprintf(buf, "<a rel=\"nofollow\" class=\"u-url p-name\" href=\"%s\">%s</a>", c.URL, c.Author)
+	} else {
+		fmt.Fprintf(buf, "<span class=\"p-name\">%s</span>", c.Author)
+	}
+	fmt.Fprintf(buf, "</span> <span class=\"comment-date dt-published\">%s</span>\n", c.Date.Format("2006-01-02 15:04"))
+	buf.WriteString("</div>\n")
+	buf.WriteString("<div class=\"comment-body e-content\">\n")
+	buf.WriteString(c.Content)
+	buf.WriteString("</div>\n")
+	buf.WriteString("</div>\n")
+}
@@ -0,0 +1,98 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// TestFrontMatterGolden checks that front matter is the same as it was
+// before entries had typed fields: tags as in export and empty values
+// kept.
+func TestFrontMatterGolden(t *testing.T) {
+	export := `AUTHOR: 
+TITLE: Tags test
+BASENAME: tags_test
+STATUS: Publish
+ALLOW COMMENTS: 1
+CONVERT BREAKS: 0
+PRIMARY CATEGORY: 
+CATEGORY: News
+CATEGORY: 
+TAGS: foo, "bar baz", qux
+DATE: 01/02/2006 03:04:05 PM
+-----
+BODY:
+<p>Hi</p>
+-----
+--------
+AUTHOR: me
+TITLE: 
+BASENAME: empty
+STATUS: Draft
+CONVERT BREAKS: textile_2
+TAGS: 
+DATE: 01/03/2006 03:04:05 PM
+-----
+BODY:
+Hello
+-----
+--------
+AUTHOR: me
+TITLE: Changed
+DATE: 01/04/2006 03:04:05 PM
+TAGS: foo, bar
+-----
+BODY:
+-----
+--------
+`
+	golden := []string{`---
+author: ""
+category: ""
+date: 2006-01-02 15:04:05 +00:00
+primary_category: ""
+status: "Publish"
+tags: "foo, \"bar baz\", qux"
+title: "Tags test"
+---
+`, `---
+author: "me"
+date: 2006-01-03 15:04:05 +00:00
+markup: textile
+status: "Draft"
+tags: ""
+title: ""
+---
+`, `---
+date: 2006-01-04 15:04:05 +00:00
+tags: "bar,\"new tag\""
+title: "Changed"
+---
+`}
+	p := mt.NewParser(bytes.NewReader([]byte(export)))
+	for i := 0; ; i++ {
+		e, err := p.Next()
+		if err == io.EOF {
+			if i != len(golden) {
+				t.Fatalf("parsed %d entries", i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 2 {
+			// Changed values are written as they are.
+			e.Author = ""
+			e.Tags = []string{"bar", "new tag"}
+		}
+		var buf bytes.Buffer
+		WriteFrontMatter(&buf, FrontMatter(e))
+		if buf.String() != golden[i] {
+			t.Errorf("entry %d:\n%s\nwant:\n%s", i, buf.String(), golden[i])
+		}
+	}
+}
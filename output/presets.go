@@ -0,0 +1,130 @@
+package output
+
+// articleComment is a comment template for HTML5 presets.
+const articleComment = `<article class="comment{{if .IsAuthor}} by-author{{end}}{{if mf2}} p-comment h-cite{{end}}" id="comment-{{.Index}}">
+<header>{{if mf2}}<span class="p-author h-card">{{end}}{{if .URL}}<a rel="nofollow"{{if mf2}} class="u-url p-name"{{end}} href="{{safeURL .URL}}">{{html .Author}}</a>{{else if mf2}}<span class="p-name">{{html .Author}}</span>{{else}}{{html .Author}}{{end}}{{if mf2}}</span>{{end}}, <time{{if mf2}} class="dt-published"{{end}} datetime="{{.Date.Format "2006-01-02T15:04:05Z07:00"}}">{{.Date.Format "January 2, 2006"}}</time></header>
+{{if mf2}}<div class="e-content">
+{{end}}{{.SafeContent}}{{if mf2}}</div>
+{{end}}</article>
+`
+
+// Presets are built-in template sets. Each set defines "post",
+// "comments" (comment block) and "comment" (single comment) templates;
+// "index" is optional. The "html" preset makes standalone HTML5
+// documents instead of files with front matter.
+var Presets = map[string]map[string]string{
+	"kkr": {
+		"post": `---
+{{range $k, $v := .Header}}{{$k}}: {{$v}}
+{{end}}---
+{{.Body}}{{generated "comments"}}{{template "comments" .}}{{endGenerated "comments"}}`,
+		"comments": `{{if .Comments}}
+
+<div class="comments">
+{{range .Comments}}{{template "comment" .}}{{end}}</div>
+{{end}}`,
+		"comment": `<div class="comment{{if mf2}} p-comment h-cite{{end}}">
+<div class="comment-header">
+<span class="comment-author{{if mf2}} p-author h-card{{end}}">{{if .URL}}<a rel="nofollow"{{if mf2}} class="u-url p-name"{{end}} href="{{safeURL .URL}}">{{html .Author}}</a>{{else if mf2}}<span class="p-name">{{html .Author}}</span>{{else}}{{html .Author}}{{end}}</span> <span class="comment-date{{if mf2}} dt-published{{end}}">{{.Date.Format "2006-01-02 15:04"}}</span>
+</div>
+<div class="comment-body{{if mf2}} e-content{{end}}">
+{{.SafeContent}}</div>
+</div>
+`,
+	},
+	"jekyll": {
+		"post": `---
+layout: post
+title: {{quote .Title}}
+date: {{.Date.Format "2006-01-02 15:04:05 -0700"}}
+{{if .Author}}author: {{quote .Author}}
+{{end}}{{with .Header.description}}description: {{.}}
+{{end}}{{if .Categories}}categories: [{{range $i, $c := .Categories}}{{if $i}}, {{end}}{{quote $c}}{{end}}]
+{{end}}{{if .Tags}}tags: [{{range $i, $t := .Tags}}{{if $i}}, {{end}}{{quote $t}}{{end}}]
+{{end}}{{if eq .Status "Draft"}}published: false
+{{end}}---
+{{.Body}}{{generated "comments"}}{{template "comments" .}}{{endGenerated "comments"}}`,
+		"comments": `{{if .Comments}}
+<section class="comments">
+{{range .Comments}}{{template "comment" .}}{{end}}</section>
+{{end}}`,
+		"comment": articleComment,
+	},
+	"hugo": {
+		"post": `---
+title: {{quote .Title}}
+date: {{.Date.Format "2006-01-02T15:04:05Z07:00"}}
+{{if .Author}}author: {{quote .Author}}
+{{end}}{{with .Header.description}}description: {{.}}
+{{end}}{{with .Header.translationKey}}translationKey: {{.}}
+{{end}}{{if .Categories}}categories: [{{range $i, $c := .Categories}}{{if $i}}, {{end}}{{quote $c}}{{end}}]
+{{end}}{{if .Tags}}tags: [{{range $i, $t := .Tags}}{{if $i}}, {{end}}{{quote $t}}{{end}}]
+{{end}}{{if eq .Status "Draft"}}draft: true
+{{end}}---
+{{.Body}}{{generated "comments"}}{{template "comments" .}}{{endGenerated "comments"}}`,
+		"comments": `{{if .Comments}}
+<section class="comments">
+{{range .Comments}}{{template "comment" .}}{{end}}</section>
+{{end}}`,
+		"comment": articleComment,
+	},
+	"eleventy": {
+		"post": `---
+layout: post.njk
+title: {{quote .Title}}
+date: {{.Date.Format "2006-01-02T15:04:05Z07:00"}}
+{{if .Author}}author: {{quote .Author}}
+{{end}}{{with .Header.description}}description: {{.}}
+{{end}}{{if .Categories}}categories: [{{range $i, $c := .Categories}}{{if $i}}, {{end}}{{quote $c}}{{end}}]
+{{end}}{{if .Tags}}tags: [{{range $i, $t := .Tags}}{{if $i}}, {{end}}{{quote $t}}{{end}}]
+{{end}}{{if eq .Status "Draft"}}permalink: false
+eleventyExcludeFromCollections: true
+{{end}}---
+{{.Body}}{{generated "comments"}}{{template "comments" .}}{{endGenerated "comments"}}`,
+		"comments": `{{if .Comments}}
+<section class="comments">
+{{range .Comments}}{{template "comment" .}}{{end}}</section>
+{{end}}`,
+		"comment": articleComment,
+	},
+	"html": {
+		"post": `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{html .Title}}</title>
+{{if .CSS}}<link rel="stylesheet" href="{{.CSS}}">
+{{end}}</head>
+<body>
+<article{{if mf2}} class="h-entry"{{end}}>
+<h1{{if mf2}} class="p-name"{{end}}>{{html .Title}}</h1>
+<p class="date"><time{{if mf2}} class="dt-published"{{end}} datetime="{{.Date.Format "2006-01-02T15:04:05Z07:00"}}">{{.Date.Format "January 2, 2006"}}</time>{{if .Author}} by <span{{if mf2}} class="p-author h-card"{{end}}>{{html .Author}}</span>{{end}}</p>
+{{if mf2}}<div class="e-content">
+{{end}}{{.Body}}{{if mf2}}</div>
+{{end}}{{generated "comments"}}{{template "comments" .}}{{endGenerated "comments"}}</article>
+<p><a href="index.html">Archive</a></p>
+</body>
+</html>
+`,
+		"comments": `{{if .Comments}}<section class="comments">
+{{range .Comments}}{{template "comment" .}}{{end}}</section>
+{{end}}`,
+		"comment": articleComment,
+		"index": `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Archive</title>
+{{if .CSS}}<link rel="stylesheet" href="{{.CSS}}">
+{{end}}</head>
+<body>
+<h1>Archive</h1>
+{{with .Meta}}{{if .Count}}<p>{{.Count}} posts from {{.First.Format "January 2006"}} to {{.Last.Format "January 2006"}}.</p>
+{{end}}{{end}}<ul{{if mf2}} class="h-feed"{{end}}>
+{{range .Entries}}<li{{if mf2}} class="h-entry"{{end}}><time{{if mf2}} class="dt-published"{{end}} datetime="{{.Date.Format "2006-01-02T15:04:05Z07:00"}}">{{.Date.Format "2006-01-02"}}</time> <a{{if mf2}} class="u-url p-name"{{end}} href="{{.Filename}}">{{html .Title}}</a></li>
+{{end}}</ul>
+</body>
+</html>
+`,
+	},
+}
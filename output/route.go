@@ -0,0 +1,98 @@
+package output
+
+import (
+	"path/filepath"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// Route is where and how entries with a category or tag are written.
+type Route struct {
+	Category string // match entries with category
+	Tag      string // match entries with tag
+	Dir      string // subdirectory of output directory
+	Prefix   string // date layout of file name prefix, none if empty
+	Ext      string // file extension, Options.Ext if empty
+	Preset   string // output preset, Options.Preset if empty
+}
+
+// Matches reports whether entry has route category (or primary
+// category) or tag.
+func (r *Route) Matches(e *mt.Entry) bool {
+	if r.Category != "" {
+		return contains(e.Categories, r.Category) || e.PrimaryCategory == r.Category
+	}
+	return r.Tag != "" && contains(e.Tags, r.Tag)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// FilePrefix returns output file name prefix for entry: route
+// directory and formatted date.
+func (r *Route) FilePrefix(e *mt.Entry) string {
+	prefix := e.Date.Format(r.Prefix)
+	if r.Dir != "." && r.Dir != "" {
+		prefix = filepath.ToSlash(r.Dir) + "/" + prefix
+	}
+	return prefix
+}
+
+// defaultRoute returns the route of entries not matching Routes.
+func (o *Options) defaultRoute() *Route {
+	prefix := o.DatePrefix
+	if prefix == "" {
+		prefix = "2006-01-02-"
+	}
+	return &Route{Dir: o.PostDir, Prefix: prefix, Ext: o.ext(), Preset: o.Preset}
+}
+
+func (o *Options) ext() string {
+	if o.Ext == "" {
+		return ".html"
+	}
+	return o.Ext
+}
+
+// RouteFor returns the first of Routes matching entry, or the default
+// route with PostDir, DatePrefix, Ext and Preset of options.
+func (o *Options) RouteFor(e *mt.Entry) *Route {
+	if i := o.routeIndex(e); i >= 0 {
+		return o.Routes[i]
+	}
+	return o.defaultRoute()
+}
+
+// routeIndex returns index of route of entry in Routes, or -1 for the
+// default route.
+func (o *Options) routeIndex(e *mt.Entry) int {
+	for i, r := range o.Routes {
+		if r.Matches(e) {
+			return i
+		}
+	}
+	return -1
+}
+
+// routeExt returns file extension of route.
+func (o *Options) routeExt(r *Route) string {
+	if r.Ext == "" {
+		return o.ext()
+	}
+	return r.Ext
+}
+
+// routeOptions returns options for writing entries of route.
+func (o *Options) routeOptions(r *Route) Options {
+	ro := *o
+	if r.Preset != "" {
+		ro.Preset = r.Preset
+	}
+	return ro
+}
@@ -0,0 +1,287 @@
+package output
+
+import (
+	"bytes"
+	"html"
+	"sort"
+	"strings"
+)
+
+// Sanitizer removes elements and attributes it doesn't allow from
+// HTML. It tokenizes HTML as browsers do, so that broken or nested
+// markup can't smuggle tags past it. The zero Sanitizer keeps HTML as
+// is.
+type Sanitizer struct {
+	// Elements maps allowed elements to their allowed attributes, in
+	// addition to Attrs. Other elements are removed, keeping their
+	// content, except for contents of elements such as script and
+	// style. Comments are removed.
+	Elements map[string][]string
+	// Attrs are attributes allowed on all elements.
+	Attrs []string
+	// Schemes are allowed URL schemes of href, src, cite, poster and
+	// srcset. Other URLs are replaced with "#"; relative URLs are
+	// kept.
+	Schemes []string
+}
+
+// Publish is the sanitizer for publishing: it keeps text formatting,
+// links, images, media and tables.
+var Publish = Sanitizer{
+	Elements: map[string][]string{
+		"a": {"href", "name", "rel"}, "abbr": nil, "acronym": nil,
+		"address": nil, "b": nil, "bdi": nil, "bdo": nil, "big": nil,
+		"blockquote": {"cite"}, "br": nil, "caption": nil, "center": nil,
+		"cite": nil, "code": nil, "col": {"span"}, "colgroup": {"span"},
+		"dd": nil, "del": {"cite", "datetime"}, "details": {"open"},
+		"dfn": nil, "div": nil, "dl": nil, "dt": nil, "em": nil,
+		"figcaption": nil, "figure": nil, "h1": nil, "h2": nil, "h3": nil,
+		"h4": nil, "h5": nil, "h6": nil, "hr": nil, "i": nil,
+		"img": {"src", "srcset", "alt", "width", "height", "loading"},
+		"ins": {"cite", "datetime"}, "kbd": nil, "li": {"value"},
+		"mark": nil, "ol": {"start", "type", "reversed"}, "p": nil,
+		"picture": nil, "pre": nil, "q": {"cite"}, "rp": nil, "rt": nil,
+		"ruby": nil, "s": nil, "samp": nil, "small": nil, "span": nil,
+		"strike": nil, "strong": nil, "sub": nil, "summary": nil, "sup": nil,
+		"table": nil, "tbody": nil, "td": {"colspan", "rowspan"},
+		"tfoot": nil, "th": {"colspan", "rowspan", "scope"}, "thead": nil,
+		"time": {"datetime"}, "tr": nil, "tt": nil, "u": nil, "ul": nil,
+		"var": nil, "wbr": nil,
+		"audio":  {"src", "controls", "loop", "muted", "preload"},
+		"video":  {"src", "controls", "loop", "muted", "preload", "poster", "width", "height"},
+		"source": {"src", "srcset", "type", "media"},
+		"track":  {"src", "kind", "srclang", "label", "default"},
+	},
+	Attrs:   []string{"class", "id", "title", "lang", "dir"},
+	Schemes: []string{"http", "https", "mailto"},
+}
+
+// rawTextElements are elements whose content isn't markup, so it's
+// removed with them.
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true,
+	"xmp": true, "iframe": true, "noembed": true, "noframes": true,
+	"noscript": true, "plaintext": true,
+}
+
+// urlAttrs are attributes with URLs, checked for schemes.
+var urlAttrs = map[string]bool{"href": true, "src": true, "cite": true, "poster": true, "srcset": true}
+
+type htmlAttr struct{ name, value string }
+
+// Apply sanitizes html, calling note, if not nil, with the number of
+// removed items of each kind, e.g. "script elements".
+func (s Sanitizer) Apply(src []byte, note func(kind string, n int)) []byte {
+	if s.Elements == nil {
+		return src
+	}
+	removed := make(map[string]int)
+	var out bytes.Buffer
+	for i := 0; i < len(src); {
+		c := src[i]
+		if c != '<' {
+			if c == '>' {
+				out.WriteString("&gt;")
+			} else {
+				out.WriteByte(c)
+			}
+			i++
+			continue
+		}
+		rest := src[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			i += skipPast(rest[4:], "-->") + 4
+			removed["comments"]++
+		case len(rest) > 1 && (rest[1] == '!' || rest[1] == '?'):
+			// Doctypes, CDATA and processing instructions.
+			i += skipPast(rest, ">")
+			removed["comments"]++
+		case len(rest) > 2 && rest[1] == '/' && isASCIILetter(rest[2]):
+			name, _, _, n := parseTag(rest[2:])
+			i += n + 2
+			if _, ok := s.Elements[name]; ok {
+				out.WriteString("</" + name + ">")
+			}
+		case len(rest) > 1 && isASCIILetter(rest[1]):
+			name, attrs, selfClosing, n := parseTag(rest[1:])
+			i += n + 1
+			allowed, ok := s.Elements[name]
+			if !ok {
+				removed[name+" elements"]++
+				if rawTextElements[name] {
+					i += skipRawText(src[i:], name)
+				}
+				continue
+			}
+			out.WriteString("<" + name)
+			for _, a := range attrs {
+				if !contains(allowed, a.name) && !contains(s.Attrs, a.name) {
+					removed[a.name+" attributes"]++
+					continue
+				}
+				if urlAttrs[a.name] && !s.safeURLs(a.name, a.value) {
+					a.value = "#"
+					removed["unsafe URLs"]++
+				}
+				out.WriteString(" " + a.name)
+				if a.value != "" {
+					out.WriteString(`="` + html.EscapeString(a.value) + `"`)
+				}
+			}
+			if selfClosing {
+				out.WriteString(" /")
+			}
+			out.WriteByte('>')
+		default:
+			out.WriteString("&lt;")
+			i++
+		}
+	}
+	if note != nil {
+		kinds := make([]string, 0, len(removed))
+		for k := range removed {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		for _, k := range kinds {
+			note(k, removed[k])
+		}
+	}
+	return out.Bytes()
+}
+
+func isASCIILetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isHTMLSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r' }
+
+// skipPast returns the length of b up to and including end, or of the
+// whole b if there's no end.
+func skipPast(b []byte, end string) int {
+	if i := bytes.Index(b, []byte(end)); i >= 0 {
+		return i + len(end)
+	}
+	return len(b)
+}
+
+// skipRawText returns the length of content of raw text element name
+// in b, including its end tag.
+func skipRawText(b []byte, name string) int {
+	lower := bytes.ToLower(b)
+	for i := 0; ; {
+		j := bytes.Index(lower[i:], []byte("</"+name))
+		if j < 0 {
+			return len(b)
+		}
+		i += j + 2 + len(name)
+		if i == len(b) || isHTMLSpace(b[i]) || b[i] == '/' || b[i] == '>' {
+			return i + skipPast(b[i:], ">")
+		}
+	}
+}
+
+// parseTag parses tag name and attributes after "<" or "</" as
+// browsers do, returning lowercase names, decoded values and the
+// length of the tag including ">". Repeated attributes are dropped.
+func parseTag(b []byte) (name string, attrs []htmlAttr, selfClosing bool, n int) {
+	i := 0
+	for i < len(b) && !isHTMLSpace(b[i]) && b[i] != '/' && b[i] != '>' {
+		i++
+	}
+	name = strings.ToLower(string(b[:i]))
+	seen := make(map[string]bool)
+	for i < len(b) {
+		selfClosing = false
+		for i < len(b) && (isHTMLSpace(b[i]) || b[i] == '/') {
+			selfClosing = b[i] == '/'
+			i++
+		}
+		if i == len(b) {
+			break
+		}
+		if b[i] == '>' {
+			return name, attrs, selfClosing, i + 1
+		}
+		selfClosing = false
+		start := i
+		i++ // a name may start with "="
+		for i < len(b) && !isHTMLSpace(b[i]) && b[i] != '/' && b[i] != '>' && b[i] != '=' {
+			i++
+		}
+		a := htmlAttr{name: strings.ToLower(string(b[start:i]))}
+		for i < len(b) && isHTMLSpace(b[i]) {
+			i++
+		}
+		if i < len(b) && b[i] == '=' {
+			i++
+			for i < len(b) && isHTMLSpace(b[i]) {
+				i++
+			}
+			start := i
+			if i < len(b) && (b[i] == '"' || b[i] == '\'') {
+				q := b[i]
+				i++
+				for i < len(b) && b[i] != q {
+					i++
+				}
+				a.value = string(b[start+1 : i])
+				if i < len(b) {
+					i++
+				}
+			} else {
+				for i < len(b) && !isHTMLSpace(b[i]) && b[i] != '>' {
+					i++
+				}
+				a.value = string(b[start:i])
+			}
+			a.value = html.UnescapeString(a.value)
+		}
+		if !seen[a.name] {
+			seen[a.name] = true
+			attrs = append(attrs, a)
+		}
+	}
+	return name, attrs, selfClosing, len(b)
+}
+
+// safeURLs reports whether URLs of attribute, which are decoded, have
+// allowed schemes.
+func (s Sanitizer) safeURLs(name, value string) bool {
+	urls := []string{value}
+	if name == "srcset" {
+		urls = nil
+		for _, c := range strings.Split(value, ",") {
+			if f := strings.Fields(c); len(f) > 0 {
+				urls = append(urls, f[0])
+			}
+		}
+	}
+	for _, u := range urls {
+		if scheme := urlScheme(u); scheme != "" && !contains(s.Schemes, scheme) {
+			return false
+		}
+	}
+	return true
+}
+
+// urlScheme returns lowercase scheme of url, if any. Browsers ignore
+// leading spaces and control characters, and tabs and line breaks in
+// schemes.
+func urlScheme(url string) string {
+	i := strings.IndexAny(url, ":/?#")
+	if i < 0 || url[i] != ':' {
+		return ""
+	}
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, url[:i]))
+}
+
+// SanitizeHTML keeps only elements, attributes and URLs allowed by
+// Publish in html. It's the default Options.Sanitize.
+func SanitizeHTML(html string) string {
+	return string(Publish.Apply([]byte(html), nil))
+}
@@ -0,0 +1,53 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{`<p class="x">Hi <b>there</b><br/></p>`, `<p class="x">Hi <b>there</b><br /></p>`},
+		{`<a href='http://example.com/?a=1&amp;b=2' title=x>l</a>`, `<a href="http://example.com/?a=1&amp;b=2" title="x">l</a>`},
+		{`<svg/onload=alert(1)>`, ``},
+		{`<img src=x.png/onerror=alert(1)>`, `<img src="x.png/onerror=alert(1)">`},
+		{`<img/src="x.png"/onerror="alert(1)">`, `<img src="x.png">`},
+		{`<a href="jav&#x61;script:alert(1)">x</a>`, `<a href="#">x</a>`},
+		{`<a href="&#106;avascript&colon;alert(1)">x</a>`, `<a href="#">x</a>`},
+		{`<a href="java&#x09;script:alert(1)">x</a>`, `<a href="#">x</a>`},
+		{`<a href="javascript:alert('x y')">x</a>`, `<a href="#">x</a>`},
+		{`<a href=" JAVASCRIPT:alert(1)">x</a>`, `<a href="#">x</a>`},
+		{`<img srcset="a.png 1x, javascript:x 2x">`, `<img srcset="#">`},
+		{`<scr<script>ipt>alert(1)</script>`, `ipt&gt;alert(1)`},
+		{`<script>alert(1)</script >ok<SCRIPT src=x></SCRIPT>`, `ok`},
+		{`<style>p{}</style><textarea><img src=x></textarea>`, ``},
+		{`<iframe src="x"></iframe><object data="x"><embed src="x"></object>`, ``},
+		{`<form action="x"><input name=q></form><base href="x"><meta http-equiv=refresh>`, ``},
+		{`<!--[if IE]><script>alert(1)</script><![endif]--><p>x</p>`, `<p>x</p>`},
+		{`<p onclick="alert(1)" ONMOUSEOVER='x' style="color:red">x</p>`, `<p>x</p>`},
+		{`a < b > c <3 </ p>`, `a &lt; b &gt; c &lt;3 &lt;/ p&gt;`},
+		{`<video controls src="v.mp4" autoplay>`, `<video controls src="v.mp4">`},
+		{`<a href="x" href="javascript:y">`, `<a href="x">`},
+		{`<img src="x" alt="a &quot;b&quot; <c>"`, `<img src="x" alt="a &#34;b&#34; &lt;c&gt;">`},
+		{`<p title="unterminated`, `<p title="unterminated">`},
+	} {
+		if got := SanitizeHTML(tt.in); got != tt.want {
+			t.Errorf("SanitizeHTML(%q) =\n%q, want\n%q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizerNotes(t *testing.T) {
+	notes := make(map[string]int)
+	Publish.Apply([]byte(`<script>x</script><p onclick=x><a href="javascript:x">x</a><!-- c --><script></script>`), func(kind string, n int) {
+		notes[kind] = n
+	})
+	want := map[string]int{"script elements": 2, "onclick attributes": 1, "unsafe URLs": 1, "comments": 1}
+	if !reflect.DeepEqual(notes, want) {
+		t.Errorf("notes %v, want %v", notes, want)
+	}
+	var archive Sanitizer
+	if got := string(archive.Apply([]byte(`<script>x</script>`), nil)); got != `<script>x</script>` {
+		t.Errorf("zero Sanitizer changed HTML to %q", got)
+	}
+}
@@ -0,0 +1,72 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// MapSection maps lines of output file to lines of input.
+type MapSection struct {
+	Name        string `json:"name"`
+	InputStart  int    `json:"input_start"`
+	InputEnd    int    `json:"input_end"`
+	OutputStart int    `json:"output_start"`
+	OutputEnd   int    `json:"output_end"`
+}
+
+// SourceMap maps sections of output file to input lines. It's written
+// next to the file with .map.json extension.
+type SourceMap struct {
+	Input    string       `json:"input"`
+	Output   string       `json:"output"`
+	Sections []MapSection `json:"sections"`
+}
+
+// lineAt returns the line number at which the next write to buf starts.
+func lineAt(buf *bytes.Buffer) int {
+	return bytes.Count(buf.Bytes(), []byte("\n")) + 1
+}
+
+// add maps input section name to output lines from start to the last
+// line written to buf.
+func (m *SourceMap) add(src []mt.SrcRange, name string, start int, buf *bytes.Buffer) {
+	if m == nil {
+		return
+	}
+	for _, r := range src {
+		if r.Name == name {
+			m.Sections = append(m.Sections, MapSection{name, r.Start, r.End, start, lineAt(buf) - 1})
+		}
+	}
+}
+
+func (m *SourceMap) addComment(c *mt.Comment, start int, buf *bytes.Buffer) {
+	if m == nil {
+		return
+	}
+	m.Sections = append(m.Sections, MapSection{"comment", c.SrcStart, c.SrcEnd, start, lineAt(buf) - 1})
+}
+
+// Shift moves output lines of sections after the header by n lines,
+// which were added to the header.
+func (m *SourceMap) Shift(n int) {
+	for i := range m.Sections {
+		if m.Sections[i].Name != "header" {
+			m.Sections[i].OutputStart += n
+		}
+		m.Sections[i].OutputEnd += n
+	}
+}
+
+// Write writes source map of output file in dir.
+func (m *SourceMap) Write(dir string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, m.Output+".map.json"), append(b, '\n'), 0644)
+}
@@ -0,0 +1,47 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// SpillError is returned for entries with sections that mt.Parser saved
+// into files for being over MaxSection. They can't go through
+// conversion passes, which work on sections in memory, so they fail.
+type SpillError struct {
+	Filename string
+	Sections []string // names and sizes, e.g. "body of 1200 bytes"
+}
+
+func (e *SpillError) Error() string {
+	return fmt.Sprintf("%s: %s over the section size limit", e.Filename, strings.Join(e.Sections, ", "))
+}
+
+// spillError returns SpillError of entry src named filename.
+func spillError(filename string, src *mt.Entry) error {
+	names := make([]string, 0, len(src.Spilled))
+	for name := range src.Spilled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	e := &SpillError{Filename: filename}
+	for _, name := range names {
+		size := int64(-1)
+		if fi, err := os.Stat(src.Spilled[name]); err == nil {
+			size = fi.Size()
+		}
+		e.Sections = append(e.Sections, fmt.Sprintf("%s of %d bytes", name, size))
+	}
+	return e
+}
+
+// removeSpilled removes files of sections of entry saved by mt.Parser.
+func removeSpilled(e *mt.Entry) {
+	for _, file := range e.Spilled {
+		os.Remove(file)
+	}
+}
@@ -0,0 +1,101 @@
+package output
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// Funcs are functions available to templates, in addition to mf2,
+// generated and endGenerated set by Options.
+var Funcs = template.FuncMap{
+	"quote": strconv.Quote,
+	"unquote": func(s string) string {
+		u, err := strconv.Unquote(s)
+		if err != nil {
+			return s
+		}
+		return u
+	},
+	"join":    strings.Join,
+	"safeURL": SafeURL,
+}
+
+// SafeURL returns url escaped for an attribute value, or "#" if it has
+// a scheme other than http, https or mailto, e.g. javascript:.
+func SafeURL(url string) string {
+	u := strings.TrimSpace(url)
+	switch urlScheme(u) {
+	case "", "http", "https", "mailto":
+		return template.HTMLEscapeString(u)
+	}
+	return "#"
+}
+
+// Post is passed to the "post" template.
+type Post struct {
+	Filename   string
+	UUID       string
+	Header     map[string]string // front matter values, as in default output
+	Title      string
+	Author     string
+	Status     string
+	Date       time.Time
+	Categories []string
+	Tags       []string
+	Keywords   []string
+	Body       string
+	Comments   []*Comment
+	CSS        string // stylesheet URL
+}
+
+// Comment is a comment with fields computed for templates.
+type Comment struct {
+	*mt.Comment
+	Index        int    // 1-based position in the comment list
+	GravatarHash string // MD5 of the normalized email
+	SafeContent  string // content sanitized by Options.Sanitize, see SanitizeHTML
+	RelativeDate string // time since the post, e.g. "2 days later"
+	IsAuthor     bool   // written by the post author
+}
+
+// GravatarHash returns Gravatar hash of email.
+func GravatarHash(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return ""
+	}
+	sum := md5.Sum([]byte(email))
+	return hex.EncodeToString(sum[:])
+}
+
+// RelativeDate describes how long after the post the comment was made.
+func RelativeDate(post, c time.Time) string {
+	d := c.Sub(post)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit + " later"
+		}
+		return fmt.Sprintf("%d %ss later", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return "shortly after"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	case d < 30*24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d < 365*24*time.Hour:
+		return plural(int(d/(30*24*time.Hour)), "month")
+	default:
+		return plural(int(d/(365*24*time.Hour)), "year")
+	}
+}
@@ -0,0 +1,84 @@
+package output
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+func TestCommentTemplatesEscape(t *testing.T) {
+	c := &Comment{
+		Comment: &mt.Comment{
+			Author:  `<script>alert(1)</script>`,
+			URL:     `http://example.com/" onmouseover="alert(1)`,
+			Date:    time.Date(2004, 1, 2, 3, 4, 5, 0, time.UTC),
+			Content: `<p onclick="alert(1)">raw</p>`,
+		},
+		Index:       1,
+		SafeContent: "<p>safe</p>",
+	}
+	for _, preset := range []string{"kkr", "html"} {
+		for _, mf2 := range []bool{false, true} {
+			o := &Options{Preset: preset, MF2: mf2}
+			tmpl, err := o.Templates()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			if err := tmpl.ExecuteTemplate(&b, "comment", c); err != nil {
+				t.Fatal(err)
+			}
+			out := b.String()
+			for _, bad := range []string{"<script>", `" onmouseover`, "onclick", "raw"} {
+				if strings.Contains(out, bad) {
+					t.Errorf("%s (mf2 %v): output has %q:\n%s", preset, mf2, bad, out)
+				}
+			}
+			for _, want := range []string{"&lt;script&gt;", "<p>safe</p>"} {
+				if !strings.Contains(out, want) {
+					t.Errorf("%s (mf2 %v): output lacks %q:\n%s", preset, mf2, want, out)
+				}
+			}
+		}
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"http://example.com/?a=1&b=2", "http://example.com/?a=1&amp;b=2"},
+		{"HTTPS://example.com/", "HTTPS://example.com/"},
+		{"mailto:a@example.com", "mailto:a@example.com"},
+		{"/relative/path:x", "/relative/path:x"},
+		{"example.com", "example.com"},
+		{"javascript:alert(1)", "#"},
+		{" JavaScript:alert(1)", "#"},
+		{"java\tscript:alert(1)", "#"},
+		{"data:text/html,x", "#"},
+	} {
+		if got := SafeURL(tt.in); got != tt.want {
+			t.Errorf("SafeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewPostSanitizes(t *testing.T) {
+	e := &Entry{Source: &mt.Entry{Comments: []*mt.Comment{{
+		Content: `<p onclick="alert(1)">hi<script>alert(2)</script> <a href="javascript:alert(3)">x</a></p>`,
+	}}}}
+	var o Options
+	got := o.NewPost(e).Comments[0].SafeContent
+	if want := `<p>hi <a href="#">x</a></p>`; got != want {
+		t.Errorf("SafeContent = %q, want %q", got, want)
+	}
+	o.Sanitize = strings.ToUpper
+	if got := o.NewPost(e).Comments[0].SafeContent; !strings.HasPrefix(got, "<P ONCLICK") {
+		t.Errorf("SafeContent with Sanitize = %q", got)
+	}
+	o.Sanitize = nil
+	e.Source.Comments[0].Content = `<svg/onload=alert(1)><a href="jav&#x61;script:x">y</a><scr<script>ipt>z</script><iframe src=x></iframe>`
+	if got, want := o.NewPost(e).Comments[0].SafeContent, `<a href="#">y</a>ipt&gt;z`; got != want {
+		t.Errorf("SafeContent = %q, want %q", got, want)
+	}
+}
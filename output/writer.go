@@ -0,0 +1,274 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/dchest/mt2kkr/mt"
+)
+
+// Entry is a converted entry.
+type Entry struct {
+	Filename string            // output file name
+	Slug     string            // file name without prefix and extension
+	Route    *Route            // see Options.RouteFor
+	Header   map[string]string // front matter with quoted values
+	Content  []byte            // converted body
+	Source   *mt.Entry         // parsed entry, for date, comments, etc.
+	Data     interface{}       // set by Options.New, e.g. for passes
+}
+
+// EntryWriter is an output target of converted entries.
+type EntryWriter interface {
+	WriteEntry(e *Entry) error
+	Close() error
+}
+
+// WriteError is an error of writing entry into file.
+type WriteError struct {
+	Filename string
+	Err      error
+}
+
+func (e *WriteError) Error() string { return e.Filename + ": " + e.Err.Error() }
+
+func (e *WriteError) Unwrap() error { return e.Err }
+
+// FileWriter writes entries into files in directory.
+type FileWriter struct {
+	Dir     string
+	Options Options
+	// Written are names of files written so far, e.g. to report what
+	// was converted before cancellation or an error.
+	Written []string
+	// Posts are template data of written entries, without bodies, for
+	// the "index" template if there's one. Unlisted entries are left
+	// out.
+	Posts []*Post
+	// templates are of Routes and, the last, of the default route.
+	templates []*template.Template
+}
+
+// NewFileWriter returns a writer of entries into dir with options.
+func NewFileWriter(dir string, opts Options) (*FileWriter, error) {
+	w := &FileWriter{Dir: dir, Options: opts}
+	for i, r := range append(opts.Routes, opts.defaultRoute()) {
+		ro := opts.routeOptions(r)
+		t, err := ro.Templates()
+		if err != nil {
+			if i < len(opts.Routes) {
+				return nil, fmt.Errorf("route %d: %s", i+1, err)
+			}
+			return nil, err
+		}
+		w.templates = append(w.templates, t)
+	}
+	return w, nil
+}
+
+// Render returns contents of output file of entry.
+func (w *FileWriter) Render(e *Entry) ([]byte, error) {
+	b, _, err := w.render(e)
+	return b, err
+}
+
+func (w *FileWriter) render(e *Entry) ([]byte, *SourceMap, error) {
+	o := &w.Options
+	var smap *SourceMap
+	if o.SourceMap {
+		input := o.Input
+		if input == "" {
+			input = "-"
+		}
+		smap = &SourceMap{Input: input, Output: e.Filename}
+	}
+	i := o.routeIndex(e.Source)
+	route := o.defaultRoute()
+	if i >= 0 {
+		route = o.Routes[i]
+	} else {
+		i = len(o.Routes)
+	}
+	var buf bytes.Buffer
+	if t := w.templates[i]; t != nil {
+		ro := o.routeOptions(route)
+		data := ro.NewPost(e)
+		if err := t.ExecuteTemplate(&buf, "post", data); err != nil {
+			return nil, nil, err
+		}
+		if index := w.templates[len(o.Routes)]; index != nil && index.Lookup("index") != nil && !o.Unlisted(e.Source) {
+			data.Body = ""
+			w.Posts = append(w.Posts, data)
+		}
+		return buf.Bytes(), smap, nil
+	}
+	o.format(&buf, e, smap)
+	return buf.Bytes(), smap, nil
+}
+
+// WriteEntry writes entry into file named e.Filename, which must be
+// local to Dir. Errors are *WriteError.
+func (w *FileWriter) WriteEntry(e *Entry) error {
+	if err := w.writeEntry(e); err != nil {
+		return &WriteError{e.Filename, err}
+	}
+	w.Written = append(w.Written, e.Filename)
+	return nil
+}
+
+func (w *FileWriter) writeEntry(e *Entry) error {
+	if !filepath.IsLocal(filepath.FromSlash(e.Filename)) {
+		return errors.New("file name is outside of output directory")
+	}
+	o := &w.Options
+	out, smap, err := w.render(e)
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(w.Dir, filepath.FromSlash(e.Filename))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if o.Encrypts(e.Source) {
+		// Existing files can't be merged, and the source map would
+		// tell about the contents.
+		return os.WriteFile(file, o.Encrypter.Encrypt(out), 0644)
+	}
+	if o.Merge {
+		merged, err := mergeExisting(file, out)
+		if err != nil {
+			return err
+		}
+		if smap != nil {
+			smap.Shift(bytes.Count(merged, []byte("\n")) - bytes.Count(out, []byte("\n")))
+		}
+		out = merged
+	}
+	if o.Markers {
+		if out, err = replaceExisting(file, out); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(file, out, 0644); err != nil {
+		return err
+	}
+	if smap != nil {
+		return smap.Write(w.Dir)
+	}
+	return nil
+}
+
+// mergeExisting merges hand-added front matter fields of file, if it
+// exists, into out.
+func mergeExisting(file string, out []byte) ([]byte, error) {
+	existing, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return MergeFrontMatter(existing, out), nil
+}
+
+// replaceExisting replaces generated regions of file, if it exists,
+// with ones from out.
+func replaceExisting(file string, out []byte) ([]byte, error) {
+	existing, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ReplaceRegions(existing, out), nil
+}
+
+// Close does nothing.
+func (w *FileWriter) Close() error { return nil }
+
+// Format writes entry in the default output format: front matter, body
+// and comments block.
+func (o *Options) Format(buf *bytes.Buffer, e *Entry) {
+	o.format(buf, e, nil)
+}
+
+// format writes entry in the default format, mapping its lines to
+// input lines in smap if it's not nil.
+func (o *Options) format(buf *bytes.Buffer, e *Entry, smap *SourceMap) {
+	src := e.Source
+	WriteFrontMatter(buf, e.Header)
+	smap.add(src.Src, "header", 1, buf)
+	bodyStart := lineAt(buf)
+	buf.Write(e.Content)
+	smap.add(src.Src, "body", bodyStart, buf)
+	smap.add(src.Src, "extended body", bodyStart, buf)
+	buf.WriteString(o.Generated("comments"))
+	if len(src.Comments) > 0 {
+		buf.WriteString("\n\n<div class=\"comments\">\n")
+		for _, c := range src.Comments {
+			start := lineAt(buf)
+			WriteComment(buf, c, o.MF2)
+			smap.addComment(c, start, buf)
+		}
+		buf.WriteString("</div>\n")
+	}
+	buf.WriteString(o.EndGenerated("comments"))
+}
+
+// NewPost returns template data of entry.
+func (o *Options) NewPost(e *Entry) *Post {
+	str := func(key string) string {
+		s, _ := strconv.Unquote(e.Header[key])
+		return s
+	}
+	src := e.Source
+	comments := make([]*Comment, len(src.Comments))
+	for i, c := range src.Comments {
+		sanitize := o.Sanitize
+		if sanitize == nil {
+			sanitize = SanitizeHTML
+		}
+		safe := sanitize(c.Content)
+		comments[i] = &Comment{
+			Comment:      c,
+			Index:        i + 1,
+			GravatarHash: GravatarHash(c.Email),
+			SafeContent:  safe,
+			RelativeDate: RelativeDate(src.Date, c.Date),
+			IsAuthor:     o.isAuthor(c, str("author")),
+		}
+	}
+	return &Post{
+		Filename:   e.Filename,
+		UUID:       str("uuid"),
+		Header:     e.Header,
+		Title:      str("title"),
+		Author:     str("author"),
+		Status:     str("status"),
+		Date:       src.Date,
+		Categories: src.Categories,
+		Tags:       src.Tags,
+		Keywords:   src.Keywords,
+		Body:       string(e.Content),
+		Comments:   comments,
+		CSS:        o.CSS,
+	}
+}
+
+// isAuthor reports whether comment was written by post author: with
+// one of AuthorEmails or author's name.
+func (o *Options) isAuthor(c *mt.Comment, author string) bool {
+	for _, email := range o.AuthorEmails {
+		if strings.EqualFold(strings.TrimSpace(c.Email), email) {
+			return true
+		}
+	}
+	return author != "" && strings.EqualFold(strings.TrimSpace(c.Author), author)
+}
@@ -0,0 +1,1078 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Stderr is where print writes.
+var Stderr io.Writer = os.Stderr
+
+// Universe are predeclared names: None, True, False, builtin functions
+// len, str, repr, int, bool, list, tuple, dict, range, enumerate, zip,
+// sorted, reversed, min, max, any, all, type, hasattr, getattr, print
+// and fail, and module re with regexp functions search, findall, sub
+// and split in Go regexp syntax.
+var Universe map[string]Value
+
+func init() {
+	Universe = map[string]Value{
+		"None":  nil,
+		"True":  true,
+		"False": false,
+		"re": &Module{Name: "re", Members: map[string]Value{
+			"search":  builtin("re.search", reSearch),
+			"findall": builtin("re.findall", reFindAll),
+			"sub":     builtin("re.sub", reSub),
+			"split":   builtin("re.split", reSplit),
+		}},
+	}
+	for name, fn := range map[string]func(*interp, []Value, map[string]Value) (Value, error){
+		"len": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value
+			if err := unpackArgs(args, kwargs, "x", &x); err != nil {
+				return nil, err
+			}
+			switch x := x.(type) {
+			case string:
+				return len(x), nil
+			case *List:
+				return len(x.Elems), nil
+			case Tuple:
+				return len(x), nil
+			case *Dict:
+				return x.Len(), nil
+			}
+			return nil, fmt.Errorf("%s has no len", typeName(x))
+		},
+		"str": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value = ""
+			err := unpackArgs(args, kwargs, "x?", &x)
+			return str(x), err
+		},
+		"repr": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value
+			err := unpackArgs(args, kwargs, "x", &x)
+			return repr(x), err
+		},
+		"bool": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value = false
+			err := unpackArgs(args, kwargs, "x?", &x)
+			return truth(x), err
+		},
+		"int": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value = 0
+			base := 10
+			if err := unpackArgs(args, kwargs, "x?", &x, "base?", &base); err != nil {
+				return nil, err
+			}
+			switch x := x.(type) {
+			case int:
+				return x, nil
+			case bool:
+				return b2i(x), nil
+			case string:
+				n, err := strconv.ParseInt(strings.TrimSpace(x), base, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid literal %s", repr(x))
+				}
+				return int(n), nil
+			}
+			return nil, fmt.Errorf("can't convert %s to int", typeName(x))
+		},
+		"list": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value = Tuple{}
+			if err := unpackArgs(args, kwargs, "x?", &x); err != nil {
+				return nil, err
+			}
+			elems, err := iterable(x)
+			return &List{Elems: append([]Value(nil), elems...)}, err
+		},
+		"tuple": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value = Tuple{}
+			if err := unpackArgs(args, kwargs, "x?", &x); err != nil {
+				return nil, err
+			}
+			elems, err := iterable(x)
+			return append(Tuple{}, elems...), err
+		},
+		"dict": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			if len(args) > 1 {
+				return nil, errors.New("expected at most 1 argument")
+			}
+			d := NewDict()
+			if len(args) == 1 {
+				if err := dictUpdate(d, args[0]); err != nil {
+					return nil, err
+				}
+			}
+			for _, k := range sortedKeys(kwargs) {
+				d.Set(k, kwargs[k])
+			}
+			return d, nil
+		},
+		"range": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var start, stop, step int = 0, 0, 1
+			var err error
+			switch len(args) {
+			case 1:
+				err = unpackArgs(args, kwargs, "stop", &stop)
+			default:
+				err = unpackArgs(args, kwargs, "start", &start, "stop", &stop, "step?", &step)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if step == 0 {
+				return nil, errors.New("step is zero")
+			}
+			var l List
+			for i := start; step > 0 && i < stop || step < 0 && i > stop; i += step {
+				if len(l.Elems) == maxLen {
+					return nil, errors.New("range too long")
+				}
+				l.Elems = append(l.Elems, i)
+				if _, ok := addInt(i, step); !ok {
+					break
+				}
+			}
+			return &l, nil
+		},
+		"enumerate": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value
+			start := 0
+			if err := unpackArgs(args, kwargs, "x", &x, "start?", &start); err != nil {
+				return nil, err
+			}
+			elems, err := iterable(x)
+			if err != nil {
+				return nil, err
+			}
+			l := &List{}
+			for i, e := range elems {
+				n, ok := addInt(start, i)
+				if !ok {
+					return nil, errors.New("integer overflow")
+				}
+				l.Elems = append(l.Elems, Tuple{n, e})
+			}
+			return l, nil
+		},
+		"zip": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			if len(kwargs) > 0 {
+				return nil, errors.New("unexpected keyword argument")
+			}
+			var seqs [][]Value
+			for _, a := range args {
+				elems, err := iterable(a)
+				if err != nil {
+					return nil, err
+				}
+				seqs = append(seqs, elems)
+			}
+			l := &List{}
+			for i := 0; len(seqs) > 0; i++ {
+				t := Tuple{}
+				for _, s := range seqs {
+					if i >= len(s) {
+						return l, nil
+					}
+					t = append(t, s[i])
+				}
+				l.Elems = append(l.Elems, t)
+			}
+			return l, nil
+		},
+		"sorted": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x, key Value
+			reverse := false
+			if err := unpackArgs(args, kwargs, "x", &x, "key?", &key, "reverse?", &reverse); err != nil {
+				return nil, err
+			}
+			elems, err := iterable(x)
+			if err != nil {
+				return nil, err
+			}
+			l := &List{Elems: append([]Value(nil), elems...)}
+			return l, in.sortValues(l.Elems, key, reverse)
+		},
+		"reversed": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value
+			if err := unpackArgs(args, kwargs, "x", &x); err != nil {
+				return nil, err
+			}
+			elems, err := iterable(x)
+			if err != nil {
+				return nil, err
+			}
+			l := &List{}
+			for i := len(elems) - 1; i >= 0; i-- {
+				l.Elems = append(l.Elems, elems[i])
+			}
+			return l, nil
+		},
+		"min": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			return in.minMax(args, kwargs, -1)
+		},
+		"max": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			return in.minMax(args, kwargs, 1)
+		},
+		"any": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value
+			if err := unpackArgs(args, kwargs, "x", &x); err != nil {
+				return nil, err
+			}
+			elems, err := iterable(x)
+			for _, e := range elems {
+				if truth(e) {
+					return true, nil
+				}
+			}
+			return false, err
+		},
+		"all": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value
+			if err := unpackArgs(args, kwargs, "x", &x); err != nil {
+				return nil, err
+			}
+			elems, err := iterable(x)
+			for _, e := range elems {
+				if !truth(e) {
+					return false, nil
+				}
+			}
+			return true, err
+		},
+		"type": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value
+			err := unpackArgs(args, kwargs, "x", &x)
+			return typeName(x), err
+		},
+		"hasattr": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x Value
+			var name string
+			if err := unpackArgs(args, kwargs, "x", &x, "name", &name); err != nil {
+				return nil, err
+			}
+			_, err := attr(x, name)
+			return err == nil, nil
+		},
+		"getattr": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			var x, def Value
+			var name string
+			if err := unpackArgs(args, kwargs, "x", &x, "name", &name, "default?", &def); err != nil {
+				return nil, err
+			}
+			v, err := attr(x, name)
+			if err != nil && len(args) == 3 {
+				return def, nil
+			}
+			return v, err
+		},
+		"print": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			sep := " "
+			if err := unpackArgs(nil, kwargs, "sep?", &sep); err != nil {
+				return nil, err
+			}
+			strs := make([]string, len(args))
+			for i, a := range args {
+				strs[i] = str(a)
+			}
+			fmt.Fprintf(Stderr, "%s:%d: %s\n", in.filename, in.line, strings.Join(strs, sep))
+			return nil, nil
+		},
+		"fail": func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+			strs := make([]string, len(args))
+			for i, a := range args {
+				strs[i] = str(a)
+			}
+			return nil, errors.New(strings.Join(strs, " "))
+		},
+	} {
+		Universe[name] = builtin(name, fn)
+	}
+}
+
+func builtin(name string, fn func(*interp, []Value, map[string]Value) (Value, error)) *Builtin {
+	return &Builtin{name: name, fn: fn}
+}
+
+// unpackArgs assigns positional and keyword arguments to pairs of
+// parameter names and pointers to *Value, *string, *int or *bool.
+// Names ending with "?" are optional.
+func unpackArgs(args []Value, kwargs map[string]Value, pairs ...interface{}) error {
+	n := len(pairs) / 2
+	if len(args) > n {
+		return fmt.Errorf("expected at most %d arguments, got %d", n, len(args))
+	}
+	set := func(name string, ptr interface{}, v Value) error {
+		switch p := ptr.(type) {
+		case *Value:
+			*p = v
+			return nil
+		case *string:
+			if s, ok := v.(string); ok {
+				*p = s
+				return nil
+			}
+		case *int:
+			if i, ok := v.(int); ok {
+				*p = i
+				return nil
+			}
+		case *bool:
+			*p = truth(v)
+			return nil
+		}
+		return fmt.Errorf("%s: unexpected %s", name, typeName(v))
+	}
+	used := 0
+	for i := 0; i < n; i++ {
+		name := pairs[2*i].(string)
+		optional := strings.HasSuffix(name, "?")
+		name = strings.TrimSuffix(name, "?")
+		v, ok := kwargs[name]
+		if ok {
+			used++
+			if i < len(args) {
+				return fmt.Errorf("got multiple values for %s", name)
+			}
+		} else if i < len(args) {
+			v, ok = args[i], true
+		}
+		if !ok {
+			if !optional {
+				return fmt.Errorf("missing argument %s", name)
+			}
+			continue
+		}
+		if err := set(name, pairs[2*i+1], v); err != nil {
+			return err
+		}
+	}
+	if used != len(kwargs) {
+		return errors.New("unexpected keyword argument")
+	}
+	return nil
+}
+
+// iterable is like iterate, but also gives bytes of strings.
+func iterable(v Value) ([]Value, error) {
+	if s, ok := v.(string); ok {
+		elems := make([]Value, len(s))
+		for i := range s {
+			elems[i] = s[i : i+1]
+		}
+		return elems, nil
+	}
+	return iterate(v)
+}
+
+func sortedKeys(m map[string]Value) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func dictUpdate(d *Dict, x Value) error {
+	if src, ok := x.(*Dict); ok {
+		for _, k := range src.keys {
+			d.Set(k, src.m[k])
+		}
+		return nil
+	}
+	pairs, err := iterate(x)
+	if err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		kv, err := iterate(p)
+		if err != nil || len(kv) != 2 {
+			return errors.New("expected pairs of key and value")
+		}
+		if err := d.Set(kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *interp) sortValues(elems []Value, key Value, reverse bool) (err error) {
+	keys := elems
+	if key != nil {
+		keys = make([]Value, len(elems))
+		for i, e := range elems {
+			keys[i] = in.call(0, key, []Value{e}, nil)
+		}
+	}
+	idx := make([]int, len(elems))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		c, e := compare(keys[idx[i]], keys[idx[j]])
+		if e != nil && err == nil {
+			err = e
+		}
+		if reverse {
+			return c > 0
+		}
+		return c < 0
+	})
+	sorted := make([]Value, len(elems))
+	for i, j := range idx {
+		sorted[i] = elems[j]
+	}
+	copy(elems, sorted)
+	return err
+}
+
+func (in *interp) minMax(args []Value, kwargs map[string]Value, sign int) (Value, error) {
+	var key Value
+	if err := unpackArgs(nil, kwargs, "key?", &key); err != nil {
+		return nil, err
+	}
+	elems := args
+	if len(args) == 1 {
+		var err error
+		if elems, err = iterable(args[0]); err != nil {
+			return nil, err
+		}
+	}
+	if len(elems) == 0 {
+		return nil, errors.New("empty sequence")
+	}
+	k := func(v Value) Value {
+		if key == nil {
+			return v
+		}
+		return in.call(0, key, []Value{v}, nil)
+	}
+	best, bestKey := elems[0], k(elems[0])
+	for _, e := range elems[1:] {
+		ek := k(e)
+		c, err := compare(ek, bestKey)
+		if err != nil {
+			return nil, err
+		}
+		if c*sign > 0 {
+			best, bestKey = e, ek
+		}
+	}
+	return best, nil
+}
+
+var (
+	regexpsMu sync.Mutex
+	regexps   = make(map[string]*regexp.Regexp)
+)
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	regexpsMu.Lock()
+	defer regexpsMu.Unlock()
+	if re, ok := regexps[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexps[pattern] = re
+	return re, nil
+}
+
+func regexpArgs(args []Value, kwargs map[string]Value, pairs ...interface{}) (*regexp.Regexp, string, error) {
+	var pattern, s string
+	if err := unpackArgs(args, kwargs, append([]interface{}{"pattern", &pattern, "s", &s}, pairs...)...); err != nil {
+		return nil, "", err
+	}
+	re, err := compileRegexp(pattern)
+	return re, s, err
+}
+
+// reSearch returns list of the first match and its groups, or None.
+func reSearch(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+	re, s, err := regexpArgs(args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	m := re.FindStringSubmatch(s)
+	if m == nil {
+		return nil, nil
+	}
+	l := &List{}
+	for _, g := range m {
+		l.Elems = append(l.Elems, g)
+	}
+	return l, nil
+}
+
+// reFindAll returns all matches; with groups, of the first group, or
+// tuples of groups if there are several.
+func reFindAll(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+	re, s, err := regexpArgs(args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	l := &List{}
+	for _, m := range re.FindAllStringSubmatch(s, -1) {
+		switch len(m) {
+		case 1:
+			l.Elems = append(l.Elems, m[0])
+		case 2:
+			l.Elems = append(l.Elems, m[1])
+		default:
+			t := Tuple{}
+			for _, g := range m[1:] {
+				t = append(t, g)
+			}
+			l.Elems = append(l.Elems, t)
+		}
+	}
+	return l, nil
+}
+
+// reSub replaces matches with repl, in which $1 or ${name} are groups,
+// or with result of calling repl with list of match and groups.
+func reSub(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+	var pattern, s string
+	var repl Value
+	count := -1
+	if err := unpackArgs(args, kwargs, "pattern", &pattern, "repl", &repl, "s", &s, "count?", &count); err != nil {
+		return nil, err
+	}
+	re, err := compileRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, count) {
+		b.WriteString(s[last:m[0]])
+		switch r := repl.(type) {
+		case string:
+			b.Write(re.ExpandString(nil, r, s, m))
+		case *Function, *Builtin:
+			groups := &List{}
+			for i := 0; i < len(m); i += 2 {
+				if m[i] < 0 {
+					groups.Elems = append(groups.Elems, nil)
+				} else {
+					groups.Elems = append(groups.Elems, s[m[i]:m[i+1]])
+				}
+			}
+			v, ok := in.call(0, r, []Value{groups}, nil).(string)
+			if !ok {
+				return nil, errors.New("repl function must return string")
+			}
+			b.WriteString(v)
+		default:
+			return nil, fmt.Errorf("repl: unexpected %s", typeName(repl))
+		}
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String(), nil
+}
+
+func reSplit(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+	maxsplit := -1
+	re, s, err := regexpArgs(args, kwargs, "maxsplit?", &maxsplit)
+	if err != nil {
+		return nil, err
+	}
+	if maxsplit >= 0 {
+		maxsplit++
+	}
+	l := &List{}
+	for _, p := range re.Split(s, maxsplit) {
+		l.Elems = append(l.Elems, p)
+	}
+	return l, nil
+}
+
+// attr returns attribute name of value: a method bound to it or a
+// module member.
+func attr(v Value, name string) (Value, error) {
+	var methods map[string]method
+	switch v := v.(type) {
+	case string:
+		methods = stringMethods
+	case *List:
+		methods = listMethods
+	case *Dict:
+		methods = dictMethods
+	case *Module:
+		if m, ok := v.Members[name]; ok {
+			return m, nil
+		}
+	}
+	m, ok := methods[name]
+	if !ok {
+		return nil, fmt.Errorf("%s has no attribute %s", typeName(v), name)
+	}
+	return builtin(name, func(in *interp, args []Value, kwargs map[string]Value) (Value, error) {
+		if mutators[name] {
+			if err := checkMutable(v); err != nil {
+				return nil, err
+			}
+		}
+		return m(in, v, args, kwargs)
+	}), nil
+}
+
+// mutators are list and dict methods that change them.
+var mutators = map[string]bool{
+	"append": true, "extend": true, "insert": true, "pop": true,
+	"remove": true, "clear": true, "setdefault": true, "update": true,
+}
+
+type method func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error)
+
+func stringList(strs []string) *List {
+	l := &List{Elems: make([]Value, len(strs))}
+	for i, s := range strs {
+		l.Elems[i] = s
+	}
+	return l
+}
+
+// stringMethod returns method of string with one optional string
+// argument.
+func stringMethod(f func(s, arg string, hasArg bool) Value) method {
+	return func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var arg Value
+		if err := unpackArgs(args, kwargs, "x?", &arg); err != nil {
+			return nil, err
+		}
+		if arg == nil {
+			return f(recv.(string), "", false), nil
+		}
+		s, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %s", typeName(arg))
+		}
+		return f(recv.(string), s, true), nil
+	}
+}
+
+var stringMethods = map[string]method{
+	"lower": stringMethod(func(s, _ string, _ bool) Value { return strings.ToLower(s) }),
+	"upper": stringMethod(func(s, _ string, _ bool) Value { return strings.ToUpper(s) }),
+	"title": stringMethod(func(s, _ string, _ bool) Value {
+		prev := ' '
+		return strings.Map(func(r rune) rune {
+			defer func() { prev = r }()
+			if unicode.IsLetter(prev) {
+				return unicode.ToLower(r)
+			}
+			return unicode.ToTitle(r)
+		}, s)
+	}),
+	"capitalize": stringMethod(func(s, _ string, _ bool) Value {
+		for i, r := range s {
+			return string(unicode.ToUpper(r)) + strings.ToLower(s[i+len(string(r)):])
+		}
+		return s
+	}),
+	"strip": stringMethod(func(s, cut string, ok bool) Value {
+		if !ok {
+			return strings.TrimSpace(s)
+		}
+		return strings.Trim(s, cut)
+	}),
+	"lstrip": stringMethod(func(s, cut string, ok bool) Value {
+		if !ok {
+			return strings.TrimLeftFunc(s, unicode.IsSpace)
+		}
+		return strings.TrimLeft(s, cut)
+	}),
+	"rstrip": stringMethod(func(s, cut string, ok bool) Value {
+		if !ok {
+			return strings.TrimRightFunc(s, unicode.IsSpace)
+		}
+		return strings.TrimRight(s, cut)
+	}),
+	"removeprefix": stringMethod(func(s, x string, _ bool) Value { return strings.TrimPrefix(s, x) }),
+	"removesuffix": stringMethod(func(s, x string, _ bool) Value { return strings.TrimSuffix(s, x) }),
+	"isdigit": stringMethod(func(s, _ string, _ bool) Value {
+		return s != "" && strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) }) < 0
+	}),
+	"isalpha": stringMethod(func(s, _ string, _ bool) Value {
+		return s != "" && strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) }) < 0
+	}),
+	"isspace": stringMethod(func(s, _ string, _ bool) Value {
+		return s != "" && strings.TrimSpace(s) == ""
+	}),
+	"splitlines": stringMethod(func(s, _ string, _ bool) Value {
+		s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+		if s == "" {
+			return &List{}
+		}
+		return stringList(strings.Split(s, "\n"))
+	}),
+	"startswith": affixMethod(strings.HasPrefix),
+	"endswith":   affixMethod(strings.HasSuffix),
+	"find":       findMethod(strings.Index),
+	"rfind":      findMethod(strings.LastIndex),
+	"count": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var sub string
+		if err := unpackArgs(args, kwargs, "sub", &sub); err != nil {
+			return nil, err
+		}
+		return strings.Count(recv.(string), sub), nil
+	},
+	"replace": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var old, new string
+		count := -1
+		if err := unpackArgs(args, kwargs, "old", &old, "new", &new, "count?", &count); err != nil {
+			return nil, err
+		}
+		return strings.Replace(recv.(string), old, new, count), nil
+	},
+	"split": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var sep Value
+		maxsplit := -1
+		if err := unpackArgs(args, kwargs, "sep?", &sep, "maxsplit?", &maxsplit); err != nil {
+			return nil, err
+		}
+		s := recv.(string)
+		if sep == nil {
+			fields := strings.Fields(s)
+			if maxsplit >= 0 && len(fields) > maxsplit+1 {
+				// Keep the rest of string after maxsplit fields.
+				rest := s
+				for i := 0; i < maxsplit; i++ {
+					rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+					rest = rest[len(fields[i]):]
+				}
+				fields = append(fields[:maxsplit], strings.TrimLeftFunc(rest, unicode.IsSpace))
+			}
+			return stringList(fields), nil
+		}
+		sepStr, ok := sep.(string)
+		if !ok || sepStr == "" {
+			return nil, errors.New("sep must be a non-empty string")
+		}
+		if maxsplit >= 0 {
+			maxsplit++
+		}
+		return stringList(strings.SplitN(s, sepStr, maxsplit)), nil
+	},
+	"join": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var x Value
+		if err := unpackArgs(args, kwargs, "x", &x); err != nil {
+			return nil, err
+		}
+		elems, err := iterable(x)
+		if err != nil {
+			return nil, err
+		}
+		strs := make([]string, len(elems))
+		for i, e := range elems {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("element %d is %s, not string", i, typeName(e))
+			}
+			strs[i] = s
+		}
+		return strings.Join(strs, recv.(string)), nil
+	},
+	"format": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		return strFormat(recv.(string), args, kwargs)
+	},
+}
+
+func affixMethod(f func(s, affix string) bool) method {
+	return func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var x Value
+		if err := unpackArgs(args, kwargs, "x", &x); err != nil {
+			return nil, err
+		}
+		affixes := []Value{x}
+		if t, ok := x.(Tuple); ok {
+			affixes = t
+		}
+		for _, a := range affixes {
+			s, ok := a.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected string, got %s", typeName(a))
+			}
+			if f(recv.(string), s) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+func findMethod(f func(s, sub string) int) method {
+	return func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var sub string
+		if err := unpackArgs(args, kwargs, "sub", &sub); err != nil {
+			return nil, err
+		}
+		return f(recv.(string), sub), nil
+	}
+}
+
+var listMethods = map[string]method{
+	"append": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var x Value
+		if err := unpackArgs(args, kwargs, "x", &x); err != nil {
+			return nil, err
+		}
+		l := recv.(*List)
+		l.Elems = append(l.Elems, x)
+		return nil, nil
+	},
+	"extend": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var x Value
+		if err := unpackArgs(args, kwargs, "x", &x); err != nil {
+			return nil, err
+		}
+		elems, err := iterable(x)
+		l := recv.(*List)
+		l.Elems = append(l.Elems, elems...)
+		return nil, err
+	},
+	"insert": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var i int
+		var x Value
+		if err := unpackArgs(args, kwargs, "index", &i, "x", &x); err != nil {
+			return nil, err
+		}
+		l := recv.(*List)
+		if i < 0 {
+			i += len(l.Elems)
+		}
+		i = clamp(i, 0, len(l.Elems))
+		l.Elems = append(l.Elems[:i], append([]Value{x}, l.Elems[i:]...)...)
+		return nil, nil
+	},
+	"pop": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		l := recv.(*List)
+		i := len(l.Elems) - 1
+		if err := unpackArgs(args, kwargs, "index?", &i); err != nil {
+			return nil, err
+		}
+		if i < 0 {
+			i += len(l.Elems)
+		}
+		if i < 0 || i >= len(l.Elems) {
+			return nil, errors.New("index out of range")
+		}
+		v := l.Elems[i]
+		l.Elems = append(l.Elems[:i], l.Elems[i+1:]...)
+		return v, nil
+	},
+	"remove": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var x Value
+		if err := unpackArgs(args, kwargs, "x", &x); err != nil {
+			return nil, err
+		}
+		l := recv.(*List)
+		for i, e := range l.Elems {
+			if equal(e, x) {
+				l.Elems = append(l.Elems[:i], l.Elems[i+1:]...)
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("%s not in list", repr(x))
+	},
+	"index": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var x Value
+		if err := unpackArgs(args, kwargs, "x", &x); err != nil {
+			return nil, err
+		}
+		for i, e := range recv.(*List).Elems {
+			if equal(e, x) {
+				return i, nil
+			}
+		}
+		return nil, fmt.Errorf("%s not in list", repr(x))
+	},
+	"clear": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		recv.(*List).Elems = nil
+		return nil, unpackArgs(args, kwargs)
+	},
+}
+
+var dictMethods = map[string]method{
+	"get": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var k, def Value
+		if err := unpackArgs(args, kwargs, "key", &k, "default?", &def); err != nil {
+			return nil, err
+		}
+		if v, ok := recv.(*Dict).Get(k); ok {
+			return v, nil
+		}
+		return def, nil
+	},
+	"keys": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		return &List{Elems: recv.(*Dict).Keys()}, unpackArgs(args, kwargs)
+	},
+	"values": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		d := recv.(*Dict)
+		l := &List{}
+		for _, k := range d.keys {
+			l.Elems = append(l.Elems, d.m[k])
+		}
+		return l, unpackArgs(args, kwargs)
+	},
+	"items": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		d := recv.(*Dict)
+		l := &List{}
+		for _, k := range d.keys {
+			l.Elems = append(l.Elems, Tuple{k, d.m[k]})
+		}
+		return l, unpackArgs(args, kwargs)
+	},
+	"pop": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var k, def Value
+		if err := unpackArgs(args, kwargs, "key", &k, "default?", &def); err != nil {
+			return nil, err
+		}
+		d := recv.(*Dict)
+		v, ok := d.Get(k)
+		if d.Delete(k) {
+			return v, nil
+		}
+		if len(args) < 2 && !ok {
+			return nil, fmt.Errorf("key %s not in dict", repr(k))
+		}
+		return def, nil
+	},
+	"setdefault": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		var k, def Value
+		if err := unpackArgs(args, kwargs, "key", &k, "default?", &def); err != nil {
+			return nil, err
+		}
+		d := recv.(*Dict)
+		if v, ok := d.Get(k); ok {
+			return v, nil
+		}
+		return def, d.Set(k, def)
+	},
+	"update": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		d := recv.(*Dict)
+		if len(args) > 1 {
+			return nil, errors.New("expected at most 1 argument")
+		}
+		if len(args) == 1 {
+			if err := dictUpdate(d, args[0]); err != nil {
+				return nil, err
+			}
+		}
+		for _, k := range sortedKeys(kwargs) {
+			d.Set(k, kwargs[k])
+		}
+		return nil, nil
+	},
+	"clear": func(in *interp, recv Value, args []Value, kwargs map[string]Value) (Value, error) {
+		d := recv.(*Dict)
+		d.keys, d.m = nil, make(map[Value]Value)
+		return nil, unpackArgs(args, kwargs)
+	},
+}
+
+// format implements string % operator with %s, %r, %d and %%.
+func format(f string, x Value) (string, error) {
+	args := []Value{x}
+	if t, ok := x.(Tuple); ok {
+		args = t
+	}
+	var b strings.Builder
+	n := 0
+	for i := 0; i < len(f); i++ {
+		if f[i] != '%' {
+			b.WriteByte(f[i])
+			continue
+		}
+		i++
+		if i == len(f) {
+			return "", errors.New("incomplete format")
+		}
+		if f[i] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		if n >= len(args) {
+			return "", errors.New("not enough arguments for format string")
+		}
+		a := args[n]
+		n++
+		switch f[i] {
+		case 's':
+			b.WriteString(str(a))
+		case 'r':
+			b.WriteString(repr(a))
+		case 'd':
+			d, ok := a.(int)
+			if !ok {
+				return "", fmt.Errorf("%%d format requires int, not %s", typeName(a))
+			}
+			b.WriteString(strconv.Itoa(d))
+		default:
+			return "", fmt.Errorf("unsupported format character %q", f[i])
+		}
+	}
+	if n < len(args) {
+		return "", errors.New("too many arguments for format string")
+	}
+	return b.String(), nil
+}
+
+// strFormat implements str.format with {}, {0} and {name} fields.
+func strFormat(f string, args []Value, kwargs map[string]Value) (string, error) {
+	var b strings.Builder
+	auto := 0
+	for i := 0; i < len(f); i++ {
+		c := f[i]
+		switch {
+		case c == '{' && strings.HasPrefix(f[i:], "{{"), c == '}' && strings.HasPrefix(f[i:], "}}"):
+			b.WriteByte(c)
+			i++
+		case c == '{':
+			j := strings.IndexByte(f[i:], '}')
+			if j < 0 {
+				return "", errors.New("unmatched {")
+			}
+			field := f[i+1 : i+j]
+			i += j
+			var v Value
+			if n, err := strconv.Atoi(field); err == nil || field == "" {
+				if field == "" {
+					n = auto
+					auto++
+				}
+				if n < 0 || n >= len(args) {
+					return "", fmt.Errorf("no argument for {%s}", field)
+				}
+				v = args[n]
+			} else {
+				var ok bool
+				if v, ok = kwargs[field]; !ok {
+					return "", fmt.Errorf("no argument for {%s}", field)
+				}
+			}
+			b.WriteString(str(v))
+		case c == '}':
+			return "", errors.New("single } in format string")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,674 @@
+package script
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// maxLen limits lengths of strings and lists made by repetition and
+// range, so that scripts can't exhaust memory by accident.
+const maxLen = 1 << 24
+
+// maxSteps limits statements and comprehension elements run by a Load
+// or Call, so that nested loops over long ranges end.
+const maxSteps = 1 << 24
+
+type interp struct {
+	filename string
+	line     int // of the current statement
+	steps    int
+}
+
+// runtimeError is a panic value of script errors.
+type runtimeError struct{ err *Error }
+
+func (in *interp) run(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			re, ok := r.(runtimeError)
+			if !ok {
+				panic(r)
+			}
+			err = re.err
+		}
+	}()
+	f()
+	return nil
+}
+
+func (in *interp) errorf(line int, format string, args ...interface{}) {
+	if line == 0 {
+		line = in.line
+	}
+	panic(runtimeError{&Error{Filename: in.filename, Line: line, Msg: fmt.Sprintf(format, args...)}})
+}
+
+func (in *interp) check(line int, err error) {
+	if err != nil {
+		if e, ok := err.(*Error); ok {
+			panic(runtimeError{e})
+		}
+		in.errorf(line, "%s", err)
+	}
+}
+
+func (in *interp) step() {
+	in.steps++
+	if in.steps > maxSteps {
+		in.errorf(0, "too many steps")
+	}
+}
+
+type control int
+
+const (
+	ctlNone control = iota
+	ctlReturn
+	ctlBreak
+	ctlContinue
+)
+
+func (in *interp) execBlock(stmts []stmt, e *env) (control, Value) {
+	for _, s := range stmts {
+		if ctl, v := in.exec(s, e); ctl != ctlNone {
+			return ctl, v
+		}
+	}
+	return ctlNone, nil
+}
+
+func (in *interp) exec(s stmt, e *env) (control, Value) {
+	in.step()
+	switch s := s.(type) {
+	case *exprStmt:
+		in.line = s.line
+		in.eval(s.x, e)
+	case *assignStmt:
+		in.line = s.line
+		if s.op == "=" {
+			in.assign(s.lhs, in.eval(s.rhs, e), e)
+			break
+		}
+		op := strings.TrimSuffix(s.op, "=")
+		// Evaluate index operands once.
+		lhs := s.lhs
+		if ix, ok := lhs.(*indexExpr); ok {
+			lhs = &indexExpr{line: ix.line, x: &literal{in.eval(ix.x, e)}, index: &literal{in.eval(ix.index, e)}}
+		}
+		x, y := in.eval(lhs, e), in.eval(s.rhs, e)
+		if l, ok := x.(*List); ok && op == "+" {
+			// Lists are extended in place.
+			elems, err := iterate(y)
+			in.check(s.line, err)
+			in.check(s.line, checkMutable(l))
+			l.Elems = append(l.Elems, elems...)
+			break
+		}
+		in.assign(lhs, in.binary(s.line, op, x, y), e)
+	case *ifStmt:
+		if truth(in.eval(s.cond, e)) {
+			return in.execBlock(s.then, e)
+		}
+		return in.execBlock(s.els, e)
+	case *forStmt:
+		in.line = s.line
+		seq := in.eval(s.x, e)
+		elems, err := iterate(seq)
+		in.check(s.line, err)
+		defer startIteration(seq)()
+		for _, x := range elems {
+			in.assign(s.vars, x, e)
+			switch ctl, v := in.execBlock(s.body, e); ctl {
+			case ctlReturn:
+				return ctl, v
+			case ctlBreak:
+				return ctlNone, nil
+			}
+		}
+	case *defStmt:
+		e.vars[s.name] = in.function(s.name, s.params, e, s.body, nil)
+	case *returnStmt:
+		in.line = s.line
+		if s.x == nil {
+			return ctlReturn, nil
+		}
+		return ctlReturn, in.eval(s.x, e)
+	case *branchStmt:
+		switch s.tok {
+		case "break":
+			return ctlBreak, nil
+		case "continue":
+			return ctlContinue, nil
+		}
+	}
+	return ctlNone, nil
+}
+
+func (in *interp) function(name string, params []param, e *env, body []stmt, lambda expr) *Function {
+	f := &Function{name: name, params: params, body: body, lambda: lambda, env: e}
+	for _, p := range params {
+		var def Value
+		if p.def != nil {
+			def = in.eval(p.def, e)
+		}
+		f.defs = append(f.defs, def)
+	}
+	return f
+}
+
+func (in *interp) assign(lhs expr, v Value, e *env) {
+	switch lhs := lhs.(type) {
+	case *nameExpr:
+		e.vars[lhs.name] = v
+	case *indexExpr:
+		x, k := in.eval(lhs.x, e), in.eval(lhs.index, e)
+		switch x := x.(type) {
+		case *List:
+			i := in.index(lhs.line, k, len(x.Elems))
+			in.check(lhs.line, checkMutable(x))
+			x.Elems[i] = v
+		case *Dict:
+			in.check(lhs.line, x.Set(k, v))
+		default:
+			in.errorf(lhs.line, "%s doesn't support item assignment", typeName(x))
+		}
+	case *tupleExpr:
+		in.unpack(lhs.elems, v, e)
+	case *listExpr:
+		in.unpack(lhs.elems, v, e)
+	}
+}
+
+func (in *interp) unpack(targets []expr, v Value, e *env) {
+	elems, err := iterate(v)
+	in.check(0, err)
+	if len(elems) != len(targets) {
+		in.errorf(0, "can't unpack %d values into %d variables", len(elems), len(targets))
+	}
+	for i, t := range targets {
+		in.assign(t, elems[i], e)
+	}
+}
+
+// index returns index k of sequence of length n, counting negative
+// indexes from the end.
+func (in *interp) index(line int, k Value, n int) int {
+	i, ok := k.(int)
+	if !ok {
+		in.errorf(line, "index must be int, not %s", typeName(k))
+	}
+	if i < 0 {
+		i += n
+	}
+	if i < 0 || i >= n {
+		in.errorf(line, "index %d out of range", k)
+	}
+	return i
+}
+
+func (in *interp) eval(x expr, e *env) Value {
+	switch x := x.(type) {
+	case *literal:
+		return x.v
+	case *nameExpr:
+		v, ok := e.lookup(x.name)
+		if !ok {
+			in.errorf(x.line, "undefined: %s", x.name)
+		}
+		return v
+	case *listExpr:
+		return &List{Elems: in.evalElems(x.elems, e)}
+	case *tupleExpr:
+		return Tuple(in.evalElems(x.elems, e))
+	case *dictExpr:
+		d := NewDict()
+		for i, k := range x.keys {
+			in.check(x.line, d.Set(in.eval(k, e), in.eval(x.vals[i], e)))
+		}
+		return d
+	case *compExpr:
+		return in.comprehension(x, e)
+	case *unaryExpr:
+		v := in.eval(x.x, e)
+		switch x.op {
+		case "not":
+			return !truth(v)
+		case "-":
+			if i, ok := v.(int); ok {
+				if i == math.MinInt {
+					in.errorf(x.line, "integer overflow")
+				}
+				return -i
+			}
+		case "+":
+			if i, ok := v.(int); ok {
+				return i
+			}
+		}
+		in.errorf(x.line, "bad operand type for unary %s: %s", x.op, typeName(v))
+	case *binaryExpr:
+		a := in.eval(x.x, e)
+		switch x.op {
+		case "and":
+			if !truth(a) {
+				return a
+			}
+			return in.eval(x.y, e)
+		case "or":
+			if truth(a) {
+				return a
+			}
+			return in.eval(x.y, e)
+		}
+		return in.binary(x.line, x.op, a, in.eval(x.y, e))
+	case *condExpr:
+		if truth(in.eval(x.cond, e)) {
+			return in.eval(x.x, e)
+		}
+		return in.eval(x.y, e)
+	case *dotExpr:
+		v := in.eval(x.x, e)
+		a, err := attr(v, x.name)
+		in.check(x.line, err)
+		return a
+	case *indexExpr:
+		return in.getIndex(x.line, in.eval(x.x, e), in.eval(x.index, e))
+	case *sliceExpr:
+		return in.slice(x, e)
+	case *callExpr:
+		fn := in.eval(x.fn, e)
+		args := in.evalElems(x.args, e)
+		var kwargs map[string]Value
+		if len(x.kwargs) > 0 {
+			kwargs = make(map[string]Value)
+			for _, kw := range x.kwargs {
+				if _, ok := kwargs[kw.name]; ok {
+					in.errorf(x.line, "keyword argument %s repeated", kw.name)
+				}
+				kwargs[kw.name] = in.eval(kw.x, e)
+			}
+		}
+		return in.call(x.line, fn, args, kwargs)
+	case *lambdaExpr:
+		return in.function("lambda", x.params, e, nil, x.body)
+	}
+	panic(fmt.Sprintf("script: unknown expression %T", x))
+}
+
+func (in *interp) evalElems(xs []expr, e *env) []Value {
+	vs := make([]Value, len(xs))
+	for i, x := range xs {
+		vs[i] = in.eval(x, e)
+	}
+	return vs
+}
+
+func (in *interp) comprehension(c *compExpr, e *env) Value {
+	// Comprehension variables are local to it.
+	ce := newEnv(e)
+	var l List
+	d := NewDict()
+	var loop func(i int)
+	loop = func(i int) {
+		if i == len(c.clauses) {
+			in.step()
+			if c.dict {
+				in.check(c.line, d.Set(in.eval(c.key, ce), in.eval(c.body, ce)))
+			} else {
+				l.Elems = append(l.Elems, in.eval(c.body, ce))
+			}
+			return
+		}
+		switch cl := c.clauses[i].(type) {
+		case *forClause:
+			seq := in.eval(cl.x, ce)
+			elems, err := iterate(seq)
+			in.check(c.line, err)
+			defer startIteration(seq)()
+			for _, x := range elems {
+				in.assign(cl.vars, x, ce)
+				loop(i + 1)
+			}
+		case *ifClause:
+			if truth(in.eval(cl.cond, ce)) {
+				loop(i + 1)
+			}
+		}
+	}
+	loop(0)
+	if c.dict {
+		return d
+	}
+	return &l
+}
+
+func (in *interp) getIndex(line int, x, k Value) Value {
+	switch x := x.(type) {
+	case *List:
+		return x.Elems[in.index(line, k, len(x.Elems))]
+	case Tuple:
+		return x[in.index(line, k, len(x))]
+	case string:
+		i := in.index(line, k, len(x))
+		return x[i : i+1]
+	case *Dict:
+		if !hashable(k) {
+			in.errorf(line, "unhashable type: %s", typeName(k))
+		}
+		v, ok := x.Get(k)
+		if !ok {
+			in.errorf(line, "key %s not in dict", repr(k))
+		}
+		return v
+	}
+	in.errorf(line, "%s isn't indexable", typeName(x))
+	return nil
+}
+
+func (in *interp) slice(s *sliceExpr, e *env) Value {
+	x := in.eval(s.x, e)
+	var n int
+	switch x := x.(type) {
+	case *List:
+		n = len(x.Elems)
+	case Tuple:
+		n = len(x)
+	case string:
+		n = len(x)
+	default:
+		in.errorf(s.line, "%s can't be sliced", typeName(x))
+	}
+	step := 1
+	if s.st != nil {
+		v, ok := in.eval(s.st, e).(int)
+		if !ok || v == 0 {
+			in.errorf(s.line, "slice step must be a non-zero int")
+		}
+		step = v
+	}
+	bound := func(x expr, def int) int {
+		if x == nil {
+			return def
+		}
+		v := in.eval(x, e)
+		if v == nil {
+			return def
+		}
+		i, ok := v.(int)
+		if !ok {
+			in.errorf(s.line, "slice index must be int, not %s", typeName(v))
+		}
+		if i < 0 {
+			i += n
+		}
+		if step > 0 {
+			return clamp(i, 0, n)
+		}
+		return clamp(i, -1, n-1)
+	}
+	var lo, hi int
+	if step > 0 {
+		lo, hi = bound(s.lo, 0), bound(s.hi, n)
+	} else {
+		lo, hi = bound(s.lo, n-1), bound(s.hi, -1)
+	}
+	var idx []int
+	for i := lo; step > 0 && i < hi || step < 0 && i > hi; i += step {
+		idx = append(idx, i)
+	}
+	switch x := x.(type) {
+	case *List:
+		l := &List{}
+		for _, i := range idx {
+			l.Elems = append(l.Elems, x.Elems[i])
+		}
+		return l
+	case Tuple:
+		t := Tuple{}
+		for _, i := range idx {
+			t = append(t, x[i])
+		}
+		return t
+	}
+	str := x.(string)
+	if step == 1 {
+		return str[lo:max(lo, hi)]
+	}
+	var b strings.Builder
+	for _, i := range idx {
+		b.WriteByte(str[i])
+	}
+	return b.String()
+}
+
+func clamp(i, lo, hi int) int {
+	if i < lo {
+		return lo
+	}
+	if i > hi {
+		return hi
+	}
+	return i
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (in *interp) binary(line int, op string, x, y Value) Value {
+	switch op {
+	case "==":
+		return equal(x, y)
+	case "!=":
+		return !equal(x, y)
+	case "<", ">", "<=", ">=":
+		c, err := compare(x, y)
+		in.check(line, err)
+		switch op {
+		case "<":
+			return c < 0
+		case ">":
+			return c > 0
+		case "<=":
+			return c <= 0
+		}
+		return c >= 0
+	case "in", "not in":
+		return in.contains(line, y, x) == (op == "in")
+	}
+	switch x := x.(type) {
+	case int:
+		switch y := y.(type) {
+		case int:
+			// Ints are 64-bit, and overflow is an error.
+			switch op {
+			case "+":
+				if z, ok := addInt(x, y); ok {
+					return z
+				}
+				in.errorf(line, "integer overflow")
+			case "-":
+				if z := x - y; (z < x) == (y > 0) {
+					return z
+				}
+				in.errorf(line, "integer overflow")
+			case "*":
+				if z := x * y; x == 0 || z/x == y && !(x == -1 && y == math.MinInt) {
+					return z
+				}
+				in.errorf(line, "integer overflow")
+			case "//", "%":
+				if y == 0 {
+					in.errorf(line, "division by zero")
+				}
+				if op == "//" && x == math.MinInt && y == -1 {
+					in.errorf(line, "integer overflow")
+				}
+				q, r := x/y, x%y
+				// Round towards negative infinity.
+				if r != 0 && (r < 0) != (y < 0) {
+					q, r = q-1, r+y
+				}
+				if op == "//" {
+					return q
+				}
+				return r
+			}
+		case string:
+			if op == "*" {
+				return in.repeat(line, y, x)
+			}
+		case *List:
+			if op == "*" {
+				return &List{Elems: in.repeatElems(line, y.Elems, x)}
+			}
+		}
+	case string:
+		switch op {
+		case "+":
+			if y, ok := y.(string); ok {
+				return x + y
+			}
+		case "*":
+			if n, ok := y.(int); ok {
+				return in.repeat(line, x, n)
+			}
+		case "%":
+			s, err := format(x, y)
+			in.check(line, err)
+			return s
+		}
+	case *List:
+		switch op {
+		case "+":
+			if y, ok := y.(*List); ok {
+				return &List{Elems: append(append([]Value(nil), x.Elems...), y.Elems...)}
+			}
+		case "*":
+			if n, ok := y.(int); ok {
+				return &List{Elems: in.repeatElems(line, x.Elems, n)}
+			}
+		}
+	case Tuple:
+		if y, ok := y.(Tuple); ok && op == "+" {
+			return append(append(Tuple(nil), x...), y...)
+		}
+	}
+	in.errorf(line, "unsupported operand types for %s: %s and %s", op, typeName(x), typeName(y))
+	return nil
+}
+
+// addInt returns x+y, reporting whether it didn't overflow.
+func addInt(x, y int) (int, bool) {
+	z := x + y
+	return z, (z > x) == (y > 0)
+}
+
+func (in *interp) repeat(line int, s string, n int) string {
+	if n > 0 && len(s)*n/n != len(s) || len(s)*n > maxLen {
+		in.errorf(line, "repeated string too long")
+	}
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat(s, n)
+}
+
+func (in *interp) repeatElems(line int, elems []Value, n int) []Value {
+	if n > 0 && len(elems)*n/n != len(elems) || len(elems)*n > maxLen {
+		in.errorf(line, "repeated list too long")
+	}
+	var vs []Value
+	for i := 0; i < n; i++ {
+		vs = append(vs, elems...)
+	}
+	return vs
+}
+
+func (in *interp) contains(line int, c, x Value) bool {
+	switch c := c.(type) {
+	case string:
+		s, ok := x.(string)
+		if !ok {
+			in.errorf(line, "'in <string>' requires string, not %s", typeName(x))
+		}
+		return strings.Contains(c, s)
+	case *List:
+		return containsElem(c.Elems, x)
+	case Tuple:
+		return containsElem(c, x)
+	case *Dict:
+		if !hashable(x) {
+			in.errorf(line, "unhashable type: %s", typeName(x))
+		}
+		_, ok := c.Get(x)
+		return ok
+	}
+	in.errorf(line, "'in' requires a string, list, tuple or dict, not %s", typeName(c))
+	return false
+}
+
+func containsElem(elems []Value, x Value) bool {
+	for _, e := range elems {
+		if equal(e, x) {
+			return true
+		}
+	}
+	return false
+}
+
+func (in *interp) call(line int, fn Value, args []Value, kwargs map[string]Value) Value {
+	switch fn := fn.(type) {
+	case *Builtin:
+		v, err := fn.fn(in, args, kwargs)
+		if err != nil {
+			in.check(line, fmt.Errorf("%s: %s", fn.name, err))
+		}
+		return v
+	case *Function:
+		if fn.calling {
+			in.errorf(line, "%s called recursively", fn.name)
+		}
+		if len(args) > len(fn.params) {
+			in.errorf(line, "%s takes %d arguments, got %d", fn.name, len(fn.params), len(args))
+		}
+		e := newEnv(fn.env)
+		for i, p := range fn.params {
+			if i < len(args) {
+				if _, ok := kwargs[p.name]; ok {
+					in.errorf(line, "%s got multiple values for %s", fn.name, p.name)
+				}
+				e.vars[p.name] = args[i]
+			} else if v, ok := kwargs[p.name]; ok {
+				e.vars[p.name] = v
+			} else if p.def != nil {
+				e.vars[p.name] = fn.defs[i]
+			} else {
+				in.errorf(line, "%s missing argument %s", fn.name, p.name)
+			}
+		}
+		for k := range kwargs {
+			if _, ok := e.vars[k]; !ok {
+				in.errorf(line, "%s got unexpected argument %s", fn.name, k)
+			}
+		}
+		fn.calling = true
+		defer func() { fn.calling = false }()
+		saved := in.line
+		defer func() { in.line = saved }()
+		if fn.lambda != nil {
+			return in.eval(fn.lambda, e)
+		}
+		ctl, v := in.execBlock(fn.body, e)
+		if ctl == ctlBreak || ctl == ctlContinue {
+			in.errorf(0, "break or continue outside loop")
+		}
+		return v
+	}
+	in.errorf(line, "%s isn't callable", typeName(fn))
+	return nil
+}
@@ -0,0 +1,267 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tNewline
+	tIndent
+	tDedent
+	tName
+	tInt
+	tString
+	tOp
+)
+
+type token struct {
+	kind tokenKind
+	text string // name, operator or string value
+	num  int
+	line int
+}
+
+var keywords = map[string]bool{
+	"and": true, "break": true, "continue": true, "def": true, "elif": true,
+	"else": true, "for": true, "if": true, "in": true, "lambda": true,
+	"load": true, "not": true, "or": true, "pass": true, "return": true,
+	"while": true,
+}
+
+// ops are operators, longest first.
+var ops = []string{
+	"//=", "==", "!=", "<=", ">=", "//", "+=", "-=", "*=", "%=", "**",
+	"+", "-", "*", "/", "%", "<", ">", "=", "(", ")", "[", "]", "{", "}",
+	",", ":", ".", ";",
+}
+
+// lex splits src into tokens, with tNewline ending logical lines and
+// tIndent and tDedent around indented blocks.
+func lex(filename, src string) ([]token, error) {
+	var (
+		toks    []token
+		indents = []int{0}
+		depth   int // of brackets
+		line    = 1
+		i       int
+		bol     = true // at beginning of line
+	)
+	errorf := func(format string, args ...interface{}) error {
+		return &Error{Filename: filename, Line: line, Msg: fmt.Sprintf(format, args...)}
+	}
+	for i < len(src) {
+		if bol && depth == 0 {
+			// Measure indentation, skipping blank and comment lines.
+			col, j := 0, i
+			for j < len(src) && (src[j] == ' ' || src[j] == '\t') {
+				if src[j] == '\t' {
+					col += 8 - col%8
+				} else {
+					col++
+				}
+				j++
+			}
+			if j == len(src) {
+				i = j
+				break
+			}
+			if c := src[j]; c == '\n' || c == '\r' || c == '#' {
+				for j < len(src) && src[j] != '\n' {
+					j++
+				}
+				if j < len(src) {
+					j++
+					line++
+				}
+				i = j
+				continue
+			}
+			i = j
+			bol = false
+			if col > indents[len(indents)-1] {
+				indents = append(indents, col)
+				toks = append(toks, token{kind: tIndent, line: line})
+			}
+			for col < indents[len(indents)-1] {
+				indents = indents[:len(indents)-1]
+				toks = append(toks, token{kind: tDedent, line: line})
+			}
+			if col != indents[len(indents)-1] {
+				return nil, errorf("unindent doesn't match any outer level")
+			}
+		}
+		c := src[i]
+		switch {
+		case c == '\n':
+			if depth == 0 {
+				toks = append(toks, token{kind: tNewline, line: line})
+				bol = true
+			}
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '\\' && i+1 < len(src) && src[i+1] == '\n':
+			i += 2
+			line++
+		case isNameStart(c):
+			j := i
+			for j < len(src) && (isNameStart(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			if j < len(src) && (src[j] == '"' || src[j] == '\'') && (src[i:j] == "r" || src[i:j] == "R") {
+				s, n, nl, err := lexString(src[j:], true)
+				if err != nil {
+					return nil, errorf("%s", err)
+				}
+				toks = append(toks, token{kind: tString, text: s, line: line})
+				line += nl
+				i = j + n
+				continue
+			}
+			toks = append(toks, token{kind: tName, text: src[i:j], line: line})
+			i = j
+		case isDigit(c):
+			j := i
+			for j < len(src) && (isDigit(src[j]) || isNameStart(src[j])) {
+				j++
+			}
+			if j < len(src) && src[j] == '.' {
+				return nil, errorf("floating-point numbers are not supported")
+			}
+			n, err := strconv.ParseInt(src[i:j], 0, 64)
+			if err != nil {
+				return nil, errorf("bad number %s", src[i:j])
+			}
+			toks = append(toks, token{kind: tInt, num: int(n), line: line})
+			i = j
+		case c == '"' || c == '\'':
+			s, n, nl, err := lexString(src[i:], false)
+			if err != nil {
+				return nil, errorf("%s", err)
+			}
+			toks = append(toks, token{kind: tString, text: s, line: line})
+			line += nl
+			i += n
+		default:
+			op := ""
+			for _, o := range ops {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, errorf("unexpected character %q", c)
+			}
+			switch op {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				if depth > 0 {
+					depth--
+				}
+			}
+			toks = append(toks, token{kind: tOp, text: op, line: line})
+			i += len(op)
+		}
+	}
+	if len(toks) > 0 && toks[len(toks)-1].kind != tNewline {
+		toks = append(toks, token{kind: tNewline, line: line})
+	}
+	for len(indents) > 1 {
+		indents = indents[:len(indents)-1]
+		toks = append(toks, token{kind: tDedent, line: line})
+	}
+	return append(toks, token{kind: tEOF, line: line}), nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+// lexString reads string literal at start of s, returning its value,
+// length and number of line breaks in it.
+func lexString(s string, raw bool) (value string, n, lines int, err error) {
+	quote := s[:1]
+	if strings.HasPrefix(s, quote+quote+quote) {
+		quote = s[:3]
+	}
+	var b strings.Builder
+	i := len(quote)
+	for {
+		if i >= len(s) {
+			return "", 0, 0, fmt.Errorf("unterminated string")
+		}
+		if strings.HasPrefix(s[i:], quote) {
+			return b.String(), i + len(quote), lines, nil
+		}
+		c := s[i]
+		switch {
+		case c == '\n':
+			if len(quote) == 1 {
+				return "", 0, 0, fmt.Errorf("unterminated string")
+			}
+			lines++
+			b.WriteByte(c)
+			i++
+		case c == '\\' && i+1 < len(s):
+			if raw {
+				b.WriteString(s[i : i+2])
+				if s[i+1] == '\n' {
+					lines++
+				}
+				i += 2
+				continue
+			}
+			e := s[i+1]
+			i += 2
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			case '\\', '\'', '"':
+				b.WriteByte(e)
+			case '\n':
+				lines++
+			case 'x', 'u', 'U':
+				size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+				if i+size > len(s) {
+					return "", 0, 0, fmt.Errorf("bad escape \\%c", e)
+				}
+				r, err := strconv.ParseUint(s[i:i+size], 16, 32)
+				if err != nil {
+					return "", 0, 0, fmt.Errorf("bad escape \\%c%s", e, s[i:i+size])
+				}
+				if e == 'x' {
+					b.WriteByte(byte(r))
+				} else {
+					b.WriteRune(rune(r))
+				}
+				i += size
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+}
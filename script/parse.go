@@ -0,0 +1,643 @@
+package script
+
+import "fmt"
+
+// Syntax tree.
+type (
+	stmt interface{}
+	expr interface{}
+
+	exprStmt struct {
+		line int
+		x    expr
+	}
+	assignStmt struct {
+		line int
+		op   string // "=", "+=", etc.
+		lhs  expr
+		rhs  expr
+	}
+	ifStmt struct {
+		cond      expr
+		then, els []stmt
+	}
+	forStmt struct {
+		line int
+		vars expr
+		x    expr
+		body []stmt
+	}
+	defStmt struct {
+		line   int
+		name   string
+		params []param
+		body   []stmt
+	}
+	returnStmt struct {
+		line int
+		x    expr
+	}
+	branchStmt struct {
+		line int
+		tok  string // "break", "continue" or "pass"
+	}
+
+	param struct {
+		name string
+		def  expr // default value or nil
+	}
+
+	nameExpr struct {
+		line int
+		name string
+	}
+	literal   struct{ v Value }
+	listExpr  struct{ elems []expr }
+	tupleExpr struct{ elems []expr }
+	dictExpr  struct {
+		line       int
+		keys, vals []expr
+	}
+	compExpr struct { // list or dict comprehension
+		line      int
+		dict      bool
+		key, body expr // key is for dicts only
+		clauses   []interface{}
+	}
+	unaryExpr struct {
+		line int
+		op   string
+		x    expr
+	}
+	binaryExpr struct {
+		line int
+		op   string
+		x, y expr
+	}
+	condExpr struct{ cond, x, y expr }
+	dotExpr  struct {
+		line int
+		x    expr
+		name string
+	}
+	indexExpr struct {
+		line     int
+		x, index expr
+	}
+	sliceExpr struct {
+		line          int
+		x, lo, hi, st expr
+	}
+	callExpr struct {
+		line   int
+		fn     expr
+		args   []expr
+		kwargs []kwarg
+	}
+	lambdaExpr struct {
+		line   int
+		params []param
+		body   expr
+	}
+	kwarg struct {
+		name string
+		x    expr
+	}
+	forClause struct {
+		vars expr
+		x    expr
+	}
+	ifClause struct{ cond expr }
+)
+
+type parser struct {
+	filename string
+	toks     []token
+	pos      int
+}
+
+func parse(filename, src string) ([]stmt, error) {
+	toks, err := lex(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{filename: filename, toks: toks}
+	var stmts []stmt
+	err = p.catch(func() {
+		for p.peek().kind != tEOF {
+			stmts = append(stmts, p.stmt()...)
+		}
+	})
+	return stmts, err
+}
+
+// syntaxError is a panic value of parse errors.
+type syntaxError struct{ err *Error }
+
+func (p *parser) catch(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			se, ok := r.(syntaxError)
+			if !ok {
+				panic(r)
+			}
+			err = se.err
+		}
+	}()
+	f()
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) {
+	panic(syntaxError{&Error{Filename: p.filename, Line: p.peek().line, Msg: fmt.Sprintf(format, args...)}})
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tEOF {
+		p.pos++
+	}
+	return t
+}
+
+// is reports whether the next token is operator or keyword s.
+func (p *parser) is(s string) bool {
+	t := p.peek()
+	return (t.kind == tOp || t.kind == tName) && t.text == s
+}
+
+func (p *parser) accept(s string) bool {
+	if p.is(s) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) {
+	if !p.accept(s) {
+		p.errorf("expected %s, got %s", s, p.describe())
+	}
+}
+
+func (p *parser) describe() string {
+	t := p.peek()
+	switch t.kind {
+	case tEOF:
+		return "end of file"
+	case tNewline:
+		return "newline"
+	case tIndent:
+		return "indent"
+	case tDedent:
+		return "unindent"
+	case tInt:
+		return fmt.Sprint(t.num)
+	case tString:
+		return "string"
+	}
+	return t.text
+}
+
+func (p *parser) name() string {
+	t := p.peek()
+	if t.kind != tName || keywords[t.text] {
+		p.errorf("expected name, got %s", p.describe())
+	}
+	p.next()
+	return t.text
+}
+
+// stmt parses a compound statement or a line of simple statements.
+func (p *parser) stmt() []stmt {
+	line := p.peek().line
+	switch {
+	case p.accept("if"):
+		return []stmt{p.ifStmt()}
+	case p.accept("for"):
+		vars := p.targets()
+		p.expect("in")
+		x := p.exprList()
+		p.expect(":")
+		return []stmt{&forStmt{line: line, vars: vars, x: x, body: p.suite()}}
+	case p.accept("def"):
+		name := p.name()
+		p.expect("(")
+		params := p.params(")")
+		p.expect(")")
+		p.expect(":")
+		return []stmt{&defStmt{line: line, name: name, params: params, body: p.suite()}}
+	case p.is("while"):
+		p.errorf("while loops are not supported")
+	case p.is("load"):
+		p.errorf("load is not supported")
+	}
+	return p.simpleStmts()
+}
+
+func (p *parser) ifStmt() stmt {
+	s := &ifStmt{cond: p.test()}
+	p.expect(":")
+	s.then = p.suite()
+	switch {
+	case p.accept("elif"):
+		s.els = []stmt{p.ifStmt()}
+	case p.accept("else"):
+		p.expect(":")
+		s.els = p.suite()
+	}
+	return s
+}
+
+// suite parses a block after colon: an indented block or simple
+// statements on the same line.
+func (p *parser) suite() []stmt {
+	if p.peek().kind != tNewline {
+		return p.simpleStmts()
+	}
+	p.next()
+	if p.peek().kind != tIndent {
+		p.errorf("expected indented block")
+	}
+	p.next()
+	var stmts []stmt
+	for p.peek().kind != tDedent && p.peek().kind != tEOF {
+		stmts = append(stmts, p.stmt()...)
+	}
+	p.next()
+	return stmts
+}
+
+func (p *parser) simpleStmts() []stmt {
+	var stmts []stmt
+	for {
+		stmts = append(stmts, p.simpleStmt())
+		if !p.accept(";") || p.peek().kind == tNewline {
+			break
+		}
+	}
+	if p.peek().kind != tNewline {
+		p.errorf("unexpected %s", p.describe())
+	}
+	p.next()
+	return stmts
+}
+
+func (p *parser) simpleStmt() stmt {
+	t := p.peek()
+	switch {
+	case p.accept("return"):
+		if p.peek().kind == tNewline || p.is(";") {
+			return &returnStmt{line: t.line}
+		}
+		return &returnStmt{line: t.line, x: p.exprList()}
+	case p.accept("break"), p.accept("continue"), p.accept("pass"):
+		return &branchStmt{line: t.line, tok: t.text}
+	}
+	x := p.exprList()
+	for _, op := range []string{"=", "+=", "-=", "*=", "//=", "%="} {
+		if p.accept(op) {
+			checkTarget(p, x, op == "=")
+			return &assignStmt{line: t.line, op: op, lhs: x, rhs: p.exprList()}
+		}
+	}
+	return &exprStmt{line: t.line, x: x}
+}
+
+func checkTarget(p *parser, x expr, tuples bool) {
+	switch x := x.(type) {
+	case *nameExpr, *indexExpr:
+		return
+	case *tupleExpr:
+		if tuples {
+			for _, e := range x.elems {
+				checkTarget(p, e, true)
+			}
+			return
+		}
+	case *listExpr:
+		if tuples {
+			for _, e := range x.elems {
+				checkTarget(p, e, true)
+			}
+			return
+		}
+	}
+	p.errorf("can't assign to this expression")
+}
+
+func (p *parser) params(end string) []param {
+	var params []param
+	for !p.is(end) {
+		prm := param{name: p.name()}
+		if p.accept("=") {
+			prm.def = p.test()
+		} else if len(params) > 0 && params[len(params)-1].def != nil {
+			p.errorf("parameter %s without default follows one with default", prm.name)
+		}
+		params = append(params, prm)
+		if !p.accept(",") {
+			break
+		}
+	}
+	return params
+}
+
+// targets parses loop variables.
+func (p *parser) targets() expr {
+	x := p.primary()
+	if !p.is(",") {
+		checkTarget(p, x, true)
+		return x
+	}
+	elems := []expr{x}
+	for p.accept(",") && !p.is("in") {
+		elems = append(elems, p.primary())
+	}
+	t := &tupleExpr{elems}
+	checkTarget(p, t, true)
+	return t
+}
+
+// exprList parses expressions separated by commas, giving a tuple if
+// there's a comma.
+func (p *parser) exprList() expr {
+	x := p.test()
+	if !p.is(",") {
+		return x
+	}
+	elems := []expr{x}
+	for p.accept(",") && p.startsExpr() {
+		elems = append(elems, p.test())
+	}
+	return &tupleExpr{elems}
+}
+
+func (p *parser) startsExpr() bool {
+	t := p.peek()
+	switch t.kind {
+	case tInt, tString:
+		return true
+	case tName:
+		return !keywords[t.text] || t.text == "not" || t.text == "lambda"
+	case tOp:
+		switch t.text {
+		case "(", "[", "{", "-", "+":
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) test() expr {
+	if p.is("lambda") {
+		line := p.next().line
+		params := p.params(":")
+		p.expect(":")
+		return &lambdaExpr{line: line, params: params, body: p.test()}
+	}
+	x := p.orTest()
+	if p.accept("if") {
+		cond := p.orTest()
+		p.expect("else")
+		return &condExpr{cond: cond, x: x, y: p.test()}
+	}
+	return x
+}
+
+func (p *parser) orTest() expr {
+	x := p.andTest()
+	for p.is("or") {
+		line := p.next().line
+		x = &binaryExpr{line: line, op: "or", x: x, y: p.andTest()}
+	}
+	return x
+}
+
+func (p *parser) andTest() expr {
+	x := p.notTest()
+	for p.is("and") {
+		line := p.next().line
+		x = &binaryExpr{line: line, op: "and", x: x, y: p.notTest()}
+	}
+	return x
+}
+
+func (p *parser) notTest() expr {
+	if p.is("not") {
+		line := p.next().line
+		return &unaryExpr{line: line, op: "not", x: p.notTest()}
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() expr {
+	x := p.arith()
+	line := p.peek().line
+	op := ""
+	switch {
+	case p.is("not"):
+		p.next()
+		p.expect("in")
+		op = "not in"
+	default:
+		for _, o := range []string{"==", "!=", "<", ">", "<=", ">=", "in"} {
+			if p.accept(o) {
+				op = o
+				break
+			}
+		}
+	}
+	if op == "" {
+		return x
+	}
+	x = &binaryExpr{line: line, op: op, x: x, y: p.arith()}
+	for _, o := range []string{"==", "!=", "<", ">", "<=", ">=", "in", "not"} {
+		if p.is(o) {
+			p.errorf("comparisons can't be chained")
+		}
+	}
+	return x
+}
+
+func (p *parser) arith() expr {
+	x := p.term()
+	for p.is("+") || p.is("-") {
+		t := p.next()
+		x = &binaryExpr{line: t.line, op: t.text, x: x, y: p.term()}
+	}
+	return x
+}
+
+func (p *parser) term() expr {
+	x := p.factor()
+	for p.is("*") || p.is("//") || p.is("%") || p.is("/") {
+		t := p.next()
+		if t.text == "/" {
+			p.errorf("floating-point division is not supported, use //")
+		}
+		x = &binaryExpr{line: t.line, op: t.text, x: x, y: p.factor()}
+	}
+	return x
+}
+
+func (p *parser) factor() expr {
+	if p.is("-") || p.is("+") {
+		t := p.next()
+		return &unaryExpr{line: t.line, op: t.text, x: p.factor()}
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() expr {
+	x := p.operand()
+	for {
+		t := p.peek()
+		switch {
+		case p.accept("."):
+			x = &dotExpr{line: t.line, x: x, name: p.name()}
+		case p.accept("["):
+			x = p.index(x, t.line)
+		case p.accept("("):
+			x = p.call(x, t.line)
+		default:
+			return x
+		}
+	}
+}
+
+func (p *parser) index(x expr, line int) expr {
+	var lo expr
+	if !p.is(":") {
+		lo = p.test()
+		if p.accept("]") {
+			return &indexExpr{line: line, x: x, index: lo}
+		}
+	}
+	s := &sliceExpr{line: line, x: x, lo: lo}
+	p.expect(":")
+	if !p.is("]") && !p.is(":") {
+		s.hi = p.test()
+	}
+	if p.accept(":") && !p.is("]") {
+		s.st = p.test()
+	}
+	p.expect("]")
+	return s
+}
+
+func (p *parser) call(fn expr, line int) expr {
+	c := &callExpr{line: line, fn: fn}
+	for !p.is(")") {
+		t := p.peek()
+		if t.kind == tName && p.toks[p.pos+1].kind == tOp && p.toks[p.pos+1].text == "=" {
+			p.next()
+			p.next()
+			c.kwargs = append(c.kwargs, kwarg{name: t.text, x: p.test()})
+		} else {
+			if len(c.kwargs) > 0 {
+				p.errorf("positional argument follows keyword argument")
+			}
+			c.args = append(c.args, p.test())
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect(")")
+	return c
+}
+
+func (p *parser) operand() expr {
+	t := p.peek()
+	switch t.kind {
+	case tInt:
+		p.next()
+		return &literal{t.num}
+	case tString:
+		p.next()
+		s := t.text
+		// Adjacent strings are concatenated.
+		for p.peek().kind == tString {
+			s += p.next().text
+		}
+		return &literal{s}
+	case tName:
+		return &nameExpr{line: t.line, name: p.name()}
+	}
+	switch {
+	case p.accept("("):
+		if p.accept(")") {
+			return &tupleExpr{}
+		}
+		x := p.test()
+		if !p.is(",") {
+			p.expect(")")
+			return x
+		}
+		elems := []expr{x}
+		for p.accept(",") && !p.is(")") {
+			elems = append(elems, p.test())
+		}
+		p.expect(")")
+		return &tupleExpr{elems}
+	case p.accept("["):
+		if p.accept("]") {
+			return &listExpr{}
+		}
+		x := p.test()
+		if p.is("for") {
+			c := &compExpr{line: t.line, body: x, clauses: p.clauses()}
+			p.expect("]")
+			return c
+		}
+		elems := []expr{x}
+		for p.accept(",") && !p.is("]") {
+			elems = append(elems, p.test())
+		}
+		p.expect("]")
+		return &listExpr{elems}
+	case p.accept("{"):
+		d := &dictExpr{line: t.line}
+		if p.accept("}") {
+			return d
+		}
+		k := p.test()
+		p.expect(":")
+		v := p.test()
+		if p.is("for") {
+			c := &compExpr{line: t.line, dict: true, key: k, body: v, clauses: p.clauses()}
+			p.expect("}")
+			return c
+		}
+		d.keys, d.vals = append(d.keys, k), append(d.vals, v)
+		for p.accept(",") && !p.is("}") {
+			d.keys = append(d.keys, p.test())
+			p.expect(":")
+			d.vals = append(d.vals, p.test())
+		}
+		p.expect("}")
+		return d
+	}
+	p.errorf("unexpected %s", p.describe())
+	return nil
+}
+
+// clauses parses for and if clauses of comprehension.
+func (p *parser) clauses() []interface{} {
+	var cs []interface{}
+	for {
+		switch {
+		case p.accept("for"):
+			vars := p.targets()
+			p.expect("in")
+			cs = append(cs, &forClause{vars: vars, x: p.orTest()})
+		case p.accept("if"):
+			cs = append(cs, &ifClause{p.orTest()})
+		default:
+			return cs
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package script implements a subset of Starlark, the Python dialect of
+// Bazel configuration files, for per-entry transforms.
+//
+// Supported are def, if/elif/else, for, return, break, continue and
+// pass statements, assignments (with tuple unpacking and +=, -=, etc.),
+// lambda, conditional expressions, list and dict comprehensions, int,
+// string, list, tuple and dict values with the usual methods, and
+// builtins listed in Universe. Ints are 64-bit, and overflow is an
+// error, as is changing a list or dict during a loop over it. There are
+// no floats, while loops, recursion or load, and a Load or Call stops
+// with an error after running too many statements.
+package script
+
+import "fmt"
+
+// Error is an error of parsing or running script.
+type Error struct {
+	Filename string
+	Line     int
+	Msg      string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%s:%d: %s", e.Filename, e.Line, e.Msg) }
+
+// Program is a loaded script.
+type Program struct {
+	filename string
+	globals  *env
+}
+
+// Load parses script src and runs its top-level statements, which
+// usually define functions.
+func Load(filename string, src []byte) (*Program, error) {
+	stmts, err := parse(filename, string(src))
+	if err != nil {
+		return nil, err
+	}
+	p := &Program{filename: filename, globals: newEnv(nil)}
+	in := &interp{filename: filename}
+	if err := in.run(func() { in.execBlock(stmts, p.globals) }); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Global returns value of global name, or nil if it isn't defined.
+func (p *Program) Global(name string) Value { return p.globals.vars[name] }
+
+// Call calls global function name with arguments.
+func (p *Program) Call(name string, args ...Value) (Value, error) {
+	fn, ok := p.globals.vars[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: function %s is not defined", p.filename, name)
+	}
+	in := &interp{filename: p.filename}
+	var v Value
+	err := in.run(func() { v = in.call(0, fn, args, nil) })
+	return v, err
+}
+
+// env is a scope of variables: a function call or the globals.
+type env struct {
+	vars   map[string]Value
+	parent *env
+}
+
+func newEnv(parent *env) *env { return &env{vars: make(map[string]Value), parent: parent} }
+
+func (e *env) lookup(name string) (Value, bool) {
+	for ; e != nil; e = e.parent {
+		if v, ok := e.vars[name]; ok {
+			return v, true
+		}
+	}
+	v, ok := Universe[name]
+	return v, ok
+}
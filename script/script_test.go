@@ -0,0 +1,163 @@
+package script
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func run(t *testing.T, src string, args ...Value) Value {
+	t.Helper()
+	p, err := Load("test.star", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := p.Call("f", args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestEval(t *testing.T) {
+	for _, tt := range []struct{ src, want string }{
+		{"def f(): return 1 + 2 * 3", "7"},
+		{"def f(): return -7 // 2, -7 % 2", "(-4, 1)"},
+		{`def f(): return "a" + 'b' * 3`, `"abbb"`},
+		{`def f(): return "%s=%d %r" % ("x", 1, "y")`, `"x=1 \"y\""`},
+		{`def f(): return "{} {name} {0}".format("a", name="n")`, `"a n a"`},
+		{`def f(): return " A b ".strip().lower().split(" ")`, `["a", "b"]`},
+		{`def f(): return ",".join([str(x) for x in range(5) if x % 2 == 0])`, `"0,2,4"`},
+		{`def f(): return {k: v for k, v in [("a", 1), ("b", 2)]}`, `{"a": 1, "b": 2}`},
+		{`def f(): return "abcdef"[1:-1], "abcdef"[::-1], [1, 2, 3][-1]`, `("bcde", "fedcba", 3)`},
+		{`def f(): return 1 if "b" in "abc" else 2, 3 not in [1, 2]`, `(1, True)`},
+		{`def f(): return sorted(["bb", "a", "ccc"], key=lambda s: len(s), reverse=True)`, `["ccc", "bb", "a"]`},
+		{`def f(): return re.sub(r"(\w+)@(\w+)", "$2 at $1", "me@host"), re.findall("[0-9]+", "a1b22")`, `("host at me", ["1", "22"])`},
+		{`def f(): return re.sub("[aeiou]", lambda m: m[0].upper(), "banana")`, `"bAnAnA"`},
+		{"def f(): return min(3, 1, 2), max([1, 5, 2]), any([0, 1]), all([])", "(1, 5, True, True)"},
+		{"def f(): return [i * j for i in range(1, 3) for j in range(2)]", "[0, 1, 0, 2]"},
+		{`def f(): return dict(a=1).get("b", "x"), len({"a": 1}), enumerate(["a"])`, `("x", 1, [(0, "a")])`},
+		{"def f():\n  return \"\"\"a\nb\"\"\".splitlines()", `["a", "b"]`},
+		{`def f(): return "Hello World".startswith(("x", "He")), "x".endswith("y"), "abc".find("c")`, "(True, False, 2)"},
+		{`def f(): return "the quick fox".title(), "hello".capitalize()`, `("The Quick Fox", "Hello")`},
+		{`def f(): return int("42") + int(True), bool(""), type([])`, `(43, False, "list")`},
+		{"def f(): return range(9223372036854775806, 9223372036854775807, 2)", "[9223372036854775806]"},
+	} {
+		if got := repr(run(t, tt.src)); got != tt.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	src := `
+# Count words of titles.
+counts = {}
+
+def add(words, n=1):
+    for w in words:
+        if w in ("a", "the"):
+            continue
+        elif w == "stop":
+            break
+        else:
+            counts[w] = counts.get(w, 0) + n
+    return counts
+
+def f(titles):
+    total = 0
+    for t in titles:
+        add(t.lower().split())
+        total += 1
+    l = []
+    l += ["x"]
+    a, b = 1, 2
+    a, b = b, a
+    pass; total *= 10
+    return total, sorted(counts.items()), l, (a, b)
+`
+	titles := &List{Elems: []Value{"The Cat", "a cat and dog", "one STOP two"}}
+	want := `(30, [("and", 1), ("cat", 2), ("dog", 1), ("one", 1)], ["x"], (2, 1))`
+	if got := repr(run(t, src, titles)); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestMutation(t *testing.T) {
+	src := `
+def f(e):
+    e["title"] = e["title"].upper()
+    e["comments"] = [c for c in e["comments"] if "spam" not in c["content"]]
+    e["tags"].append("new")
+    e.pop("drop")
+    return e
+`
+	v, err := FromGo(map[string]interface{}{
+		"title": "hi",
+		"tags":  []interface{}{"a"},
+		"drop":  true,
+		"comments": []interface{}{
+			map[string]interface{}{"content": "ok"},
+			map[string]interface{}{"content": "buy spam"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ToGo(run(t, src, v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := got.(map[string]interface{})
+	if m["title"] != "HI" || len(m["comments"].([]interface{})) != 1 || len(m["tags"].([]interface{})) != 2 {
+		t.Errorf("got %v", m)
+	}
+	if _, ok := m["drop"]; ok {
+		t.Errorf("drop wasn't removed: %v", m)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, tt := range []struct{ src, want string }{
+		{"def f():\n  return x", "test.star:2: undefined: x"},
+		{"def f():\n  while True: pass", "test.star:2: while loops are not supported"},
+		{"def f():\n  return f()", "test.star:2: f called recursively"},
+		{"def f():\n  return 1 / 2", "test.star:2: floating-point division is not supported, use //"},
+		{"def f():\n  x = [1]\n  return x[5]", "test.star:3: index 5 out of range"},
+		{"def f():\n  return {}['k']", `test.star:2: key "k" not in dict`},
+		{"def f():\n  return 1 + 'a'", "test.star:2: unsupported operand types for +: int and string"},
+		{"def f():\n   x = 1\n  y = 2", "test.star:3: unindent doesn't match any outer level"},
+		{"def f():\n  fail('bad', 1)", "test.star:2: fail: bad 1"},
+		{"def f(:\n  pass", "test.star:1: expected name, got :"},
+		{"def f():\n  'a' * 100000000", "test.star:2: repeated string too long"},
+		{"def f():\n  return 9223372036854775807 + 1", "test.star:2: integer overflow"},
+		{"def f():\n  return (-9223372036854775807 - 1) // -1", "test.star:2: integer overflow"},
+		{"def f():\n  return 3037000500 * 3037000500", "test.star:2: integer overflow"},
+		{"def f():\n  x = -9223372036854775807 - 1\n  return -x", "test.star:3: integer overflow"},
+		{"def f():\n  l = [1]\n  for x in l:\n    l.append(x)", "test.star:4: append: can't change list during iteration"},
+		{"def f():\n  d = {1: 2}\n  for k in d:\n    d[k + 1] = k", "test.star:4: can't change dict during iteration"},
+		{"def f():\n  l = [1]\n  return [l.pop() for x in l]", "test.star:3: pop: can't change list during iteration"},
+		{"def f():\n  for i in range(10000):\n    for j in range(10000):\n      pass", "test.star:3: too many steps"},
+	} {
+		_, err := func() (Value, error) {
+			p, err := Load("test.star", []byte(tt.src))
+			if err != nil {
+				return nil, err
+			}
+			return p.Call("f")
+		}()
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%q: got error %v, want %s", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var b strings.Builder
+	Stderr = &b
+	defer func() { Stderr = os.Stderr }()
+	run(t, "def f():\n  print('a', 1)\n")
+	if got := b.String(); got != "test.star:2: a 1\n" {
+		t.Errorf("got %q", got)
+	}
+}
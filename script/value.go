@@ -0,0 +1,447 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Value is a script value: nil (None), bool, int, string, *List, Tuple,
+// *Dict, *Function, *Builtin or *Module.
+type Value interface{}
+
+// List is a mutable list.
+type List struct {
+	Elems []Value
+	iters int // running for loops over the list
+}
+
+// Tuple is an immutable list.
+type Tuple []Value
+
+// Dict is a mapping keeping insertion order. Keys are None, bool, int
+// or string.
+type Dict struct {
+	keys  []Value
+	m     map[Value]Value
+	iters int
+}
+
+// NewDict returns an empty dict.
+func NewDict() *Dict { return &Dict{m: make(map[Value]Value)} }
+
+// Keys returns keys in insertion order.
+func (d *Dict) Keys() []Value { return append([]Value(nil), d.keys...) }
+
+// Get returns value of key.
+func (d *Dict) Get(k Value) (Value, bool) {
+	v, ok := d.m[k]
+	return v, ok
+}
+
+// Set sets value of key, which must be hashable.
+func (d *Dict) Set(k, v Value) error {
+	if !hashable(k) {
+		return fmt.Errorf("unhashable type: %s", typeName(k))
+	}
+	if err := checkMutable(d); err != nil {
+		return err
+	}
+	if _, ok := d.m[k]; !ok {
+		d.keys = append(d.keys, k)
+	}
+	d.m[k] = v
+	return nil
+}
+
+// Delete removes key, reporting whether it was present.
+func (d *Dict) Delete(k Value) bool {
+	if !hashable(k) {
+		return false
+	}
+	if _, ok := d.m[k]; !ok {
+		return false
+	}
+	delete(d.m, k)
+	for i, key := range d.keys {
+		if key == k {
+			d.keys = append(d.keys[:i], d.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Len returns the number of keys.
+func (d *Dict) Len() int { return len(d.keys) }
+
+func hashable(v Value) bool {
+	switch v.(type) {
+	case nil, bool, int, string:
+		return true
+	}
+	return false
+}
+
+// Function is a function defined in script.
+type Function struct {
+	name    string
+	params  []param
+	defs    []Value // default values of params
+	body    []stmt
+	lambda  expr
+	env     *env
+	calling bool
+}
+
+// Builtin is a function implemented in Go.
+type Builtin struct {
+	name string
+	fn   func(in *interp, args []Value, kwargs map[string]Value) (Value, error)
+}
+
+// Module is a namespace of values, e.g. re.
+type Module struct {
+	Name    string
+	Members map[string]Value
+}
+
+func typeName(v Value) string {
+	switch v.(type) {
+	case nil:
+		return "NoneType"
+	case bool:
+		return "bool"
+	case int:
+		return "int"
+	case string:
+		return "string"
+	case *List:
+		return "list"
+	case Tuple:
+		return "tuple"
+	case *Dict:
+		return "dict"
+	case *Function, *Builtin:
+		return "function"
+	case *Module:
+		return "module"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func truth(v Value) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case string:
+		return v != ""
+	case *List:
+		return len(v.Elems) > 0
+	case Tuple:
+		return len(v) > 0
+	case *Dict:
+		return v.Len() > 0
+	}
+	return true
+}
+
+// str returns v as by str(): strings as is, others as by repr().
+func str(v Value) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return repr(v)
+}
+
+func repr(v Value) string {
+	var b strings.Builder
+	writeRepr(&b, v, nil)
+	return b.String()
+}
+
+func writeRepr(b *strings.Builder, v Value, seen map[Value]bool) {
+	if _, ok := v.(*List); ok || isDict(v) {
+		if seen[v] {
+			b.WriteString("...")
+			return
+		}
+		if seen == nil {
+			seen = make(map[Value]bool)
+		}
+		seen[v] = true
+		defer delete(seen, v)
+	}
+	elems := func(open, close string, xs []Value) {
+		b.WriteString(open)
+		for i, x := range xs {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeRepr(b, x, seen)
+		}
+		if close == ")" && len(xs) == 1 {
+			b.WriteString(",")
+		}
+		b.WriteString(close)
+	}
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("None")
+	case bool:
+		if v {
+			b.WriteString("True")
+		} else {
+			b.WriteString("False")
+		}
+	case int:
+		b.WriteString(strconv.Itoa(v))
+	case string:
+		b.WriteString(strconv.Quote(v))
+	case *List:
+		elems("[", "]", v.Elems)
+	case Tuple:
+		elems("(", ")", v)
+	case *Dict:
+		b.WriteString("{")
+		for i, k := range v.keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeRepr(b, k, seen)
+			b.WriteString(": ")
+			writeRepr(b, v.m[k], seen)
+		}
+		b.WriteString("}")
+	case *Function:
+		fmt.Fprintf(b, "<function %s>", v.name)
+	case *Builtin:
+		fmt.Fprintf(b, "<built-in function %s>", v.name)
+	case *Module:
+		fmt.Fprintf(b, "<module %s>", v.Name)
+	}
+}
+
+func isDict(v Value) bool {
+	_, ok := v.(*Dict)
+	return ok
+}
+
+func equal(x, y Value) bool {
+	switch x := x.(type) {
+	case *List:
+		y, ok := y.(*List)
+		return ok && equalElems(x.Elems, y.Elems)
+	case Tuple:
+		y, ok := y.(Tuple)
+		return ok && equalElems(x, y)
+	case *Dict:
+		y, ok := y.(*Dict)
+		if !ok || x.Len() != y.Len() {
+			return false
+		}
+		for _, k := range x.keys {
+			yv, ok := y.m[k]
+			if !ok || !equal(x.m[k], yv) {
+				return false
+			}
+		}
+		return true
+	case *Function, *Builtin, *Module:
+		return x == y
+	}
+	if !hashable(y) {
+		return false
+	}
+	return x == y
+}
+
+func equalElems(x, y []Value) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if !equal(x[i], y[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// compare returns -1, 0 or 1 comparing ordered values of the same type.
+func compare(x, y Value) (int, error) {
+	switch x := x.(type) {
+	case int:
+		if y, ok := y.(int); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if y, ok := y.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	case bool:
+		if y, ok := y.(bool); ok {
+			return compare(b2i(x), b2i(y))
+		}
+	case *List:
+		if y, ok := y.(*List); ok {
+			return compareElems(x.Elems, y.Elems)
+		}
+	case Tuple:
+		if y, ok := y.(Tuple); ok {
+			return compareElems(x, y)
+		}
+	}
+	return 0, fmt.Errorf("can't compare %s with %s", typeName(x), typeName(y))
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func compareElems(x, y []Value) (int, error) {
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if c, err := compare(x[i], y[i]); err != nil || c != 0 {
+			return c, err
+		}
+	}
+	return compare(len(x), len(y))
+}
+
+// iterate returns elements of iterable value: a copy, so that the
+// value can change while iterating.
+func iterate(v Value) ([]Value, error) {
+	switch v := v.(type) {
+	case *List:
+		return append([]Value(nil), v.Elems...), nil
+	case Tuple:
+		return v, nil
+	case *Dict:
+		return v.Keys(), nil
+	}
+	return nil, fmt.Errorf("%s is not iterable", typeName(v))
+}
+
+// startIteration marks v, if it's a list or dict, as iterated over
+// until the returned function is called.
+func startIteration(v Value) (end func()) {
+	switch v := v.(type) {
+	case *List:
+		v.iters++
+		return func() { v.iters-- }
+	case *Dict:
+		v.iters++
+		return func() { v.iters-- }
+	}
+	return func() {}
+}
+
+// checkMutable returns an error if v is a list or dict being iterated
+// over: as in Starlark, it can't change then.
+func checkMutable(v Value) error {
+	switch v := v.(type) {
+	case *List:
+		if v.iters > 0 {
+			return errors.New("can't change list during iteration")
+		}
+	case *Dict:
+		if v.iters > 0 {
+			return errors.New("can't change dict during iteration")
+		}
+	}
+	return nil
+}
+
+// FromGo returns script value of a Go value as decoded from JSON: nil,
+// bool, numbers, string, []interface{} and map[string]interface{}.
+// Maps become dicts with sorted keys.
+func FromGo(v interface{}) (Value, error) {
+	switch v := v.(type) {
+	case nil, bool, int, string:
+		return v, nil
+	case float64:
+		if v != float64(int(v)) {
+			return nil, fmt.Errorf("number %v isn't an integer", v)
+		}
+		return int(v), nil
+	case []interface{}:
+		l := &List{Elems: make([]Value, len(v))}
+		for i, x := range v {
+			var err error
+			if l.Elems[i], err = FromGo(x); err != nil {
+				return nil, err
+			}
+		}
+		return l, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		d := NewDict()
+		for _, k := range keys {
+			x, err := FromGo(v[k])
+			if err != nil {
+				return nil, err
+			}
+			d.Set(k, x)
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("unsupported Go type %T", v)
+}
+
+// ToGo returns Go value of script value, as FromGo takes. Dict keys
+// must be strings.
+func ToGo(v Value) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, bool, int, string:
+		return v, nil
+	case *List:
+		return toGoElems(v.Elems)
+	case Tuple:
+		return toGoElems(v)
+	case *Dict:
+		m := make(map[string]interface{}, v.Len())
+		for _, k := range v.keys {
+			s, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("dict key %s isn't a string", repr(k))
+			}
+			x, err := ToGo(v.m[k])
+			if err != nil {
+				return nil, err
+			}
+			m[s] = x
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("can't convert %s", typeName(v))
+}
+
+func toGoElems(elems []Value) ([]interface{}, error) {
+	xs := make([]interface{}, len(elems))
+	for i, e := range elems {
+		var err error
+		if xs[i], err = ToGo(e); err != nil {
+			return nil, err
+		}
+	}
+	return xs, nil
+}